@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// bisection are the settings of the bisect command.
+type bisection struct {
+	good, bad string
+	build     string // shell command building the server, if any
+	threshold string // median boot time, or percentage over the median of the good commit
+	runs      int    // measured runs per commit
+}
+
+// run drives git bisect between the good and bad commits, building and benchmarking each commit
+// to test, until the first commit over the threshold is found. Commits that do not build or boot
+// are skipped.
+func (b *bisection) run(ctx context.Context, bench *boottime.Benchmark) error {
+	var limit time.Duration
+	var percentage float64
+	if strings.HasSuffix(b.threshold, "%") {
+		var err error
+		if percentage, err = parseThreshold(b.threshold); err != nil {
+			return err
+		}
+	} else if d, err := time.ParseDuration(b.threshold); err != nil || d <= 0 {
+		return fmt.Errorf("invalid threshold: %s (expected a duration, as in 250ms, or a percentage over the good commit, as in 10%%)", b.threshold)
+	} else {
+		limit = d
+	}
+	bench.Runs = b.runs
+
+	// Relative revisions such as HEAD~10 change meaning once bisecting moves HEAD.
+	good, err := git(ctx, "rev-parse", "--verify", b.good+"^{commit}")
+	if err != nil {
+		return err
+	}
+	bad, err := git(ctx, "rev-parse", "--verify", b.bad+"^{commit}")
+	if err != nil {
+		return err
+	}
+	if _, err := git(ctx, "bisect", "start", bad, good); err != nil {
+		return err
+	}
+	defer git(context.Background(), "bisect", "reset")
+	if limit == 0 {
+		next, err := git(ctx, "rev-parse", "HEAD")
+		if err != nil {
+			return err
+		}
+		if _, err := git(ctx, "checkout", "--quiet", good); err != nil {
+			return err
+		}
+		reference, err := b.measure(ctx, bench)
+		if err != nil {
+			return fmt.Errorf("good commit %s: %v", b.good, err)
+		}
+		limit = time.Duration(float64(reference) * (1 + percentage/100))
+		fmt.Printf("Good commit %s: median of %s ms, threshold of %s ms\n", b.good, formatMillis(reference), formatMillis(limit))
+		if _, err := git(ctx, "checkout", "--quiet", next); err != nil {
+			return err
+		}
+	}
+	for {
+		commit, err := git(ctx, "rev-parse", "--short", "HEAD")
+		if err != nil {
+			return err
+		}
+		color.New(color.FgMagenta, color.Bold).Printf("Commit %s\n", commit)
+		verdict := "skip"
+		if median, err := b.measure(ctx, bench); ctx.Err() != nil {
+			return ctx.Err()
+		} else if err != nil {
+			color.Yellow("Skipping %s: %v", commit, err)
+		} else if median > limit {
+			verdict = "bad"
+			color.Red("Bad: median of %s ms over %s ms", formatMillis(median), formatMillis(limit))
+		} else {
+			verdict = "good"
+			color.Green("Good: median of %s ms", formatMillis(median))
+		}
+		out, err := git(ctx, "bisect", verdict)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(out, "is the first bad commit") {
+			fmt.Println(out)
+			return nil
+		}
+		if strings.Contains(out, "only 'skip'ped commits left") {
+			return fmt.Errorf("unable to bisect further: %s", out)
+		}
+	}
+}
+
+// measure builds the checked out commit and returns the median boot time of the benchmark.
+func (b *bisection) measure(ctx context.Context, bench *boottime.Benchmark) (time.Duration, error) {
+	if err := buildServer(ctx, b.build); err != nil {
+		return 0, err
+	}
+	results, err := bench.Run(ctx)
+	if err != nil {
+		return 0, err
+	}
+	durations := boottime.Durations(results.Measured())
+	if len(durations) == 0 {
+		return 0, fmt.Errorf("no successful run out of %d", len(results.Runs))
+	}
+	return boottime.Median(durations), nil
+}
+
+// buildServer runs the shell command building the server at the checked out commit, if any, with
+// its output going to the standard error.
+func buildServer(ctx context.Context, command string) error {
+	if len(command) == 0 {
+		return nil
+	}
+	build := exec.CommandContext(ctx, "sh", "-c", command)
+	build.Stdout, build.Stderr = os.Stderr, os.Stderr
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("build failed: %v", err)
+	}
+	return nil
+}
+
+// git runs a git command in the working directory and returns its trimmed output.
+func git(ctx context.Context, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
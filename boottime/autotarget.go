@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"runtime"
+)
+
+// ListeningPhase marks when the server was first seen listening, with Benchmark.AutoTarget.
+const ListeningPhase = "listening"
+
+// autoTargetModes are the modes whose target can be discovered.
+var autoTargetModes = map[string]bool{"http-get": true, "tcp-connect": true, "prom-metric": true, "health-groups": true}
+
+var errNotListening = errors.New("the server does not listen yet")
+
+// checkAutoTarget tells whether the target of the benchmark can be discovered.
+func (b *Benchmark) checkAutoTarget() error {
+	if !b.AutoTarget {
+		return nil
+	}
+	if !autoTargetModes[b.Mode] {
+		return fmt.Errorf("the target of the %s mode cannot be discovered (supported: http-get, tcp-connect, prom-metric, health-groups)", b.Mode)
+	}
+	if remoteLaunchers[b.Launcher] {
+		return fmt.Errorf("the target of servers of the %s launcher cannot be discovered, as they run elsewhere", b.Launcher)
+	}
+	if runtime.GOOS != "linux" {
+		return errors.New("discovering the target is only supported on Linux")
+	}
+	return nil
+}
+
+// autoTarget is the target of the mode once the server listens on address: address for host:port
+// targets, or the URL target with its host and port replaced, http://address/ by default.
+func autoTarget(mode, target, address string) string {
+	host, port, _ := net.SplitHostPort(address)
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+		if ip.To4() == nil {
+			host = "::1"
+		}
+	}
+	address = net.JoinHostPort(host, port)
+	if mode == "tcp-connect" {
+		return address
+	}
+	u, err := url.Parse(target)
+	if err != nil || len(u.Scheme) == 0 {
+		u = &url.URL{Scheme: "http", Path: "/"}
+	}
+	u.Host = address
+	return u.String()
+}
+
+// autoTargetProbe waits for the server to listen, then probes its first listening address with the
+// probe of the mode. Attempts fail until the server listens.
+type autoTargetProbe struct {
+	b      *Benchmark
+	pid    int
+	target string // discovered during the current run
+	probe  Probe  // of the discovered target
+}
+
+// attach makes the probe look for the sockets of the process of the current run.
+func (p *autoTargetProbe) attach(pid int) {
+	p.pid = pid
+}
+
+func (p *autoTargetProbe) Setup(ctx context.Context) error {
+	return nil
+}
+
+func (p *autoTargetProbe) Check(ctx context.Context) (ProbeResult, error) {
+	if p.probe != nil {
+		return p.probe.Check(ctx)
+	}
+	if p.pid <= 0 {
+		return ProbeResult{}, errNotListening
+	}
+	addresses, err := listeningAddresses(p.pid)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	if len(addresses) == 0 {
+		return ProbeResult{}, errNotListening
+	}
+	b := *p.b
+	b.Target = autoTarget(b.Mode, b.Target, addresses[0])
+	probe, err := b.probe()
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	if err := probe.Setup(ctx); err != nil {
+		return ProbeResult{}, fmt.Errorf("probe setup failed: %v", err)
+	}
+	p.b.Logger.Debug("target discovered", "target", b.Target, "listening", addresses)
+	p.target, p.probe = b.Target, probe
+	result, err := probe.Check(ctx)
+	result.Reached = append([]string{ListeningPhase}, result.Reached...)
+	return result, err
+}
+
+func (p *autoTargetProbe) Teardown() error {
+	probe := p.probe
+	p.pid, p.target, p.probe = 0, "", nil
+	if probe != nil {
+		return probe.Teardown()
+	}
+	return nil
+}
@@ -0,0 +1,779 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Benchmark describes a series of boot measurements of an executable.
+type Benchmark struct {
+	Name   string // name of the scenario, if any
+	Mode   string // connection mode, see Modes
+	Target string // connection target, a URL for http-get or a host:port for tcp-connect
+	// AutoTarget probes the first address the server listens on, on Linux, rather than Target.
+	// For modes with URL targets, only the host and port of Target are replaced.
+	AutoTarget bool
+	Command    string   // executable to boot
+	Args       []string // arguments passed to the executable
+	Env        []string // environment variables added to those of the executable, as KEY=value
+	// WorkDir is the directory the executable runs from, with the exec, shell and deploy launchers,
+	// that of the benchmark when empty. Relative executable paths are resolved from it.
+	WorkDir string
+	// StdinFile is fed to the standard input of the executable, which otherwise reads from
+	// /dev/null. CloseStdin starts the executable with its standard input closed instead, for servers
+	// that behave differently when it is missing. Both apply to the exec, shell and deploy launchers.
+	StdinFile  string
+	CloseStdin bool
+
+	// Description and Hypothesis document the scenario, and are carried into the results.
+	Description string
+	Hypothesis  string
+	// ExpectedFail marks a benchmark known to fail, such as a broken configuration kept for
+	// tracking, whose failures are still reported but should not fail the session, as xfail tests.
+	// It is carried into the results.
+	ExpectedFail bool
+
+	// Profile is the name of the profile the benchmark was set up with, if any, see Profiles.
+	// Too few runs for the profile are warned about, or rejected by strict profiles.
+	Profile string
+
+	// Launcher is the name of the launcher running the executable, DefaultLauncher when empty.
+	Launcher string
+	// Checkpoint is the directory of the checkpoint restored by the crac and criu launchers.
+	Checkpoint string
+	// Deploy holds the options of the deploy launcher.
+	Deploy DeployOptions
+	// Systemd holds the options of the systemd-scope launcher.
+	Systemd SystemdOptions
+	// Docker holds the options of the docker launcher, which runs the image given as Command.
+	Docker DockerOptions
+	// Kubernetes holds the options of the kubernetes launcher, which applies the manifest given as
+	// Command, and of the kubernetes-ready mode.
+	Kubernetes KubernetesOptions
+
+	DryRuns int           // number of runs to perform and discard before measuring
+	Runs    int           // number of measured runs
+	Pause   time.Duration // pause between consecutive runs
+
+	// RunTimeout bounds the time from spawning the server to the end of a run, which then fails
+	// with the ProbeTimeoutCategory when the server was not ready yet, or else with the
+	// RunTimeoutCategory. Runs may last forever when zero.
+	RunTimeout time.Duration
+	// ReadyTimeout bounds the time from spawning the server to its readiness, milestones included,
+	// the run then failing with the ProbeTimeoutCategory. Only RunTimeout applies when zero.
+	ReadyTimeout time.Duration
+	// OnFailure is the policy towards failed runs, AbortOnFailure when empty. Interrupted runs always
+	// stop the benchmark.
+	OnFailure string
+
+	// Settle makes runs after the first wait, once the pause is over, for the load average, CPU
+	// usage and disk I/O of the system to return to the baseline sampled before the first run, up
+	// to SettleTimeout. Only supported on Linux.
+	Settle bool
+
+	HTTP     HTTPOptions     // options of the http-get mode
+	TCP      TCPOptions      // options of the tcp-connect mode
+	Prom     PromOptions     // options of the prom-metric mode
+	Health   HealthOptions   // options of the health-groups mode
+	Callback CallbackOptions // options of the callback mode
+	File     FileOptions     // options of the file mode
+	LogFile  LogFileOptions  // options of the logfile mode
+	LogMatch LogMatchOptions // options of the log-match mode
+
+	// MaxProbeRate caps the number of probe attempts per second, unlimited when zero.
+	MaxProbeRate float64
+
+	// StableFor is the window during which probe attempts must keep succeeding once the server is
+	// ready for readiness to be finalized, none when zero. Runs are still measured up to the moment
+	// the server became ready, see Run.Flaps.
+	StableFor time.Duration
+
+	// PollInterval is the minimum time between the starts of consecutive probe attempts, so that
+	// probing does not take a CPU core, none when zero. A longer interval is kept from MaxProbeRate.
+	PollInterval time.Duration
+	// PollBackoff is the factor by which the interval grows after each failed attempt, up to
+	// PollMaxInterval, or DefaultPollMaxInterval when zero. The interval is constant when PollBackoff
+	// is 0 or 1, and goes back to PollInterval after each successful attempt.
+	PollBackoff     float64
+	PollMaxInterval time.Duration
+
+	// ReadyAfterRequests is the number of consecutive successful probe attempts after which the
+	// server is considered ready, 1 when zero.
+	ReadyAfterRequests int
+
+	// CalibrationRuns is the number of pairs of calibration runs performed before the dry runs to
+	// estimate how much probing delays readiness, none when zero. See ProbeCalibration.
+	CalibrationRuns int
+	// CalibrationProbeRate is the probe rate of reference calibration runs, DefaultCalibrationProbeRate when zero.
+	CalibrationProbeRate float64
+
+	// WatchPorts maps names to host:port addresses of ports that the server opens. The moment each
+	// starts accepting connections before readiness is marked with a phase, see PortPhasePrefix.
+	WatchPorts map[string]string
+
+	// Dependencies are started before the runs and stopped after them, their placeholders being
+	// replaced in the command, arguments, environment and target, see ContainerDependency.
+	Dependencies []Dependency
+
+	// Milestones are stages of the boot of the server waited for in order before the probe of the
+	// benchmark, each marked with a phase, see MilestonePhasePrefix, so that the boot time is broken
+	// down into the durations between them.
+	Milestones []LifecycleEvent
+	// Events are lifecycle events of the server waited for in order once it is ready, each marked
+	// with a phase, see EventPhasePrefix. Runs end once the last event happened.
+	Events []LifecycleEvent
+
+	// LingeringSockets is the policy towards sockets lingering on the target port before each run,
+	// FlagLingeringSockets when empty.
+	LingeringSockets string
+
+	// UpgradeSignal is the signal sent to the server once ready, as in USR2, to make it upgrade in
+	// place. Runs then measure the time from the signal to the moment the probe reports a new
+	// generation of the server, and the server is not upgraded when empty.
+	UpgradeSignal string
+
+	// CrashRecovery kills the server once ready and starts it again, measuring the time until it is
+	// ready again as the recovery of the run, see Recovery. The restarted server is stopped as usual.
+	CrashRecovery bool
+
+	// ShutdownSignal is the signal stopping the server at the end of each run, DefaultShutdownSignal
+	// when empty. The server is killed when still running after ShutdownGrace, or
+	// DefaultShutdownGrace when 0.
+	ShutdownSignal string
+	ShutdownGrace  time.Duration
+
+	// HostProfile is embedded in the results, see CalibrateHost.
+	HostProfile *HostProfile
+
+	// MeasureCPUScore measures the CPU score of the host before the runs, see MeasureCPUScore.
+	MeasureCPUScore bool
+
+	// ReservedCPUs is the number of CPUs reserved to the benchmark itself, on Linux: the benchmark
+	// is pinned to them and the server to the other CPUs with taskset, so that CPU hungry boots do
+	// not delay their own probing. None when zero.
+	ReservedCPUs int
+
+	// ReadOnlyRootfs runs the server with its root filesystem mounted read-only, as production
+	// platforms often do, on Linux with the exec and shell launchers. Output lines reporting refused
+	// writes are kept in Run.RefusedWrites.
+	ReadOnlyRootfs bool
+
+	// Capabilities restricts the capabilities of the server, on Linux with the exec and shell
+	// launchers: NoCapabilities, DefaultCapabilities or a comma-separated list of the capabilities
+	// to keep. Unchanged when empty.
+	Capabilities string
+	// Seccomp is the seccomp profile of the server, on Linux with the exec and shell launchers:
+	// RuntimeDefaultSeccomp or none when empty.
+	Seccomp string
+
+	// CollectJVMMetrics reads the counters of the JVM with jcmd once the server is ready, see JVMMetrics.
+	// The process must be the JVM itself rather than a wrapper.
+	CollectJVMMetrics bool
+
+	// OnRun is called after each successful run, including dry runs, when not nil.
+	OnRun func(run Run)
+
+	// OnMisconfiguration is called when the first run suggests that the benchmark is misconfigured,
+	// and stops the benchmark with ErrMisconfigured when it returns false. The misconfiguration is
+	// logged as a warning when nil.
+	OnMisconfiguration func(m Misconfiguration) bool
+
+	// OnAttempt is called after each probe attempt, when not nil.
+	OnAttempt func(attempt Attempt)
+
+	// OnOutput is called with each line written by the server, when not nil.
+	// The output is discarded otherwise. It may be called concurrently for stdout and stderr.
+	OnOutput func(line OutputLine)
+
+	// Logger receives diagnostics, nothing is logged when nil.
+	Logger *Logger
+
+	interleaving *interleaving // with RunInterleaved
+	turn         int
+}
+
+// RunError reports which run of a benchmark failed.
+type RunError struct {
+	Dry   bool
+	Index int
+	Err   error
+}
+
+// Category returns the category of the failure, see ErrorCategory.
+func (e *RunError) Category() string {
+	return ErrorCategory(e.Err)
+}
+
+func (e *RunError) Error() string {
+	kind := "run"
+	if e.Dry {
+		kind = "dry run"
+	}
+	return fmt.Sprintf("%s %d failed: %v", kind, e.Index+1, e.Err)
+}
+
+// DefaultCalibrationProbeRate is the default probe rate of reference calibration runs, low enough
+// not to disturb most servers.
+const DefaultCalibrationProbeRate = 10
+
+// Validate checks the settings of the benchmark without launching anything, so that invalid ones
+// are reported before running any benchmark. Run validates the benchmark first.
+func (b *Benchmark) Validate() error {
+	if b.Runs < 1 {
+		return fmt.Errorf("the number of runs must be at least 1, not %d", b.Runs)
+	}
+	if b.DryRuns < 0 || b.CalibrationRuns < 0 {
+		return errors.New("the numbers of dry runs and calibration runs must not be negative")
+	}
+	if b.Pause < 0 {
+		return errors.New("the pause must not be negative")
+	}
+	if b.MaxProbeRate < 0 || b.CalibrationProbeRate < 0 || b.ReadyAfterRequests < 0 {
+		return errors.New("the probe rates and the number of requests to be ready must not be negative")
+	}
+	if err := b.checkModes(); err != nil {
+		return err
+	}
+	if err := b.checkProcessSettings(); err != nil {
+		return err
+	}
+	if err := checkLingeringSocketsPolicy(b.LingeringSockets); err != nil {
+		return err
+	}
+	if err := checkFailurePolicy(b.OnFailure); err != nil {
+		return err
+	}
+	if b.RunTimeout < 0 || b.ReadyTimeout < 0 {
+		return fmt.Errorf("the run and ready timeouts must not be negative")
+	}
+	if b.RunTimeout > 0 && b.ReadyTimeout > b.RunTimeout {
+		return fmt.Errorf("the ready timeout (%s) must not exceed the run timeout (%s)", b.ReadyTimeout, b.RunTimeout)
+	}
+	if err := b.checkReadOnlyRootfs(); err != nil {
+		return err
+	}
+	if err := b.checkRestrictions(); err != nil {
+		return err
+	}
+	if err := b.checkAutoTarget(); err != nil {
+		return err
+	}
+	if err := b.checkShutdown(); err != nil {
+		return err
+	}
+	if err := b.checkPolling(); err != nil {
+		return err
+	}
+	if err := b.checkCrashRecovery(); err != nil {
+		return err
+	}
+	if b.StableFor < 0 {
+		return fmt.Errorf("the stability window must not be negative")
+	}
+	return nil
+}
+
+// Run performs the calibration runs if any, the dry runs, then the measured runs.
+// When a run fails, the results collected so far are returned along with a *RunError, unless the
+// failure policy carries on, in which case a *RunError is only returned when no measured run
+// succeeded. Cancelling ctx kills the running process, interrupts in-flight probes and pauses,
+// and fails the current run with the context error.
+func (b *Benchmark) Run(ctx context.Context) (*Results, error) {
+	if err := b.waitTurn(ctx); err != nil {
+		return nil, err
+	}
+	defer b.passTurn(true)
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	if err := b.checkProfile(); err != nil {
+		return nil, err
+	}
+	if len(b.Dependencies) > 0 {
+		placeholders, stopDependencies, err := b.startDependencies(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer stopDependencies()
+		b = b.expand(placeholders)
+	}
+	var probe Probe = &autoTargetProbe{b: b}
+	if !b.AutoTarget {
+		modeProbe, err := b.probe()
+		if err != nil {
+			return nil, err
+		}
+		probe = modeProbe
+	}
+	milestones, err := b.eventProbes("milestone", b.Milestones)
+	if err != nil {
+		return nil, err
+	}
+	events, err := b.eventProbes("event", b.Events)
+	if err != nil {
+		return nil, err
+	}
+	launcherFactory, err := b.launcherFactory()
+	if err != nil {
+		return nil, err
+	}
+	s := &session{Benchmark: b, probe: probe, milestones: milestones, events: events, launcherFactory: launcherFactory, server: b.serverBenchmark(probe)}
+	results := b.newResults()
+	if b.ReservedCPUs > 0 {
+		reservation, restore, err := reserveCPUs(b.ReservedCPUs)
+		if err != nil {
+			return nil, err
+		}
+		defer restore()
+		b.Logger.Debug("CPUs reserved", "tool", formatCPUList(reservation.Tool), "server", formatCPUList(reservation.Server))
+		s.serverCPUs = formatCPUList(reservation.Server)
+		results.CPUReservation = reservation
+	}
+	if b.MeasureCPUScore {
+		score, err := MeasureCPUScore(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b.Logger.Debug("CPU score measured", "score", score)
+		results.Host.CPUScore, results.Host.CPUScoreWorkload = score, CPUScoreWorkload
+	}
+	if b.Settle {
+		baseline, err := sampleSystemLoad(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to sample the baseline load of the system: %v", err)
+		}
+		b.Logger.Debug("baseline system load", "load", baseline.Load, "cpu", baseline.CPU, "io", baseline.IO)
+		s.baseline = &baseline
+		results.SettleBaseline = &baseline
+	}
+	if b.CalibrationRuns > 0 {
+		calibration, err := s.calibrate(ctx)
+		results.ProbeCalibration = calibration
+		if err != nil {
+			return results, err
+		}
+	}
+	runs := []struct {
+		dry   bool
+		count int
+	}{{true, b.DryRuns}, {false, b.Runs}}
+	var settleWait time.Duration
+	var failure *RunError
+	retries := 0
+	// The environment is read before the pause preceding each run rather than right before it, as
+	// counting containers may take up to containerCountTimeout and disturb the run.
+	environment := snapshotEnvironment(ctx)
+	for _, kind := range runs {
+		for i := 0; i < kind.count; i++ {
+			if len(results.Runs) > 0 {
+				b.passTurn(false)
+				if err := b.waitTurn(ctx); err != nil {
+					return results, err
+				}
+			}
+			run, err := s.measure(ctx, runSpec{dry: kind.dry, index: i, probeRate: b.MaxProbeRate})
+			run.SettleWait, run.Environment = settleWait, environment
+			if err != nil {
+				run.Error = err.Error()
+				run.ErrorCategory = ErrorCategory(err)
+				results.Runs = append(results.Runs, run)
+				failure = &RunError{Dry: kind.dry, Index: i, Err: err}
+				switch {
+				case ctx.Err() != nil || b.OnFailure == "" || b.OnFailure == AbortOnFailure:
+					return results, failure
+				case b.OnFailure == RetryOnFailure && retries == MaxRunRetries:
+					return results, fmt.Errorf("%w, after %d retries", failure, retries)
+				case b.OnFailure == RetryOnFailure:
+					retries++
+					b.Logger.Warn("run failed, performing it again", "error", failure, "retry", retries)
+					i--
+				default:
+					b.Logger.Warn("run failed, skipping it", "error", failure)
+				}
+			} else {
+				retries = 0
+				results.Runs = append(results.Runs, run)
+				if b.OnRun != nil {
+					b.OnRun(run)
+				}
+			}
+			if err == nil && len(results.Runs) == 1 {
+				if m := s.misconfiguration(run); m != nil {
+					if b.OnMisconfiguration == nil && b.strict() {
+						return results, fmt.Errorf("%w: %s", ErrMisconfigured, m.Symptom)
+					} else if b.OnMisconfiguration == nil {
+						b.Logger.Warn("the benchmark looks misconfigured", "symptom", m.Symptom, "causes", strings.Join(m.Causes, "; "))
+					} else if !b.OnMisconfiguration(*m) {
+						return results, ErrMisconfigured
+					}
+				}
+			}
+			if !kind.dry && i == kind.count-1 {
+				break // no pause after the last run
+			}
+			environment = snapshotEnvironment(ctx)
+			if settleWait, err = s.pause(ctx); err != nil {
+				return results, err
+			}
+		}
+	}
+	if failure != nil && len(results.Measured()) == 0 {
+		return results, failure
+	}
+	return results, nil
+}
+
+// serverBenchmark returns the benchmark with the command, arguments and environment of the server
+// completed by the probe when it implements ServerEnvironment, or the benchmark itself.
+func (b *Benchmark) serverBenchmark(probe Probe) *Benchmark {
+	environment, ok := probe.(ServerEnvironment)
+	if !ok {
+		return b
+	}
+	server := b.expand(environment.Placeholders())
+	server.Env = append(server.Env, environment.Environment()...)
+	return server
+}
+
+func (b *Benchmark) newResults() *Results {
+	host := CurrentHost()
+	return &Results{
+		SchemaVersion: SchemaVersion,
+		Scenario:      b.Name,
+		Description:   b.Description,
+		Hypothesis:    b.Hypothesis,
+		ExpectedFail:  b.ExpectedFail,
+		Profile:       b.Profile,
+		Mode:          b.Mode,
+		Target:        redactTarget(b.Target),
+		Command:       b.Command,
+		Args:          b.Args,
+		StartedAt:     time.Now(),
+		Host:          &host,
+		HostProfile:   b.HostProfile,
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// session holds what the runs of a benchmark share.
+type session struct {
+	*Benchmark
+	probe           Probe
+	milestones      []eventProbe
+	events          []eventProbe
+	launcherFactory LauncherFactory
+	server          *Benchmark  // what launchers get, see ServerEnvironment
+	baseline        *SystemLoad // when settling
+	serverCPUs      string      // when reserving CPUs
+}
+
+// runSpec tells how to perform a run.
+type runSpec struct {
+	dry         bool
+	index       int
+	probeRate   float64 // unlimited when zero
+	calibration bool    // calibration runs are not reported to the callbacks
+}
+
+// calibrate alternates runs probing at the configured rate and at the reference rate.
+func (s *session) calibrate(ctx context.Context) (*ProbeCalibration, error) {
+	calibration := &ProbeCalibration{ProbeRate: s.MaxProbeRate, ReferenceProbeRate: s.CalibrationProbeRate}
+	if calibration.ReferenceProbeRate <= 0 {
+		calibration.ReferenceProbeRate = DefaultCalibrationProbeRate
+	}
+	for i := 0; i < s.CalibrationRuns; i++ {
+		for _, rate := range []float64{calibration.ProbeRate, calibration.ReferenceProbeRate} {
+			run, err := s.measure(ctx, runSpec{index: i, probeRate: rate, calibration: true})
+			if err != nil {
+				return calibration, fmt.Errorf("calibration run %d failed: %v", i+1, err)
+			}
+			if rate == calibration.ProbeRate {
+				calibration.Durations = append(calibration.Durations, run.Duration)
+			} else {
+				calibration.ReferenceDurations = append(calibration.ReferenceDurations, run.Duration)
+			}
+			if _, err := s.pause(ctx); err != nil {
+				return calibration, err
+			}
+		}
+	}
+	calibration.estimate()
+	s.Logger.Info("probe calibration", "delay", calibration.Delay, "rate", calibration.ProbeRate, "reference_rate", calibration.ReferenceProbeRate)
+	return calibration, nil
+}
+
+// await probes the server until the required number of consecutive attempts succeed, counting
+// attempts whose result is not accepted as failures when accept is not nil, and returns the result
+// of the attempt that made the server ready along with the moment it did. The probe resolution of
+// the run is the time between the last failed attempt and the first of the successful ones.
+//
+// With a stability window, attempts go on until they kept succeeding for the window, and readiness
+// is only finalized then, a failure in the window counting as a flap of the run.
+func (s *session) await(ctx context.Context, spec runSpec, run *Run, start time.Time, interval time.Duration, accept func(ProbeResult) bool) (*ProbeResult, time.Time, error) {
+	required := s.ReadyAfterRequests
+	if required < 1 {
+		required = 1
+	}
+	var attemptStart, failureStart, readyAt time.Time
+	var resolution time.Duration
+	var ready *ProbeResult
+	wait := interval
+	successes := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, readyAt, err
+		}
+		if !attemptStart.IsZero() && wait > 0 {
+			if err := sleep(ctx, wait-time.Since(attemptStart)); err != nil {
+				return nil, readyAt, err
+			}
+		}
+		run.Attempts++
+		attemptStart = time.Now()
+		result, checkErr := s.probe.Check(ctx)
+		if result.Latency == 0 {
+			result.Latency = time.Since(attemptStart)
+		}
+		for _, phase := range result.Reached {
+			if !run.reached(phase) {
+				run.mark(phase, time.Since(start))
+			}
+		}
+		if s.OnAttempt != nil && !spec.calibration {
+			s.OnAttempt(Attempt{
+				Dry:         spec.dry,
+				Run:         spec.index,
+				Number:      run.Attempts,
+				Offset:      attemptStart.Sub(start),
+				ProbeResult: result,
+				Err:         checkErr,
+			})
+		}
+		if checkErr != nil || accept != nil && !accept(result) {
+			if ready != nil {
+				run.Flaps++
+				s.Logger.Debug("the server failed a probe within the stability window", "ready_for", time.Since(readyAt), "error", checkErr)
+				ready = nil
+			}
+			successes = 0
+			failureStart = attemptStart
+			wait = s.backoff(wait)
+			continue
+		}
+		successes++
+		wait = interval
+		if successes == 1 && !failureStart.IsZero() {
+			resolution = attemptStart.Sub(failureStart)
+		}
+		if successes == 1 && required > 1 && accept == nil && !run.reached(FirstSuccessPhase) {
+			run.mark(FirstSuccessPhase, time.Since(start))
+		}
+		if successes == required {
+			readyResult := result
+			ready, readyAt = &readyResult, time.Now()
+		}
+		if ready != nil && time.Since(readyAt) >= s.StableFor {
+			run.ProbeResolution = resolution
+			return ready, readyAt, nil
+		}
+	}
+}
+
+func (s *session) measure(ctx context.Context, spec runSpec) (Run, error) {
+	run := Run{Dry: spec.dry, Index: spec.index}
+	run.StartedAt = time.Now()
+	launcher, err := s.launcherFactory(s.server)
+	if err != nil {
+		return run, err
+	}
+	if pinnable, ok := launcher.(cpuPinnable); ok && len(s.serverCPUs) > 0 && !remoteLaunchers[s.Launcher] {
+		pinnable.pinCPUs(s.serverCPUs)
+	}
+	defer func() {
+		if err := launcher.Cleanup(); err != nil {
+			s.Logger.Warn("launcher cleanup failed", "error", err)
+		}
+	}()
+	if err := s.checkLingeringSockets(ctx, &run); err != nil {
+		return run, err
+	}
+	if err := s.probe.Setup(ctx); err != nil {
+		return run, fmt.Errorf("probe setup failed: %v", err)
+	}
+	defer func() {
+		if err := s.probe.Teardown(); err != nil {
+			s.Logger.Warn("probe teardown failed", "error", err)
+		}
+	}()
+	if len(s.milestones)+len(s.events) > 0 && !spec.calibration {
+		teardownEvents, err := s.setupEvents(ctx)
+		if err != nil {
+			return run, err
+		}
+		defer teardownEvents()
+	}
+	interval := s.probeInterval(spec.probeRate)
+	start := time.Now()
+	var stdout, stderr *lineWriter
+	var refused *refusedWrites
+	var tail *outputTail
+	if !spec.calibration {
+		if s.ReadOnlyRootfs {
+			refused = &refusedWrites{}
+		}
+		tail = &outputTail{}
+	}
+	stdout, stderr = s.outputWriters(spec, start, refused, tail, s.outputProbes(spec))
+	runCtx := ctx
+	if s.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, s.RunTimeout)
+		defer cancel()
+	}
+	if err := launcher.Start(ctx, writerOrNil(stdout), writerOrNil(stderr)); err != nil {
+		return run, categorize(SpawnErrorCategory, err)
+	}
+	awaitCtx, stopAwait := s.watchServer(runCtx, &launcher)
+	defer stopAwait(nil)
+	run.mark(SpawnedPhase, time.Since(start))
+	s.Logger.Debug("process started", "pid", launcher.Pid())
+	auto, _ := s.probe.(*autoTargetProbe)
+	if auto != nil {
+		auto.attach(launcher.Pid())
+	}
+	var ports *portWatcher
+	if len(s.WatchPorts) > 0 {
+		ports = watchPorts(s.WatchPorts, start)
+	}
+	var ready *ProbeResult
+	var readyAt time.Time
+	readyCtx := awaitCtx
+	if s.ReadyTimeout > 0 {
+		var cancel context.CancelFunc
+		readyCtx, cancel = context.WithTimeout(awaitCtx, s.ReadyTimeout)
+		defer cancel()
+	}
+	if len(s.milestones) > 0 && !spec.calibration {
+		err = s.awaitEvents(readyCtx, s.milestones, MilestonePhasePrefix, &run, start, interval)
+	}
+	if err == nil {
+		ready, readyAt, err = s.await(readyCtx, spec, &run, start, interval, nil)
+	}
+	var early *exitError
+	if err != nil && errors.As(context.Cause(awaitCtx), &early) {
+		err = categorize(CrashCategory, early)
+	} else if err != nil && readyCtx.Err() == context.DeadlineExceeded && awaitCtx.Err() == nil {
+		err = categorize(ProbeTimeoutCategory, fmt.Errorf("the server was not ready within %s", s.ReadyTimeout))
+	}
+	if auto != nil {
+		run.Target = redactTarget(auto.target)
+	}
+	if err == nil {
+		run.Duration = readyAt.Sub(start)
+		run.mark(ReadyPhase, run.Duration)
+		if s.StableFor > 0 {
+			run.mark(StablePhase, time.Since(start))
+		}
+		run.ReadyProbe = ready
+		s.Logger.Debug("connection established", "target", s.Target, "duration", run.Duration, "attempts", run.Attempts)
+		if pid := launcher.Pid(); pid > 0 && !spec.calibration {
+			var schedErr error
+			if run.Scheduling, schedErr = readScheduling(pid); schedErr != nil {
+				s.Logger.Debug("unable to read scheduling statistics", "pid", pid, "error", schedErr)
+			}
+			var memErr error
+			if run.Memory, memErr = readMemory(pid); memErr != nil {
+				s.Logger.Debug("unable to read the memory of the server", "pid", pid, "error", memErr)
+			}
+			var cpuErr error
+			if run.CPU, cpuErr = readCPUTime(pid); cpuErr != nil {
+				s.Logger.Debug("unable to read the CPU time of the server", "pid", pid, "error", cpuErr)
+			}
+		}
+		if s.CollectJVMMetrics && !spec.calibration {
+			var jvmErr error
+			if run.JVM, jvmErr = collectJVMMetrics(ctx, launcher.Pid()); jvmErr != nil {
+				s.Logger.Warn("unable to collect JVM metrics", "pid", launcher.Pid(), "error", jvmErr)
+			}
+		}
+		if len(s.events) > 0 && !spec.calibration {
+			err = s.awaitEvents(runCtx, s.events, EventPhasePrefix, &run, start, interval)
+		}
+		if len(s.UpgradeSignal) > 0 && err == nil {
+			err = s.upgrade(runCtx, spec, &run, launcher, start, interval)
+		}
+		if s.CrashRecovery && !spec.calibration && err == nil {
+			launcher, err = s.recover(runCtx, spec, &run, launcher, start, interval, writerOrNil(stdout), writerOrNil(stderr))
+		}
+	}
+	if err != nil && runCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		if run.reached(ReadyPhase) {
+			err = categorize(RunTimeoutCategory, fmt.Errorf("the server was ready, but the run did not complete within %s", s.RunTimeout))
+		} else {
+			err = categorize(ProbeTimeoutCategory, fmt.Errorf("the run did not complete within %s", s.RunTimeout))
+		}
+	}
+	if ports != nil {
+		ports.done(&run)
+	}
+	termination := s.shutdown(launcher, &run, err != nil)
+	if stdout != nil {
+		stdout.flush()
+		stderr.flush()
+	}
+	if run.Recovery != nil {
+		run.Recovery.Exit, run.Recovery.Resources = termination.Exit, termination.Resources
+	} else {
+		run.recordTermination(termination)
+	}
+	if refused != nil {
+		run.RefusedWrites = refused.reported()
+	}
+	if errors.As(err, &early) && ctx.Err() == nil {
+		if tail != nil {
+			run.StderrTail = tail.lines()
+		}
+		s.logExit(err, run.StderrTail)
+	}
+	if err != nil && len(run.RefusedWrites) > 0 && ctx.Err() == nil {
+		err = categorize(ReadOnlyRootfsCategory, err)
+	} else if err != nil && termination.Exit != nil && len(termination.Exit.Signal) == 0 && !remoteLaunchers[s.Launcher] {
+		err = categorize(CrashCategory, err)
+	}
+	return run, err
+}
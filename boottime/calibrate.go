@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// HostProfile describes how well a host performs the operations that boot time measurements rely
+// on, so that consumers of results can judge the quality of the environment they come from.
+type HostProfile struct {
+	MeasuredAt time.Time `json:"measured_at"`
+	Host       Host      `json:"host"` // including the CPU score
+
+	// SpawnLatency is the median time to spawn a trivial process and wait for its exit.
+	SpawnLatency time.Duration `json:"spawn_latency_ns"`
+	// LoopbackRTT is the median round trip time of a byte over a TCP loopback connection.
+	LoopbackRTT time.Duration `json:"loopback_rtt_ns"`
+	// DiskRead is the throughput of reading a freshly written file, in bytes per second.
+	DiskRead float64 `json:"disk_read_bytes_per_second"`
+	// DiskReadCached tells that the page cache could not be dropped, so that DiskRead is the
+	// throughput of the page cache more than of the disk.
+	DiskReadCached bool `json:"disk_read_cached,omitempty"`
+	// TimerResolution is the smallest step observed between readings of the monotonic clock.
+	TimerResolution time.Duration `json:"timer_resolution_ns"`
+	// SleepOvershoot is the median time a sleep of 1ms lasts longer than requested.
+	SleepOvershoot time.Duration `json:"sleep_overshoot_ns"`
+}
+
+const (
+	calibrationSpawns    = 20
+	calibrationRoundTrip = 1000
+	calibrationFileSize  = 32 << 20
+	calibrationSleeps    = 50
+)
+
+// CalibrateHost measures the HostProfile of the current host. The disk is measured in dir, or in
+// the default directory for temporary files when empty.
+func CalibrateHost(ctx context.Context, dir string) (*HostProfile, error) {
+	profile := &HostProfile{MeasuredAt: time.Now(), Host: CurrentHost()}
+	var err error
+	if profile.Host.CPUScore, err = MeasureCPUScore(ctx); err != nil {
+		return nil, err
+	}
+	profile.Host.CPUScoreWorkload = CPUScoreWorkload
+	if profile.SpawnLatency, err = spawnLatency(ctx); err != nil {
+		return nil, fmt.Errorf("unable to measure the spawn latency: %v", err)
+	}
+	if profile.LoopbackRTT, err = loopbackRTT(); err != nil {
+		return nil, fmt.Errorf("unable to measure the loopback round trip time: %v", err)
+	}
+	if profile.DiskRead, profile.DiskReadCached, err = diskReadThroughput(dir); err != nil {
+		return nil, fmt.Errorf("unable to measure the disk read throughput: %v", err)
+	}
+	profile.TimerResolution = timerResolution()
+	profile.SleepOvershoot, err = sleepOvershoot(ctx)
+	return profile, err
+}
+
+func spawnLatency(ctx context.Context) (time.Duration, error) {
+	name, err := exec.LookPath("true")
+	if err != nil {
+		return 0, err
+	}
+	latencies := make([]time.Duration, calibrationSpawns)
+	for i := range latencies {
+		start := time.Now()
+		if err := exec.CommandContext(ctx, name).Run(); err != nil {
+			return 0, err
+		}
+		latencies[i] = time.Since(start)
+	}
+	return Median(latencies), nil
+}
+
+func loopbackRTT() (time.Duration, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}()
+	conn, err := net.DialTimeout("tcp", listener.Addr().String(), time.Second)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetNoDelay(true)
+	}
+	rtts := make([]time.Duration, calibrationRoundTrip)
+	buffer := []byte{0}
+	for i := range rtts {
+		start := time.Now()
+		if _, err := conn.Write(buffer); err != nil {
+			return 0, err
+		}
+		if _, err := io.ReadFull(conn, buffer); err != nil {
+			return 0, err
+		}
+		rtts[i] = time.Since(start)
+	}
+	return Median(rtts), nil
+}
+
+// diskReadThroughput writes a file, drops it from the page cache when supported, and reads it back.
+func diskReadThroughput(dir string) (float64, bool, error) {
+	file, err := ioutil.TempFile(dir, "time-to-boot-server-calibration-")
+	if err != nil {
+		return 0, false, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	block := make([]byte, 1<<20)
+	for i := range block {
+		block[i] = byte(i)
+	}
+	for written := 0; written < calibrationFileSize; written += len(block) {
+		if _, err := file.Write(block); err != nil {
+			return 0, false, err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return 0, false, err
+	}
+	cached := !dropPageCache(file)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, false, err
+	}
+	start := time.Now()
+	read, err := io.CopyBuffer(ioutil.Discard, file, block)
+	if err != nil {
+		return 0, false, err
+	}
+	return float64(read) / time.Since(start).Seconds(), cached, nil
+}
+
+func timerResolution() time.Duration {
+	resolution := time.Duration(0)
+	for i := 0; i < 100000; i++ {
+		start := time.Now()
+		step := time.Since(start)
+		for step == 0 {
+			step = time.Since(start)
+		}
+		if resolution == 0 || step < resolution {
+			resolution = step
+		}
+	}
+	return resolution
+}
+
+func sleepOvershoot(ctx context.Context) (time.Duration, error) {
+	overshoots := make([]time.Duration, calibrationSleeps)
+	for i := range overshoots {
+		start := time.Now()
+		if err := sleep(ctx, time.Millisecond); err != nil {
+			return 0, err
+		}
+		overshoots[i] = time.Since(start) - time.Millisecond
+	}
+	return Median(overshoots), nil
+}
+
+// SaveHostProfile writes profile to path, creating its directory if needed.
+func SaveHostProfile(path string, profile *HostProfile) error {
+	data, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// ErrStaleHostProfile tells that a host profile was measured on another host, or on the same host
+// with another kernel or number of CPUs.
+var ErrStaleHostProfile = errors.New("the host profile was measured on another host, run calibrate again")
+
+// LoadHostProfile reads the host profile at path, or returns nil when there is none. A profile
+// that does not describe the current host is returned along with ErrStaleHostProfile.
+func LoadHostProfile(path string) (*HostProfile, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	profile := &HostProfile{}
+	if err := json.Unmarshal(data, profile); err != nil {
+		return nil, fmt.Errorf("invalid host profile %s: %v", path, err)
+	}
+	current := CurrentHost()
+	host := profile.Host
+	if host.Name != current.Name || host.OS != current.OS || host.Arch != current.Arch ||
+		host.CPUs != current.CPUs || host.Kernel != current.Kernel {
+		return profile, ErrStaleHostProfile
+	}
+	return profile, nil
+}
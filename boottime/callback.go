@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// CallbackOptions configures the callback mode.
+type CallbackOptions struct {
+	Port int `yaml:"port"` // local port of the callback listener, a free port when zero
+}
+
+// CallbackPlaceholder is replaced by the port of the callback listener in the command, arguments
+// and environment of the server in the callback mode.
+const CallbackPlaceholder = "{callback}"
+
+// CallbackURLVariable is the environment variable holding the URL the server posts to when ready
+// in the callback mode.
+const CallbackURLVariable = "BOOT_CALLBACK_URL"
+
+func init() {
+	RegisterProbe("phone-home", newPhoneHomeProbe)
+	RegisterProbe("callback", newCallbackProbe)
+}
+
+// callbackProbe listens for HTTP callbacks during each run, and succeeds once a POST request
+// reaches its path. Checks wait for callbacks instead of polling the server.
+type callbackProbe struct {
+	address string // listening address, as in :8000
+	path    string // any path when empty
+	server  *http.Server
+	calls   chan *http.Request
+	called  bool // a callback was received during the run
+}
+
+// newPhoneHomeProbe waits for the phone-home callback of cloud-init, or any POST request, on the
+// address given as target.
+func newPhoneHomeProbe(b *Benchmark) (Probe, error) {
+	if _, _, err := net.SplitHostPort(b.Target); err != nil {
+		return nil, fmt.Errorf("the phone-home mode needs a listening address as target, as in :8000: %v", err)
+	}
+	return &callbackProbe{address: b.Target}, nil
+}
+
+// newCallbackProbe waits for the server, or anything it spawns, to post to /ready on a local port.
+func newCallbackProbe(b *Benchmark) (Probe, error) {
+	port := b.Callback.Port
+	if port == 0 {
+		listener, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			return nil, err
+		}
+		port = listener.Addr().(*net.TCPAddr).Port
+		listener.Close()
+	}
+	return &callbackProbe{address: net.JoinHostPort("localhost", strconv.Itoa(port)), path: "/ready"}, nil
+}
+
+func (p *callbackProbe) Placeholders() map[string]string {
+	_, port, _ := net.SplitHostPort(p.address)
+	return map[string]string{CallbackPlaceholder: port}
+}
+
+func (p *callbackProbe) Environment() []string {
+	if len(p.path) == 0 {
+		return nil
+	}
+	return []string{CallbackURLVariable + "=http://" + p.address + p.path}
+}
+
+// Setup listens for callbacks, so that callbacks of previous runs are never taken into account.
+func (p *callbackProbe) Setup(ctx context.Context) error {
+	listener, err := net.Listen("tcp", p.address)
+	if err != nil {
+		return err
+	}
+	p.calls = make(chan *http.Request, 1)
+	p.called = false
+	p.server = &http.Server{Handler: http.HandlerFunc(p.serve)}
+	go p.server.Serve(listener)
+	return nil
+}
+
+func (p *callbackProbe) serve(w http.ResponseWriter, r *http.Request) {
+	if len(p.path) > 0 && r.URL.Path != p.path {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "callbacks must be POST requests", http.StatusMethodNotAllowed)
+		return
+	}
+	select {
+	case p.calls <- r:
+	default: // a callback is already pending
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *callbackProbe) Teardown() error {
+	return p.server.Close()
+}
+
+// Check waits for a callback. Readiness is latched once a callback was received, as servers call
+// back once, so that later checks of a stability window or of several required successes pass.
+func (p *callbackProbe) Check(ctx context.Context) (ProbeResult, error) {
+	if p.called {
+		return ProbeResult{}, nil
+	}
+	select {
+	case <-p.calls:
+		p.called = true
+		return ProbeResult{}, nil
+	case <-ctx.Done():
+		return ProbeResult{}, ctx.Err()
+	}
+}
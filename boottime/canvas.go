@@ -0,0 +1,197 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+)
+
+// canvas draws plots, in pixels from the top left corner.
+type canvas interface {
+	rect(x, y, w, h float64, fill color.RGBA)
+	line(x1, y1, x2, y2 float64, stroke color.RGBA)
+	circle(x, y, r float64, stroke color.RGBA)
+	polygon(xs, ys []float64, fill color.RGBA)
+	// text writes text centered on x, or starting at x when not centered, with its baseline at y.
+	text(x, y float64, s string, centered bool)
+	writeTo(w io.Writer) error
+}
+
+func cssColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// svgCanvas draws SVG documents.
+type svgCanvas struct {
+	width, height int
+	elements      []string
+}
+
+func (c *svgCanvas) rect(x, y, w, h float64, fill color.RGBA) {
+	c.elements = append(c.elements, fmt.Sprintf(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`, x, y, w, h, cssColor(fill)))
+}
+
+func (c *svgCanvas) line(x1, y1, x2, y2 float64, stroke color.RGBA) {
+	c.elements = append(c.elements, fmt.Sprintf(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`, x1, y1, x2, y2, cssColor(stroke)))
+}
+
+func (c *svgCanvas) circle(x, y, r float64, stroke color.RGBA) {
+	c.elements = append(c.elements, fmt.Sprintf(`<circle cx="%.1f" cy="%.1f" r="%.1f" fill="none" stroke="%s"/>`, x, y, r, cssColor(stroke)))
+}
+
+func (c *svgCanvas) polygon(xs, ys []float64, fill color.RGBA) {
+	points := make([]string, len(xs))
+	for i := range xs {
+		points[i] = fmt.Sprintf("%.1f,%.1f", xs[i], ys[i])
+	}
+	c.elements = append(c.elements, fmt.Sprintf(`<polygon points="%s" fill="%s"/>`, strings.Join(points, " "), cssColor(fill)))
+}
+
+func (c *svgCanvas) text(x, y float64, s string, centered bool) {
+	anchor := "start"
+	if centered {
+		anchor = "middle"
+	}
+	c.elements = append(c.elements, fmt.Sprintf(`<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`, x, y, anchor, html.EscapeString(s)))
+}
+
+func (c *svgCanvas) writeTo(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="Verdana,DejaVu Sans,sans-serif" font-size="11">
+<rect width="100%%" height="100%%" fill="#ffffff"/>
+%s
+</svg>
+`, c.width, c.height, strings.Join(c.elements, "\n"))
+	return err
+}
+
+// pngCanvas draws PNG images, with text in a small bitmap font.
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+func newPNGCanvas(width, height int) *pngCanvas {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	return &pngCanvas{img: img}
+}
+
+func (c *pngCanvas) rect(x, y, w, h float64, fill color.RGBA) {
+	for py := int(math.Round(y)); py < int(math.Round(y+h)); py++ {
+		for px := int(math.Round(x)); px < int(math.Round(x+w)); px++ {
+			c.img.SetRGBA(px, py, fill)
+		}
+	}
+}
+
+func (c *pngCanvas) line(x1, y1, x2, y2 float64, stroke color.RGBA) {
+	steps := int(math.Max(math.Abs(x2-x1), math.Abs(y2-y1))) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		c.img.SetRGBA(int(math.Round(x1+t*(x2-x1))), int(math.Round(y1+t*(y2-y1))), stroke)
+	}
+}
+
+func (c *pngCanvas) circle(x, y, r float64, stroke color.RGBA) {
+	steps := int(2*math.Pi*r) + 8
+	for i := 0; i < steps; i++ {
+		a := 2 * math.Pi * float64(i) / float64(steps)
+		c.img.SetRGBA(int(math.Round(x+r*math.Cos(a))), int(math.Round(y+r*math.Sin(a))), stroke)
+	}
+}
+
+// polygon fills a polygon with the even-odd rule, one scanline at a time.
+func (c *pngCanvas) polygon(xs, ys []float64, fill color.RGBA) {
+	bounds := c.img.Bounds()
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		y := float64(py) + 0.5
+		var crossings []float64
+		for i := range xs {
+			j := (i + 1) % len(xs)
+			if (ys[i] <= y) != (ys[j] <= y) {
+				crossings = append(crossings, xs[i]+(y-ys[i])/(ys[j]-ys[i])*(xs[j]-xs[i]))
+			}
+		}
+		for i := 1; i < len(crossings); i++ {
+			for j := i; j > 0 && crossings[j] < crossings[j-1]; j-- {
+				crossings[j], crossings[j-1] = crossings[j-1], crossings[j]
+			}
+		}
+		for i := 0; i+1 < len(crossings); i += 2 {
+			for px := int(math.Round(crossings[i])); px < int(math.Round(crossings[i+1])); px++ {
+				c.img.SetRGBA(px, py, fill)
+			}
+		}
+	}
+}
+
+// glyphs are 3x5 pixels, one bit per pixel from the top left, by row.
+var glyphs = map[rune]uint16{
+	'0': 0x7b6f, '1': 0x2c97, '2': 0x73e7, '3': 0x72cf, '4': 0x5bc9, '5': 0x79cf, '6': 0x79ef,
+	'7': 0x7292, '8': 0x7bef, '9': 0x7bcf, 'A': 0x2bed, 'B': 0x6bae, 'C': 0x3923, 'D': 0x6b6e,
+	'E': 0x79a7, 'F': 0x79a4, 'G': 0x396b, 'H': 0x5bed, 'I': 0x7497, 'J': 0x126a, 'K': 0x5bad,
+	'L': 0x4927, 'M': 0x5fed, 'N': 0x6b6d, 'O': 0x2b6a, 'P': 0x6ba4, 'Q': 0x2b73, 'R': 0x6bad,
+	'S': 0x388e, 'T': 0x7492, 'U': 0x5b6f, 'V': 0x5b6a, 'W': 0x5bfd, 'X': 0x5aad, 'Y': 0x5a92,
+	'Z': 0x72a7, '.': 0x0002, ',': 0x0014, ':': 0x0410, '-': 0x01c0, '+': 0x05d0, '(': 0x2922,
+	')': 0x224a, '/': 0x12a4, '%': 0x52a5, '_': 0x0007, '=': 0x0e38, '?': 0x6282,
+}
+
+// glyphScale is the size of the pixels of glyphs, and glyphAdvance the width of a character.
+const (
+	glyphScale   = 2
+	glyphAdvance = 4 * glyphScale
+)
+
+func (c *pngCanvas) text(x, y float64, s string, centered bool) {
+	s = strings.ToUpper(s)
+	if centered {
+		x -= float64(len([]rune(s))*glyphAdvance) / 2
+	}
+	top := int(y) - 5*glyphScale
+	black := color.RGBA{0, 0, 0, 0xff}
+	for i, r := range []rune(s) {
+		bits, found := glyphs[r]
+		if !found {
+			continue // spaces and unknown characters
+		}
+		left := int(x) + i*glyphAdvance
+		for bit := 0; bit < 15; bit++ {
+			if bits&(1<<uint(14-bit)) != 0 {
+				px, py := left+(bit%3)*glyphScale, top+(bit/3)*glyphScale
+				c.rect(float64(px), float64(py), glyphScale, glyphScale, black)
+			}
+		}
+	}
+}
+
+func (c *pngCanvas) writeTo(w io.Writer) error {
+	return png.Encode(w, c.img)
+}
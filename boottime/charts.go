@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+)
+
+// plotFailure marks failed runs.
+var plotFailure = color.RGBA{0xd6, 0x27, 0x28, 0xff}
+
+// plotLeft is the left margin of plots with a vertical axis, wide enough for its labels.
+const plotLeft = 56
+
+// HistogramSVG returns an SVG document plotting the histogram of the boot times of the measured
+// runs of results, with buckets of equal width.
+func HistogramSVG(results *Results, buckets int) (string, error) {
+	d, err := newDistribution(results)
+	if err != nil {
+		return "", err
+	}
+	if buckets < 1 {
+		return "", fmt.Errorf("invalid number of buckets: %d", buckets)
+	}
+	d.title = "Distribution of the b" + d.title[1:]
+	c := &svgCanvas{width: plotWidth, height: plotHeight}
+	d.axes(c)
+	first, last := d.values[0], d.values[len(d.values)-1]
+	if first == last {
+		buckets = 1
+	}
+	width := (last - first) / float64(buckets)
+	counts := make([]int, buckets)
+	highest := 0
+	for _, v := range d.values {
+		i := buckets - 1
+		if width > 0 {
+			i = int(math.Min((v-first)/width, float64(buckets-1)))
+		}
+		counts[i]++
+		highest = int(math.Max(float64(highest), float64(counts[i])))
+	}
+	for i, count := range counts {
+		if count == 0 {
+			continue
+		}
+		x1, x2 := d.x(first+float64(i)*width), d.x(first+float64(i+1)*width)
+		if width == 0 {
+			x1, x2 = d.x(first)-4, d.x(first)+4
+		}
+		height := float64(count) / float64(highest) * (plotBottom - plotTop - 14)
+		c.rect(x1+1, plotBottom-height, math.Max(x2-x1-2, 1), height, plotFill)
+		c.text((x1+x2)/2, plotBottom-height-4, strconv.Itoa(count), true)
+	}
+	var b bytes.Buffer
+	err = c.writeTo(&b)
+	return b.String(), err
+}
+
+// RunsSVG returns an SVG document plotting the boot time of the measured runs of results in the
+// order they ran, failed runs being crosses at the bottom, which shows trends such as warming up.
+func RunsSVG(results *Results) (string, error) {
+	d, err := newDistribution(results)
+	if err != nil {
+		return "", err
+	}
+	var runs []Run
+	for _, run := range results.Runs {
+		if !run.Dry {
+			runs = append(runs, run)
+		}
+	}
+	c := &svgCanvas{width: plotWidth, height: plotHeight}
+	c.text(plotWidth/2, 24, "Runs in order", true)
+	y := func(v float64) float64 {
+		return plotBottom - (v-d.min)/(d.max-d.min)*(plotBottom-plotTop)
+	}
+	x := func(i int) float64 {
+		if len(runs) == 1 {
+			return (plotLeft + plotWidth - plotMargin) / 2
+		}
+		return plotLeft + float64(i)/float64(len(runs)-1)*(plotWidth-plotMargin-plotLeft)
+	}
+	step := niceStep(d.max - d.min)
+	for tick := math.Ceil(d.min/step) * step; tick <= d.max; tick += step {
+		c.line(plotLeft, y(tick), plotWidth-plotMargin, y(tick), plotGrid)
+		c.text(plotLeft-40, y(tick)+4, strconv.FormatFloat(tick, 'f', -1, 64), false)
+	}
+	c.line(plotLeft, plotTop, plotLeft, plotBottom, plotStroke)
+	c.line(plotLeft, plotBottom, plotWidth-plotMargin, plotBottom, plotStroke)
+	c.text(plotLeft-40, plotTop-18, "ms", false)
+	c.text(plotWidth/2, plotBottom+18, "run", true)
+	for i, run := range runs {
+		if run.Failed() {
+			c.line(x(i)-4, plotBottom-8, x(i)+4, plotBottom, plotFailure)
+			c.line(x(i)-4, plotBottom, x(i)+4, plotBottom-8, plotFailure)
+			continue
+		}
+		c.circle(x(i), y(millis(run.Duration)), 3, plotFill)
+	}
+	var b bytes.Buffer
+	err = c.writeTo(&b)
+	return b.String(), err
+}
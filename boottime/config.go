@@ -0,0 +1,354 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Scenario is a benchmark definition from a configuration file.
+type Scenario struct {
+	Name        string            `yaml:"name"`
+	Extends     string            `yaml:"extends"` // name of the scenario this one inherits from
+	Description string            `yaml:"description"`
+	Hypothesis  string            `yaml:"hypothesis"` // the expected outcome, as in "20% faster than jvm"
+	Tags        []string          `yaml:"tags"`       // to select scenarios, as in "native"
+	Profile     string            `yaml:"profile"`    // see Profiles
+	Mode        string            `yaml:"mode"`
+	Target      string            `yaml:"target"`
+	AutoTarget  bool              `yaml:"auto_target"`
+	Executable  string            `yaml:"executable"`
+	Args        []string          `yaml:"args"`
+	WorkDir     string            `yaml:"workdir"`
+	StdinFile   string            `yaml:"stdin_file"`
+	CloseStdin  bool              `yaml:"close_stdin"`
+	Launcher    string            `yaml:"launcher"`
+	Checkpoint  string            `yaml:"checkpoint"`
+	Deploy      DeployOptions     `yaml:"deploy"`
+	Systemd     SystemdOptions    `yaml:"systemd"`
+	Docker      DockerOptions     `yaml:"docker"`
+	Kubernetes  KubernetesOptions `yaml:"kubernetes"`
+	Env         map[string]string `yaml:"env"`
+	DryRuns     int               `yaml:"dry_runs"`
+	Runs        int               `yaml:"runs"`
+	Pause       Duration          `yaml:"pause"`
+	RunTimeout  Duration          `yaml:"run_timeout"`
+	OnFailure   string            `yaml:"on_failure"`
+	Settle      bool              `yaml:"settle"`
+	HTTP        HTTPOptions       `yaml:"http"`
+	TCP         TCPOptions        `yaml:"tcp"`
+	Prom        PromOptions       `yaml:"prom"`
+	Health      HealthOptions     `yaml:"health"`
+	Callback    CallbackOptions   `yaml:"callback"`
+	File        FileOptions       `yaml:"file"`
+	LogFile     LogFileOptions    `yaml:"logfile"`
+	LogMatch    LogMatchOptions   `yaml:"log_match"`
+
+	ExpectedFail         bool              `yaml:"expected_fail"` // failures do not fail the session
+	ReadyTimeout         Duration          `yaml:"ready_timeout"`
+	MaxProbeRate         float64           `yaml:"max_probe_rate"`
+	PollInterval         Duration          `yaml:"poll_interval"`
+	PollBackoff          float64           `yaml:"poll_backoff"`
+	PollMaxInterval      Duration          `yaml:"poll_max_interval"`
+	ReadyAfterRequests   int               `yaml:"ready_after_requests"`
+	StableFor            Duration          `yaml:"stable_for"`
+	CalibrationRuns      int               `yaml:"calibration_runs"`
+	CalibrationProbeRate float64           `yaml:"calibration_probe_rate"`
+	JVMMetrics           bool              `yaml:"jvm_metrics"`
+	ReservedCPUs         int               `yaml:"reserve_cpus"`
+	CPUScore             bool              `yaml:"cpu_score"`
+	ReadOnlyRootfs       bool              `yaml:"read_only_rootfs"`
+	Capabilities         string            `yaml:"capabilities"`
+	Seccomp              string            `yaml:"seccomp"`
+	UpgradeSignal        string            `yaml:"upgrade_signal"`
+	CrashRecovery        bool              `yaml:"crash_recovery"`
+	ShutdownSignal       string            `yaml:"shutdown_signal"`
+	ShutdownGrace        Duration          `yaml:"shutdown_grace"`
+	LingeringSockets     string            `yaml:"lingering_sockets"`
+	WatchPorts           map[string]string `yaml:"watch_ports"`
+	Milestones           []LifecycleEvent  `yaml:"milestones"`
+	Events               []LifecycleEvent  `yaml:"events"`
+}
+
+// Duration is a duration of configuration files, as in 1m30s, where bare numbers are seconds as on
+// the command line.
+type Duration time.Duration
+
+// UnmarshalYAML decodes a duration such as 1m30s, or a number of seconds.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration: %s (expected a duration such as 1m30s, or a number of seconds)", value)
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Config is the content of a configuration file, as in:
+//
+//	defaults:
+//	  target: http://localhost:8080/
+//	  runs: 10
+//	scenarios:
+//	  - name: jvm
+//	    executable: java
+//	    args: [-jar, app.jar]
+//	  - name: jvm-small-heap
+//	    extends: jvm
+//	    args: [-Xmx64m, -jar, app.jar]
+//
+// The settings of a scenario are taken from, by increasing priority, the built-in defaults, the
+// profile if any, the defaults block, the scenario it extends (recursively), and the scenario itself. Nested blocks
+// such as http and env are merged key by key, while lists such as args are replaced.
+//
+// An smtp block sets how reports are emailed, see SMTPSettings.
+type Config struct {
+	Scenarios []Scenario
+	SMTP      SMTPSettings
+}
+
+// builtinDefaults are the settings of scenarios that neither they nor the defaults block specify.
+var builtinDefaults = map[interface{}]interface{}{
+	"mode":     "http-get",
+	"target":   "http://localhost:8080/",
+	"launcher": DefaultLauncher,
+	"dry_runs": 2,
+	"runs":     20,
+	"pause":    "10s",
+}
+
+type rawConfig struct {
+	Defaults  map[interface{}]interface{}   `yaml:"defaults"`
+	Scenarios []map[interface{}]interface{} `yaml:"scenarios"`
+	SMTP      SMTPSettings                  `yaml:"smtp"`
+}
+
+// LoadConfig reads a configuration file and resolves the settings of its scenarios.
+func LoadConfig(path string) (*Config, error) {
+	return LoadConfigWithProfile(path, "")
+}
+
+// LoadConfigWithProfile reads a configuration file like LoadConfig, with the profile of the
+// scenarios that do not set one.
+func LoadConfigWithProfile(path string, profile string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := parseConfig(data, profile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses the content of a configuration file and resolves the settings of its scenarios.
+func ParseConfig(data []byte) (*Config, error) {
+	return parseConfig(data, "")
+}
+
+func parseConfig(data []byte, defaultProfile string) (*Config, error) {
+	var raw rawConfig
+	if err := yaml.UnmarshalStrict(data, &raw); err != nil {
+		return nil, err
+	}
+	byName := make(map[string]map[interface{}]interface{})
+	var names []string
+	for i, scenario := range raw.Scenarios {
+		name, _ := scenario["name"].(string)
+		if len(name) == 0 {
+			return nil, fmt.Errorf("scenario #%d has no name", i+1)
+		}
+		if _, found := byName[name]; found {
+			return nil, fmt.Errorf("duplicate scenario: %s", name)
+		}
+		byName[name] = scenario
+		names = append(names, name)
+	}
+	base := merge(builtinDefaults, raw.Defaults)
+	config := &Config{SMTP: raw.SMTP}
+	for _, name := range names {
+		settings, err := resolve(name, byName, nil)
+		if err != nil {
+			return nil, err
+		}
+		resolved := merge(base, settings)
+		profileName, _ := resolved["profile"].(string)
+		if len(profileName) == 0 {
+			profileName = defaultProfile
+		}
+		if len(profileName) > 0 {
+			// Profiles preset settings that the configuration file does not set.
+			profile, err := LookupProfile(profileName)
+			if err != nil {
+				return nil, fmt.Errorf("scenario %s: %v", name, err)
+			}
+			resolved = merge(merge(builtinDefaults, profile.settings()), merge(raw.Defaults, settings))
+			resolved["profile"] = profileName
+		}
+		scenario, err := decodeScenario(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s: %v", name, err)
+		}
+		config.Scenarios = append(config.Scenarios, scenario)
+	}
+	return config, nil
+}
+
+// resolve returns the settings of a scenario merged over those of the scenarios it extends.
+func resolve(name string, byName map[string]map[interface{}]interface{}, visiting []string) (map[interface{}]interface{}, error) {
+	for _, visited := range visiting {
+		if visited == name {
+			return nil, fmt.Errorf("scenario %s extends itself through %v", name, visiting)
+		}
+	}
+	scenario, found := byName[name]
+	if !found {
+		return nil, fmt.Errorf("scenario %s extends an unknown scenario: %s", visiting[len(visiting)-1], name)
+	}
+	parentName, _ := scenario["extends"].(string)
+	if len(parentName) == 0 {
+		return scenario, nil
+	}
+	parent, err := resolve(parentName, byName, append(visiting, name))
+	if err != nil {
+		return nil, err
+	}
+	return merge(parent, scenario), nil
+}
+
+// merge returns the settings of base overridden by those of override, merging nested maps.
+func merge(base, override map[interface{}]interface{}) map[interface{}]interface{} {
+	merged := make(map[interface{}]interface{}, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		nestedOverride, overrideIsMap := value.(map[interface{}]interface{})
+		nestedBase, baseIsMap := merged[key].(map[interface{}]interface{})
+		if overrideIsMap && baseIsMap {
+			merged[key] = merge(nestedBase, nestedOverride)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+func decodeScenario(settings map[interface{}]interface{}) (Scenario, error) {
+	var scenario Scenario
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return scenario, err
+	}
+	err = yaml.UnmarshalStrict(data, &scenario)
+	return scenario, err
+}
+
+// Benchmark creates the benchmark of the scenario.
+// Environment variable values may reference secrets, see ResolveSecret.
+func (s Scenario) Benchmark() (*Benchmark, error) {
+	env := make([]string, 0, len(s.Env))
+	for key, value := range s.Env {
+		resolved, err := ResolveSecret(value)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s, environment variable %s: %v", s.Name, key, err)
+		}
+		env = append(env, key+"="+resolved)
+	}
+	sort.Strings(env)
+	return &Benchmark{
+		Name:        s.Name,
+		Description: s.Description,
+		Profile:     s.Profile,
+		Hypothesis:  s.Hypothesis,
+		Mode:        s.Mode,
+		Target:      s.Target,
+		AutoTarget:  s.AutoTarget,
+		Command:     s.Executable,
+		Args:        s.Args,
+		WorkDir:     s.WorkDir,
+		StdinFile:   s.StdinFile,
+		CloseStdin:  s.CloseStdin,
+		Launcher:    s.Launcher,
+		Checkpoint:  s.Checkpoint,
+		Deploy:      s.Deploy,
+		Systemd:     s.Systemd,
+		Docker:      s.Docker,
+		Kubernetes:  s.Kubernetes,
+		Env:         env,
+		DryRuns:     s.DryRuns,
+		Runs:        s.Runs,
+		Pause:       time.Duration(s.Pause),
+		RunTimeout:  time.Duration(s.RunTimeout),
+		OnFailure:   s.OnFailure,
+		Settle:      s.Settle,
+		HTTP:        s.HTTP,
+		TCP:         s.TCP,
+		Prom:        s.Prom,
+		Health:      s.Health,
+		Callback:    s.Callback,
+		File:        s.File,
+		LogFile:     s.LogFile,
+		LogMatch:    s.LogMatch,
+
+		ExpectedFail:         s.ExpectedFail,
+		ReadyTimeout:         time.Duration(s.ReadyTimeout),
+		MaxProbeRate:         s.MaxProbeRate,
+		PollInterval:         time.Duration(s.PollInterval),
+		PollBackoff:          s.PollBackoff,
+		PollMaxInterval:      time.Duration(s.PollMaxInterval),
+		ReadyAfterRequests:   s.ReadyAfterRequests,
+		StableFor:            time.Duration(s.StableFor),
+		CalibrationRuns:      s.CalibrationRuns,
+		CalibrationProbeRate: s.CalibrationProbeRate,
+		CollectJVMMetrics:    s.JVMMetrics,
+		ReservedCPUs:         s.ReservedCPUs,
+		MeasureCPUScore:      s.CPUScore,
+		ReadOnlyRootfs:       s.ReadOnlyRootfs,
+		Capabilities:         s.Capabilities,
+		Seccomp:              s.Seccomp,
+		UpgradeSignal:        s.UpgradeSignal,
+		CrashRecovery:        s.CrashRecovery,
+		ShutdownSignal:       s.ShutdownSignal,
+		ShutdownGrace:        time.Duration(s.ShutdownGrace),
+		LingeringSockets:     s.LingeringSockets,
+		WatchPorts:           s.WatchPorts,
+		Milestones:           s.Milestones,
+		Events:               s.Events,
+	}, nil
+}
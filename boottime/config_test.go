@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigDurations(t *testing.T) {
+	for _, c := range []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"10", 10 * time.Second},
+		{"1.5", 1500 * time.Millisecond},
+		{"0", 0},
+		{"1m30s", 90 * time.Second},
+		{"250ms", 250 * time.Millisecond},
+		{`"30"`, 30 * time.Second},
+	} {
+		config, err := ParseConfig([]byte("scenarios:\n  - name: api\n    executable: api\n    pause: " + c.value +
+			"\n    run_timeout: " + c.value + "\n    http:\n      timeout: " + c.value + "\n"))
+		if err != nil {
+			t.Errorf("%s: %v", c.value, err)
+			continue
+		}
+		b, err := config.Scenarios[0].Benchmark()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Pause != c.expected || b.RunTimeout != c.expected || time.Duration(b.HTTP.Timeout) != c.expected {
+			t.Errorf("%s: expected %s, got a pause of %s, a run timeout of %s and an HTTP timeout of %s",
+				c.value, c.expected, b.Pause, b.RunTimeout, time.Duration(b.HTTP.Timeout))
+		}
+	}
+}
+
+func TestConfigInvalidDuration(t *testing.T) {
+	_, err := ParseConfig([]byte("scenarios:\n  - name: api\n    executable: api\n    pause: soon\n"))
+	if err == nil || !strings.Contains(err.Error(), "invalid duration: soon") {
+		t.Errorf("expected an invalid duration error, got %v", err)
+	}
+}
+
+func TestConfigDefaultPause(t *testing.T) {
+	config, err := ParseConfig([]byte("defaults:\n  pause: 3\nscenarios:\n  - name: api\n    executable: api\n  - name: quick\n    extends: api\n    pause: 0.5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []time.Duration{3 * time.Second, 500 * time.Millisecond} {
+		if pause := time.Duration(config.Scenarios[i].Pause); pause != expected {
+			t.Errorf("%s: expected a pause of %s, got %s", config.Scenarios[i].Name, expected, pause)
+		}
+	}
+}
+
+func TestConfigMerge(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+defaults:
+  runs: 5
+  env: {A: defaults, B: defaults}
+scenarios:
+  - name: base
+    executable: server
+    args: [--base]
+    env: {B: base}
+  - name: child
+    extends: base
+    args: [--child]
+    env: {C: child}
+  - name: grandchild
+    extends: child
+    runs: 3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range []struct {
+		name       string
+		executable string
+		args       string
+		env        map[string]string
+		runs       int
+		dryRuns    int
+	}{
+		{"base", "server", "--base", map[string]string{"A": "defaults", "B": "base"}, 5, 2},
+		{"child", "server", "--child", map[string]string{"A": "defaults", "B": "base", "C": "child"}, 5, 2},
+		{"grandchild", "server", "--child", map[string]string{"A": "defaults", "B": "base", "C": "child"}, 3, 2},
+	} {
+		s := config.Scenarios[i]
+		if s.Name != c.name || s.Executable != c.executable || strings.Join(s.Args, " ") != c.args || s.Runs != c.runs || s.DryRuns != c.dryRuns {
+			t.Errorf("%s: expected %s %s with %d runs and %d dry runs, got %s %s %v with %d runs and %d dry runs",
+				c.name, c.executable, c.args, c.runs, c.dryRuns, s.Name, s.Executable, s.Args, s.Runs, s.DryRuns)
+		}
+		if len(s.Env) != len(c.env) {
+			t.Errorf("%s: expected the environment %v, got %v", c.name, c.env, s.Env)
+			continue
+		}
+		for key, value := range c.env {
+			if s.Env[key] != value {
+				t.Errorf("%s: expected the environment %v, got %v", c.name, c.env, s.Env)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// containerDependency is a dependency running in a container started with testcontainers-go.
+type containerDependency struct {
+	name      string
+	request   testcontainers.ContainerRequest
+	container testcontainers.Container
+}
+
+// ContainerDependency returns a dependency running in a container started with testcontainers-go,
+// as in:
+//
+//	b.Dependencies = append(b.Dependencies, boottime.ContainerDependency("postgres", testcontainers.ContainerRequest{
+//		Image:        "postgres:16",
+//		ExposedPorts: []string{"5432/tcp"},
+//		Env:          map[string]string{"POSTGRES_PASSWORD": "secret"},
+//		WaitingFor:   wait.ForListeningPort("5432/tcp"),
+//	}))
+//
+// Its placeholders are {name.host}, the host where its ports are exposed, and {name.port:port} for
+// each exposed port, the port mapped on that host, as in {postgres.port:5432}. Ports of other
+// protocols than TCP keep their protocol, as in {dns.port:53/udp}.
+func ContainerDependency(name string, request testcontainers.ContainerRequest) Dependency {
+	return &containerDependency{name: name, request: request}
+}
+
+func (d *containerDependency) Start(ctx context.Context) (map[string]string, error) {
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{ContainerRequest: d.request, Started: true})
+	if container != nil {
+		d.container = container
+	}
+	if err != nil {
+		return nil, fmt.Errorf("container %s: %v", d.name, err)
+	}
+	host, err := container.Host(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("container %s: %v", d.name, err)
+	}
+	placeholders := map[string]string{"{" + d.name + ".host}": host}
+	for _, exposed := range d.request.ExposedPorts {
+		port := exposed
+		if i := strings.LastIndexByte(port, ':'); i >= 0 {
+			port = port[i+1:] // host port bindings, as in 127.0.0.1:5432:5432/tcp
+		}
+		mapped, err := container.MappedPort(ctx, port)
+		if err != nil {
+			return nil, fmt.Errorf("container %s: %v", d.name, err)
+		}
+		placeholders["{"+d.name+".port:"+strings.TrimSuffix(port, "/tcp")+"}"] = mapped.Port()
+	}
+	return placeholders, nil
+}
+
+func (d *containerDependency) Stop(ctx context.Context) error {
+	if d.container == nil {
+		return nil
+	}
+	err := d.container.Terminate(ctx)
+	d.container = nil
+	if err != nil {
+		return fmt.Errorf("container %s: %v", d.name, err)
+	}
+	return nil
+}
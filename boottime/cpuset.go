@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// CPUReservation tells which CPUs the benchmark and the server ran on, see Benchmark.ReservedCPUs.
+type CPUReservation struct {
+	Tool   []int `json:"tool"`
+	Server []int `json:"server"`
+}
+
+// reserveCPUs pins the program to the last count CPUs it may run on, and returns the reservation
+// along with a function restoring the previous affinity.
+func reserveCPUs(count int) (*CPUReservation, func(), error) {
+	cpus, err := processAffinity()
+	if err != nil {
+		return nil, nil, err
+	}
+	if count >= len(cpus) {
+		return nil, nil, fmt.Errorf("unable to reserve %d CPUs out of %d, the server needs at least one", count, len(cpus))
+	}
+	reservation := &CPUReservation{Tool: cpus[len(cpus)-count:], Server: cpus[:len(cpus)-count]}
+	if err := setProcessAffinity(reservation.Tool); err != nil {
+		return nil, nil, err
+	}
+	procs := runtime.GOMAXPROCS(count)
+	return reservation, func() {
+		runtime.GOMAXPROCS(procs)
+		setProcessAffinity(cpus)
+	}, nil
+}
+
+// formatCPUList formats CPUs as a list for taskset, as in 0,1,2.
+func formatCPUList(cpus []int) string {
+	list := make([]string, len(cpus))
+	for i, cpu := range cpus {
+		list[i] = strconv.Itoa(cpu)
+	}
+	return strings.Join(list, ",")
+}
+
+// cpuPinnable launchers can run the server on a list of CPUs.
+type cpuPinnable interface {
+	pinCPUs(list string)
+}
+
+func (l *processLauncher) pinCPUs(list string) {
+	l.cpus = list
+}
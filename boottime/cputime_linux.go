@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTick is the unit of the CPU times of /proc, USER_HZ, which is 100 Hz on every architecture.
+const clockTick = 10 * time.Millisecond
+
+// readCPUTime sums the CPU times of /proc/<pid>/stat for the process and its live descendants,
+// along with those of the descendants that exited and were waited for.
+func readCPUTime(pid int) (*CPUTime, error) {
+	stats, err := readProcStats()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := processTree(stats, pid)
+	if err != nil {
+		return nil, err
+	}
+	cpu := &CPUTime{}
+	for _, id := range tree {
+		fields := stats[id]
+		var values [4]int64 // utime, stime, cutime and cstime, fields 14 to 17
+		for i, field := range fields[11:15] {
+			if values[i], err = strconv.ParseInt(field, 10, 64); err != nil {
+				return nil, fmt.Errorf("unexpected stat content of process %d: %q", id, strings.Join(fields, " "))
+			}
+		}
+		cpu.User += time.Duration(values[0]+values[2]) * clockTick
+		cpu.System += time.Duration(values[1]+values[3]) * clockTick
+	}
+	return cpu, nil
+}
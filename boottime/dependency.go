@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Dependency is an ephemeral dependency of the server, such as a database in a container, started
+// before the runs of a benchmark and stopped after them.
+type Dependency interface {
+	// Start starts the dependency and returns the values of its placeholders, such as
+	// {postgres.port:5432}, which are replaced in the command, arguments, environment and target of
+	// the benchmark, so that the server finds dependencies listening on dynamic ports.
+	Start(ctx context.Context) (map[string]string, error)
+	// Stop stops the dependency, and is also called when Start failed to release what it created.
+	Stop(ctx context.Context) error
+}
+
+// startDependencies starts the dependencies of b in order, and returns their placeholders and a
+// function stopping them in reverse order.
+func (b *Benchmark) startDependencies(ctx context.Context) (map[string]string, func(), error) {
+	placeholders := make(map[string]string)
+	var started []Dependency
+	stop := func() {
+		for i := len(started) - 1; i >= 0; i-- {
+			// Dependencies are stopped even when the benchmark was interrupted.
+			if err := started[i].Stop(context.Background()); err != nil {
+				b.Logger.Warn("unable to stop a dependency", "error", err)
+			}
+		}
+	}
+	for i, dependency := range b.Dependencies {
+		values, err := dependency.Start(ctx)
+		started = append(started, dependency)
+		if err != nil {
+			stop()
+			return nil, nil, fmt.Errorf("unable to start dependency %d: %v", i+1, err)
+		}
+		for placeholder, value := range values {
+			placeholders[placeholder] = value
+		}
+		b.Logger.Debug("dependency started", "placeholders", len(values))
+	}
+	return placeholders, stop, nil
+}
+
+// expand returns a copy of b whose command, arguments, environment and target have their
+// placeholders replaced.
+func (b *Benchmark) expand(placeholders map[string]string) *Benchmark {
+	expanded := *b
+	replace := func(s string) string {
+		for placeholder, value := range placeholders {
+			s = strings.Replace(s, placeholder, value, -1)
+		}
+		return s
+	}
+	expanded.Command = replace(b.Command)
+	expanded.Target = replace(b.Target)
+	expanded.Args = make([]string, len(b.Args))
+	for i, arg := range b.Args {
+		expanded.Args[i] = replace(arg)
+	}
+	expanded.Env = make([]string, 0, len(b.Env))
+	for _, variable := range b.Env {
+		expanded.Env = append(expanded.Env, replace(variable))
+	}
+	return &expanded
+}
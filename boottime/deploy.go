@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeployOptions configures the deploy launcher.
+type DeployOptions struct {
+	// Teardown is a shell command run after each run to remove what the deploy command created,
+	// so that the next run starts cold. Nothing is run when empty.
+	Teardown string `yaml:"teardown"`
+}
+
+// DeployRunVariable is the environment variable holding a unique identifier of the run, passed to
+// the deploy and teardown commands, as in a revision tag.
+const DeployRunVariable = "BOOT_RUN_ID"
+
+// RemoteProbeTimeout is the timeout of probe attempts when the benchmark does not set one and the
+// server runs on a remote platform, with the deploy, infra and kubernetes launchers, where unanswered
+// connections would otherwise block probing for minutes.
+const RemoteProbeTimeout = 30 * time.Second
+
+var remoteLaunchers = map[string]bool{"deploy": true, "infra": true, "kubernetes": true}
+
+func (b *Benchmark) probeTimeout(timeout time.Duration) time.Duration {
+	if timeout == 0 && remoteLaunchers[b.Launcher] {
+		return RemoteProbeTimeout
+	}
+	return timeout
+}
+
+func init() {
+	RegisterLauncher("deploy", newDeployLauncher)
+	RegisterLauncher("infra", newInfraLauncher)
+}
+
+// deployLauncher runs a command that deploys the server to a remote platform, as a shell command
+// line with the arguments appended. The server is probed on its public endpoint while the command
+// runs, and the command may exit before the server is ready.
+type deployLauncher struct {
+	*processLauncher
+	teardown string
+}
+
+func newDeployLauncher(b *Benchmark) (Launcher, error) {
+	shell, err := newShellLauncher(b)
+	if err != nil {
+		return nil, err
+	}
+	process := shell.(*processLauncher)
+	process.env = append(process.env, DeployRunVariable+"="+strconv.FormatInt(time.Now().UnixNano(), 36))
+	return &deployLauncher{processLauncher: process, teardown: b.Deploy.Teardown}, nil
+}
+
+// infraLauncher runs an infrastructure apply command, as in terraform apply or cdk deploy, whose
+// teardown destroys the infrastructure. Once the server is ready the apply command is left to
+// complete rather than killed, so that the infrastructure state stays consistent.
+type infraLauncher struct {
+	*deployLauncher
+}
+
+func newInfraLauncher(b *Benchmark) (Launcher, error) {
+	if len(b.Deploy.Teardown) == 0 {
+		return nil, fmt.Errorf("the infra launcher needs a teardown command destroying the infrastructure")
+	}
+	deploy, err := newDeployLauncher(b)
+	if err != nil {
+		return nil, err
+	}
+	return &infraLauncher{deploy.(*deployLauncher)}, nil
+}
+
+// Signal only forwards signals other than os.Kill, cancelling the benchmark still kills the command.
+func (l *infraLauncher) Signal(sig os.Signal) error {
+	if sig == os.Kill {
+		return nil
+	}
+	return l.deployLauncher.Signal(sig)
+}
+
+// Pid returns 0 since the server does not run on the local host.
+func (l *deployLauncher) Pid() int {
+	return 0
+}
+
+func (l *deployLauncher) Cleanup() error {
+	if len(l.teardown) == 0 {
+		return nil
+	}
+	cmd := exec.Command("/bin/sh", "-c", l.teardown)
+	cmd.Env = append(os.Environ(), l.env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("teardown failed: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterExporter("boxplot", newDistributionExporter(boxplot))
+	RegisterExporter("violin", newDistributionExporter(violin))
+}
+
+// Layout of distribution plots, in pixels.
+const (
+	plotWidth  = 640
+	plotHeight = 220
+	plotMargin = 24
+	plotTop    = 40  // below the title
+	plotBottom = 180 // above the axis
+)
+
+var (
+	plotFill   = color.RGBA{0x4c, 0x78, 0xa8, 0xff}
+	plotStroke = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	plotGrid   = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+	plotWhite  = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// distribution are the boot times of measured runs in milliseconds, sorted, as plotted.
+type distribution struct {
+	title    string
+	values   []float64
+	min, max float64 // of the axis
+}
+
+// x returns the horizontal position of a value.
+func (d *distribution) x(v float64) float64 {
+	return plotMargin + (v-d.min)/(d.max-d.min)*(plotWidth-2*plotMargin)
+}
+
+// quantile interpolates linearly between the closest values.
+func (d *distribution) quantile(q float64) float64 {
+	position := q * float64(len(d.values)-1)
+	i := int(position)
+	if i+1 >= len(d.values) {
+		return d.values[len(d.values)-1]
+	}
+	return d.values[i] + (position-float64(i))*(d.values[i+1]-d.values[i])
+}
+
+// niceStep returns a step of 1, 2 or 5 times a power of 10 giving about 6 ticks over span.
+func niceStep(span float64) float64 {
+	raw := span / 6
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, factor := range []float64{1, 2, 5} {
+		if factor*magnitude >= raw {
+			return factor * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// axes draws the title, the grid and the axis in milliseconds.
+func (d *distribution) axes(c canvas) {
+	c.text(plotWidth/2, 24, d.title, true)
+	step := niceStep(d.max - d.min)
+	for tick := math.Ceil(d.min/step) * step; tick <= d.max; tick += step {
+		x := d.x(tick)
+		c.line(x, plotTop, x, plotBottom, plotGrid)
+		c.line(x, plotBottom, x, plotBottom+4, plotStroke)
+		c.text(x, plotBottom+18, strconv.FormatFloat(tick, 'f', -1, 64), true)
+	}
+	c.line(plotMargin, plotBottom, plotWidth-plotMargin, plotBottom, plotStroke)
+	c.text(plotWidth/2, plotBottom+34, "ms", true)
+}
+
+// boxplot draws the quartiles as a box, with whiskers to the furthest values within 1.5 times the
+// interquartile range, and the values beyond as outliers.
+func boxplot(d *distribution, c canvas) {
+	q1, q2, q3 := d.quantile(0.25), d.quantile(0.5), d.quantile(0.75)
+	low, high := q1-1.5*(q3-q1), q3+1.5*(q3-q1)
+	lowWhisker, highWhisker := q1, q3
+	for _, v := range d.values {
+		if v >= low && v < lowWhisker {
+			lowWhisker = v
+		}
+		if v <= high && v > highWhisker {
+			highWhisker = v
+		}
+	}
+	middle := float64(plotTop+plotBottom) / 2
+	c.line(d.x(lowWhisker), middle, d.x(q1), middle, plotStroke)
+	c.line(d.x(q3), middle, d.x(highWhisker), middle, plotStroke)
+	c.line(d.x(lowWhisker), middle-12, d.x(lowWhisker), middle+12, plotStroke)
+	c.line(d.x(highWhisker), middle-12, d.x(highWhisker), middle+12, plotStroke)
+	c.rect(d.x(q1), middle-25, math.Max(d.x(q3)-d.x(q1), 1), 50, plotFill)
+	c.line(d.x(q2), middle-25, d.x(q2), middle+25, plotWhite)
+	for _, v := range d.values {
+		if v < low || v > high {
+			c.circle(d.x(v), middle, 3, plotStroke)
+		}
+	}
+}
+
+// violin draws the density of the values, estimated with Gaussian kernels and the bandwidth of
+// Silverman's rule of thumb, with the quartiles and the median inside.
+func violin(d *distribution, c canvas) {
+	n := float64(len(d.values))
+	var sum, squares float64
+	for _, v := range d.values {
+		sum += v
+	}
+	for _, v := range d.values {
+		squares += (v - sum/n) * (v - sum/n)
+	}
+	spread := math.Sqrt(squares / math.Max(n-1, 1))
+	if iqr := (d.quantile(0.75) - d.quantile(0.25)) / 1.34; iqr > 0 && iqr < spread {
+		spread = iqr
+	}
+	bandwidth := 0.9 * spread * math.Pow(n, -0.2)
+	if bandwidth <= 0 {
+		bandwidth = (d.max - d.min) / 50
+	}
+	const points = 120
+	densities := make([]float64, points+1)
+	peak := 0.0
+	for i := range densities {
+		x := d.min + float64(i)/points*(d.max-d.min)
+		for _, v := range d.values {
+			z := (x - v) / bandwidth
+			densities[i] += math.Exp(-z * z / 2)
+		}
+		peak = math.Max(peak, densities[i])
+	}
+	middle := float64(plotTop+plotBottom) / 2
+	halfHeight := float64(plotBottom-plotTop)/2 - 8
+	xs := make([]float64, 0, 2*len(densities))
+	ys := make([]float64, 0, 2*len(densities))
+	for i, density := range densities {
+		xs = append(xs, d.x(d.min+float64(i)/points*(d.max-d.min)))
+		ys = append(ys, middle-density/peak*halfHeight)
+	}
+	for i := len(densities) - 1; i >= 0; i-- {
+		xs = append(xs, d.x(d.min+float64(i)/points*(d.max-d.min)))
+		ys = append(ys, middle+densities[i]/peak*halfHeight)
+	}
+	c.polygon(xs, ys, plotFill)
+	c.rect(d.x(d.quantile(0.25)), middle-3, math.Max(d.x(d.quantile(0.75))-d.x(d.quantile(0.25)), 1), 6, plotStroke)
+	c.line(d.x(d.quantile(0.5)), middle-10, d.x(d.quantile(0.5)), middle+10, plotWhite)
+}
+
+// newDistribution returns the distribution of the boot times of the measured runs of results, with
+// an axis a little wider than their range.
+func newDistribution(results *Results) (*distribution, error) {
+	durations := Durations(results.Measured())
+	if len(durations) == 0 {
+		return nil, fmt.Errorf("no measured run to plot")
+	}
+	d := &distribution{title: fmt.Sprintf("Boot time, %d runs", len(durations))}
+	if len(results.Scenario) > 0 {
+		d.title = fmt.Sprintf("Boot time of %s, %d runs", results.Scenario, len(durations))
+	}
+	for _, duration := range durations {
+		d.values = append(d.values, millis(duration))
+	}
+	sort.Float64s(d.values)
+	span := d.values[len(d.values)-1] - d.values[0]
+	if span == 0 {
+		span = math.Max(d.values[0]/10, 1)
+	}
+	d.min, d.max = math.Max(d.values[0]-span/10, 0), d.values[len(d.values)-1]+span/10
+	return d, nil
+}
+
+// newDistributionExporter creates exporters plotting the distribution of the boot times of the
+// measured runs as SVG, or as PNG when the destination ends with .png.
+func newDistributionExporter(plot func(d *distribution, c canvas)) ExporterFactory {
+	return func(destination string) (Exporter, error) {
+		return ExporterFunc(func(results *Results) error {
+			d, err := newDistribution(results)
+			if err != nil {
+				return err
+			}
+			var c canvas = &svgCanvas{width: plotWidth, height: plotHeight}
+			if strings.HasSuffix(strings.ToLower(destination), ".png") {
+				c = newPNGCanvas(plotWidth, plotHeight)
+			}
+			d.axes(c)
+			plot(d, c)
+			return writeTo(destination, c.writeTo)
+		}), nil
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+)
+
+// DockerOptions configures the docker launcher.
+type DockerOptions struct {
+	// Publish are the ports of the container published on the host, as in 8080:8080, see docker run
+	// --publish. The target probes the host ports.
+	Publish []string `yaml:"publish"`
+	// CPUs and Memory limit the resources of the container, as in 1.5 and 512m. Unlimited when empty.
+	CPUs   string `yaml:"cpus"`
+	Memory string `yaml:"memory"`
+	// Options are other options of docker run, as in --network=host.
+	Options []string `yaml:"options"`
+}
+
+// ContainerPrefix starts the names of the containers of the docker launcher, which are followed by
+// the process identifier of the benchmark and the run number, so that leftovers of an interrupted
+// benchmark can be found with docker ps --filter name=time-to-boot-server-.
+const ContainerPrefix = "time-to-boot-server-"
+
+var dockerContainers int64
+
+func init() {
+	RegisterLauncher("docker", newDockerLauncher)
+}
+
+// dockerLauncher runs the image given as command in a container with docker run, the arguments
+// being the command of the container. The run is measured from docker run, so it covers creating
+// and starting the container. docker run forwards the signals it gets to the container, and the
+// container is removed after each run.
+type dockerLauncher struct {
+	*processLauncher
+	container string
+}
+
+func newDockerLauncher(b *Benchmark) (Launcher, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, fmt.Errorf("the docker launcher needs the docker command: %v", err)
+	}
+	container := fmt.Sprintf("%s%d-%d", ContainerPrefix, os.Getpid(), atomic.AddInt64(&dockerContainers, 1))
+	args := []string{"run", "--rm", "--name", container}
+	for _, port := range b.Docker.Publish {
+		args = append(args, "--publish", port)
+	}
+	if len(b.Docker.CPUs) > 0 {
+		args = append(args, "--cpus", b.Docker.CPUs)
+	}
+	if len(b.Docker.Memory) > 0 {
+		args = append(args, "--memory", b.Docker.Memory)
+	}
+	// Variables are only named, so that docker run passes them from its own environment and their
+	// values, secrets included, do not show in the process list.
+	for _, variable := range b.Env {
+		args = append(args, "--env", strings.SplitN(variable, "=", 2)[0])
+	}
+	args = append(append(append(args, b.Docker.Options...), b.Command), b.Args...)
+	return &dockerLauncher{processLauncher: &processLauncher{name: "docker", args: args, env: b.Env}, container: container}, nil
+}
+
+// Pid returns 0 since the local process is the docker client rather than the server.
+func (l *dockerLauncher) Pid() int {
+	return 0
+}
+
+// Wait drops the resources of the docker client, which are not those of the server.
+func (l *dockerLauncher) Wait() (Termination, error) {
+	termination, err := l.processLauncher.Wait()
+	termination.Resources = Resources{}
+	return termination, err
+}
+
+// Cleanup removes the container when it outlived docker run, as when the client got killed.
+func (l *dockerLauncher) Cleanup() error {
+	if l.cmd == nil || l.cmd.Process == nil {
+		return nil
+	}
+	if err := l.processLauncher.Cleanup(); err != nil {
+		return err
+	}
+	output, err := exec.Command("docker", "rm", "--force", l.container).CombinedOutput()
+	if err != nil && !strings.Contains(string(output), "No such container") {
+		return fmt.Errorf("unable to remove the container %s: %v: %s", l.container, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSMTPPort is the port of SMTP servers whose port is not set, for message submission.
+const DefaultSMTPPort = 587
+
+// SMTPSettings tells how to send emails, such as reports. The password may reference a secret,
+// see ResolveSecret.
+type SMTPSettings struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"` // DefaultSMTPPort when zero
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// WithEnvironment returns the settings with those that are not set taken from the SMTP_HOST,
+// SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD and SMTP_FROM environment variables.
+func (s SMTPSettings) WithEnvironment() (SMTPSettings, error) {
+	fill := func(value *string, variable string) {
+		if len(*value) == 0 {
+			*value = os.Getenv(variable)
+		}
+	}
+	fill(&s.Host, "SMTP_HOST")
+	fill(&s.Username, "SMTP_USERNAME")
+	fill(&s.Password, "SMTP_PASSWORD")
+	fill(&s.From, "SMTP_FROM")
+	if port := os.Getenv("SMTP_PORT"); s.Port == 0 && len(port) > 0 {
+		var err error
+		if s.Port, err = strconv.Atoi(port); err != nil {
+			return s, fmt.Errorf("invalid SMTP_PORT: %s", port)
+		}
+	}
+	return s, nil
+}
+
+// MailPart is a body of a message, with its content type, as in text/markdown.
+type MailPart struct {
+	ContentType string
+	Body        []byte
+}
+
+// SendMail sends a message to the recipients, made of alternative parts from the plainest to the
+// richest, as in a text/markdown part followed by a text/html one. STARTTLS is used when the server
+// supports it, and authentication when a username is set.
+func (s SMTPSettings) SendMail(to []string, subject string, parts ...MailPart) error {
+	if len(s.Host) == 0 {
+		return errors.New("no SMTP server, see the smtp block of configuration files or SMTP_HOST")
+	}
+	if len(s.From) == 0 {
+		return errors.New("no sender address, see the smtp block of configuration files or SMTP_FROM")
+	}
+	if len(to) == 0 {
+		return errors.New("no recipients")
+	}
+	if len(parts) == 0 {
+		return errors.New("no message body")
+	}
+	port := s.Port
+	if port == 0 {
+		port = DefaultSMTPPort
+	}
+	var auth smtp.Auth
+	if len(s.Username) > 0 {
+		password, err := ResolveSecret(s.Password)
+		if err != nil {
+			return fmt.Errorf("SMTP password: %v", err)
+		}
+		auth = smtp.PlainAuth("", s.Username, password, s.Host)
+	}
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", s.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	if len(parts) == 1 {
+		fmt.Fprintf(&message, "Content-Type: %s; charset=utf-8\r\n", parts[0].ContentType)
+		fmt.Fprintf(&message, "Content-Transfer-Encoding: 8bit\r\n\r\n")
+		message.Write(crlf(parts[0].Body))
+	} else {
+		alternatives := multipart.NewWriter(&message)
+		fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", alternatives.Boundary())
+		for _, part := range parts {
+			w, err := alternatives.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {part.ContentType + "; charset=utf-8"},
+				"Content-Transfer-Encoding": {"8bit"},
+			})
+			if err != nil {
+				return err
+			}
+			w.Write(crlf(part.Body))
+		}
+		alternatives.Close()
+	}
+	address := net.JoinHostPort(s.Host, strconv.Itoa(port))
+	if err := smtp.SendMail(address, auth, s.From, to, message.Bytes()); err != nil {
+		return fmt.Errorf("unable to send the email through %s: %v", address, err)
+	}
+	return nil
+}
+
+// crlf terminates the lines of body with CRLF, as SMTP expects.
+func crlf(body []byte) []byte {
+	return bytes.Replace(bytes.Replace(body, []byte("\r\n"), []byte("\n"), -1), []byte("\n"), []byte("\r\n"), -1)
+}
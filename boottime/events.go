@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventPhasePrefix prefixes the names of the phases marking when the lifecycle events of the server
+// happened, as in event:cluster-joined.
+const EventPhasePrefix = "event:"
+
+// MilestonePhasePrefix prefixes the names of the phases marking when the server reached the
+// milestones of its boot, as in milestone:http-up.
+const MilestonePhasePrefix = "milestone:"
+
+// LifecycleEvent is an event of the server timed after readiness, such as a first background job
+// processed or a cluster join, or a milestone of its boot, such as the TCP port accepting
+// connections, detected with a probe of its own.
+type LifecycleEvent struct {
+	Name   string `yaml:"name"`
+	Mode   string `yaml:"mode"`   // mode of the probe detecting the event, that of the benchmark when empty
+	Target string `yaml:"target"` // target of the probe, that of the benchmark when empty
+	// Match is the condition of the prom-metric mode, the pattern of the logfile mode or the
+	// regular expression of the file mode, that of the benchmark when empty.
+	Match string `yaml:"match"`
+}
+
+// eventProbe is the probe detecting an event.
+type eventProbe struct {
+	LifecycleEvent
+	probe Probe
+}
+
+// eventProbes creates the probes of events, or milestones as told by kind, with the options of the
+// modes of b.
+func (b *Benchmark) eventProbes(kind string, events []LifecycleEvent) ([]eventProbe, error) {
+	probes := make([]eventProbe, 0, len(events))
+	seen := make(map[string]bool, len(events))
+	for _, event := range events {
+		if len(event.Name) == 0 || strings.ContainsAny(event.Name, " \t") {
+			return nil, fmt.Errorf("invalid %s name: %q", kind, event.Name)
+		}
+		if seen[event.Name] {
+			return nil, fmt.Errorf("duplicate %s: %s", kind, event.Name)
+		}
+		seen[event.Name] = true
+		eb := *b
+		if len(event.Mode) > 0 {
+			eb.Mode = event.Mode
+		}
+		if len(event.Target) > 0 {
+			eb.Target = event.Target
+		}
+		if len(event.Match) > 0 {
+			eb.Prom.Condition, eb.LogFile.Pattern, eb.File.Match = event.Match, event.Match, event.Match
+		}
+		probe, err := eb.probe()
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %v", kind, event.Name, err)
+		}
+		if _, ok := probe.(ServerEnvironment); ok {
+			return nil, fmt.Errorf("%s %s: the %s mode cannot detect %ss", kind, event.Name, eb.Mode, kind)
+		}
+		probes = append(probes, eventProbe{LifecycleEvent: event, probe: probe})
+	}
+	return probes, nil
+}
+
+// setupEvents sets the probes of the milestones and events up before spawning the server, so that
+// they observe everything from the start of the run, and returns a function tearing them down.
+func (s *session) setupEvents(ctx context.Context) (func(), error) {
+	probes := append(append([]eventProbe{}, s.milestones...), s.events...)
+	teardown := func(probes []eventProbe) {
+		for _, event := range probes {
+			if err := event.probe.Teardown(); err != nil {
+				s.Logger.Warn("event probe teardown failed", "event", event.Name, "error", err)
+			}
+		}
+	}
+	for i, event := range probes {
+		if err := event.probe.Setup(ctx); err != nil {
+			teardown(probes[:i])
+			return nil, fmt.Errorf("probe setup of %s failed: %v", event.Name, err)
+		}
+	}
+	return func() { teardown(probes) }, nil
+}
+
+// awaitEvents waits for events or milestones one after the other, marking their phases with the
+// given prefix.
+func (s *session) awaitEvents(ctx context.Context, events []eventProbe, prefix string, run *Run, start time.Time, interval time.Duration) error {
+	for _, event := range events {
+		var attemptStart time.Time
+		wait := interval
+		for {
+			if !attemptStart.IsZero() && wait > 0 {
+				if err := sleep(ctx, wait-time.Since(attemptStart)); err != nil {
+					return err
+				}
+			}
+			attemptStart = time.Now()
+			_, err := event.probe.Check(ctx)
+			if err == nil {
+				break
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			wait = s.backoff(wait)
+		}
+		offset := time.Since(start)
+		run.mark(prefix+event.Name, offset)
+		s.Logger.Debug("event happened", "event", prefix+event.Name, "offset", offset)
+	}
+	return nil
+}
+
+// outputProbes returns the probes of a run watching the output of the server.
+func (s *session) outputProbes(spec runSpec) []OutputProbe {
+	var watchers []OutputProbe
+	if watcher, ok := s.probe.(OutputProbe); ok {
+		watchers = append(watchers, watcher)
+	}
+	if !spec.calibration {
+		for _, event := range append(append([]eventProbe{}, s.milestones...), s.events...) {
+			if watcher, ok := event.probe.(OutputProbe); ok {
+				watchers = append(watchers, watcher)
+			}
+		}
+	}
+	return watchers
+}
@@ -0,0 +1,317 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exporter writes results to some destination.
+type Exporter interface {
+	Export(results *Results) error
+}
+
+// ExporterFunc adapts a function to the Exporter interface.
+type ExporterFunc func(results *Results) error
+
+// Export calls f(results).
+func (f ExporterFunc) Export(results *Results) error {
+	return f(results)
+}
+
+// ExporterFactory creates an exporter for a destination, whose meaning depends on the exporter
+// (a file, a URL, etc). The destination is empty when not specified.
+type ExporterFactory func(destination string) (Exporter, error)
+
+var (
+	exportersMu sync.RWMutex
+	exporters   = make(map[string]ExporterFactory)
+)
+
+// RegisterExporter makes an exporter available by name, replacing any exporter with the same name.
+func RegisterExporter(name string, factory ExporterFactory) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	exporters[name] = factory
+}
+
+// ExporterNames returns the sorted names of the registered exporters.
+func ExporterNames() []string {
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewExporter creates an exporter from a specification of the form name or name=destination,
+// as in json=results.json.
+func NewExporter(spec string) (Exporter, error) {
+	name, destination := spec, ""
+	if i := strings.IndexByte(spec, '='); i >= 0 {
+		name, destination = spec[:i], spec[i+1:]
+	}
+	exportersMu.RLock()
+	factory, found := exporters[name]
+	exportersMu.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("unknown exporter: %s (available: %s)", name, strings.Join(ExporterNames(), ", "))
+	}
+	return factory(destination)
+}
+
+func init() {
+	RegisterExporter("json", newJSONExporter)
+	RegisterExporter("webhook", newWebhookExporter)
+	RegisterExporter("csv", newCSVExporter)
+	RegisterExporter("parquet", newParquetExporter)
+	RegisterExporter("prometheus", newPrometheusExporter)
+	RegisterExporter("influx", newInfluxExporter)
+}
+
+// writeTo calls write with the file at path, or with the standard output when path is empty or "-".
+func writeTo(path string, write func(w io.Writer) error) error {
+	if len(path) == 0 || path == "-" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func newJSONExporter(destination string) (Exporter, error) {
+	return ExporterFunc(func(results *Results) error {
+		data, err := json.MarshalIndent(RedactValue(results), "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeTo(destination, func(w io.Writer) error {
+			_, err := w.Write(append(data, '\n'))
+			return err
+		})
+	}), nil
+}
+
+// newCSVExporter writes one line per run, dry runs first, with the run index, start time and
+// duration in nanoseconds, for spreadsheets and the like. Failed runs have an error.
+func newCSVExporter(destination string) (Exporter, error) {
+	return ExporterFunc(func(results *Results) error {
+		return writeTo(destination, func(w io.Writer) error {
+			out := csv.NewWriter(w)
+			out.Write([]string{"scenario", "dry", "index", "started_at", "duration_ns", "error"})
+			for _, run := range results.Runs {
+				out.Write([]string{results.Scenario, strconv.FormatBool(run.Dry), strconv.Itoa(run.Index),
+					run.StartedAt.Format(time.RFC3339Nano), strconv.FormatInt(int64(run.Duration), 10), Redact(run.Error)})
+			}
+			out.Flush()
+			return out.Error()
+		})
+	}), nil
+}
+
+// ReadResults reads results written by the json exporter.
+func ReadResults(path string) (*Results, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	results := &Results{}
+	if err := json.Unmarshal(data, results); err != nil {
+		return nil, fmt.Errorf("invalid results %s: %v", path, err)
+	}
+	return results, nil
+}
+
+func newWebhookExporter(destination string) (Exporter, error) {
+	if len(destination) == 0 {
+		return nil, fmt.Errorf("the webhook exporter needs a URL, as in webhook=https://example.com/hook")
+	}
+	return ExporterFunc(func(results *Results) error {
+		body, err := json.Marshal(RedactValue(results))
+		if err != nil {
+			return err
+		}
+		resp, err := http.Post(destination, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("webhook %s replied with status %s", Redact(destination), resp.Status)
+		}
+		return nil
+	}), nil
+}
+
+// newPrometheusExporter writes the metrics of gitlab-metrics as gauges in the Prometheus text
+// format, labelled with the scenario if any, to a file for the textfile collector of the node
+// exporter or to the standard output. A destination of the form push:URL pushes them to a
+// Pushgateway instead, under the job of the job option (boot by default) and with the scenario in
+// the grouping key, as in push:http://pushgateway:9091?job=ci.
+func newPrometheusExporter(destination string) (Exporter, error) {
+	write := func(w io.Writer, results *Results, labels string) error {
+		for _, metric := range summaryMetrics(results) {
+			if _, err := fmt.Fprintf(w, "# TYPE %s gauge\n%s%s %s\n", metric[0], metric[0], labels, metric[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !strings.HasPrefix(destination, "push:") {
+		return ExporterFunc(func(results *Results) error {
+			return writeTo(destination, func(w io.Writer) error {
+				return write(w, results, scenarioLabels(results))
+			})
+		}), nil
+	}
+	gateway, err := url.Parse(strings.TrimPrefix(destination, "push:"))
+	if err != nil || (gateway.Scheme != "http" && gateway.Scheme != "https") {
+		return nil, fmt.Errorf("invalid Pushgateway URL, expected push:http://host:port: %s", Redact(destination))
+	}
+	if password, set := gateway.User.Password(); set {
+		RegisterSecret(password)
+	}
+	job := gateway.Query().Get("job")
+	if len(job) == 0 {
+		job = "boot"
+	}
+	gateway.RawQuery = ""
+	return ExporterFunc(func(results *Results) error {
+		// PUT replaces the metrics previously pushed with the same grouping key.
+		group := *gateway
+		group.Path = strings.TrimSuffix(group.Path, "/") + "/metrics/job/" + url.PathEscape(job)
+		if len(results.Scenario) > 0 {
+			group.Path += "/scenario/" + url.PathEscape(results.Scenario)
+		}
+		var body bytes.Buffer
+		if err := write(&body, results, ""); err != nil {
+			return err
+		}
+		request, err := http.NewRequest(http.MethodPut, group.String(), &body)
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Content-Type", "text/plain; version=0.0.4")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("the Pushgateway %s replied with status %s", gateway.Redacted(), resp.Status)
+		}
+		return nil
+	}), nil
+}
+
+// newInfluxExporter posts the metrics of gitlab-metrics as the fields of a boot point in the
+// InfluxDB line protocol, tagged with the scenario if any and at the time the benchmark started, to
+// the write endpoint given as destination, as in http://influxdb:8086/write?db=ci for InfluxDB 1 or
+// http://influxdb:8086/api/v2/write?org=ci&bucket=boot for InfluxDB 2. The INFLUX_TOKEN environment
+// variable, when set, authenticates the requests.
+func newInfluxExporter(destination string) (Exporter, error) {
+	endpoint, err := url.Parse(destination)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("the influx exporter needs the URL of a write endpoint, as in influx=http://localhost:8086/write?db=ci")
+	}
+	if precision := endpoint.Query().Get("precision"); len(precision) > 0 && precision != "ns" && precision != "n" {
+		return nil, fmt.Errorf("the influx exporter writes timestamps in nanoseconds, not with precision %s", precision)
+	}
+	token := os.Getenv("INFLUX_TOKEN")
+	RegisterSecret(token)
+	if password, set := endpoint.User.Password(); set {
+		RegisterSecret(password)
+	}
+	return ExporterFunc(func(results *Results) error {
+		line := "boot"
+		if len(results.Scenario) > 0 {
+			line += ",scenario=" + influxEscaper.Replace(results.Scenario)
+		}
+		for i, metric := range summaryMetrics(results) {
+			separator := ","
+			if i == 0 {
+				separator = " "
+			}
+			line += separator + metric[0] + "=" + metric[1]
+		}
+		// Points without timestamps, as with reproducible results, get the time of the server.
+		if !results.StartedAt.IsZero() {
+			line += " " + strconv.FormatInt(results.StartedAt.UnixNano(), 10)
+		}
+		request, err := http.NewRequest(http.MethodPost, endpoint.String(), strings.NewReader(line+"\n"))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		if len(token) > 0 {
+			request.Header.Set("Authorization", "Token "+token)
+		}
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("InfluxDB at %s replied with status %s", endpoint.Redacted(), resp.Status)
+		}
+		return nil
+	}), nil
+}
+
+// scenarioLabels returns the labels of the metrics of results in the Prometheus exposition format,
+// with the scenario if any.
+func scenarioLabels(results *Results) string {
+	if len(results.Scenario) == 0 {
+		return ""
+	}
+	return `{scenario="` + prometheusEscaper.Replace(results.Scenario) + `"}`
+}
+
+// prometheusEscaper escapes label values in the Prometheus exposition format, which unlike Go
+// strings only escapes backslashes, double quotes and line feeds.
+var prometheusEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// influxEscaper escapes tag values in the InfluxDB line protocol.
+var influxEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Categories of run failures, for dashboards to break down why benchmarks fail.
+const (
+	SpawnErrorCategory   = "spawn-error"    // the launcher could not start the server
+	CrashCategory        = "crash"          // the process exited by itself before the run completed
+	ProbeTimeoutCategory = "probe-timeout"  // the server was not ready in time
+	RunTimeoutCategory   = "run-timeout"    // the server was ready, but the rest of the run did not complete in time
+	KilledByUserCategory = "killed-by-user" // the benchmark was interrupted
+	EnvironmentCategory  = "environment"    // the benchmark could not run as configured on this host
+)
+
+// Policies towards failed runs.
+const (
+	AbortOnFailure = "abort" // stop the benchmark
+	SkipOnFailure  = "skip"  // record the failed run and carry on with the next one
+	RetryOnFailure = "retry" // record the failed run and perform it again, up to MaxRunRetries times
+)
+
+// MaxRunRetries bounds how many times a failed run is performed again with RetryOnFailure, after
+// which the benchmark stops.
+const MaxRunRetries = 3
+
+func checkFailurePolicy(policy string) error {
+	switch policy {
+	case "", AbortOnFailure, SkipOnFailure, RetryOnFailure:
+		return nil
+	}
+	return fmt.Errorf("unknown failure policy: %s (expected %s, %s or %s)", policy, AbortOnFailure, SkipOnFailure, RetryOnFailure)
+}
+
+// categorizedError is an error with a category.
+type categorizedError struct {
+	category string
+	err      error
+}
+
+func (e *categorizedError) Error() string { return e.err.Error() }
+func (e *categorizedError) Unwrap() error { return e.err }
+
+func categorize(category string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &categorizedError{category: category, err: err}
+}
+
+// ErrorCategory returns the category of the error of a failed run, EnvironmentCategory when the
+// error has none.
+func ErrorCategory(err error) string {
+	var categorized *categorizedError
+	switch {
+	case errors.As(err, &categorized):
+		return categorized.category
+	case errors.Is(err, context.Canceled):
+		return KilledByUserCategory
+	case errors.Is(err, context.DeadlineExceeded):
+		return ProbeTimeoutCategory
+	}
+	return EnvironmentCategory
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// FileOptions configures the file mode, where the target is the path of a file such as a pid file,
+// a unix socket or a marker.
+type FileOptions struct {
+	// Match is a regular expression that the content of the file must match, the file only has
+	// to exist when empty.
+	Match string `yaml:"match"`
+}
+
+// fileRecheckInterval bounds how long a check waits for file system events, in case some go
+// unnoticed, as in directories that do not exist yet.
+const fileRecheckInterval = 100 * time.Millisecond
+
+func init() {
+	RegisterProbe("file", newFileProbe)
+}
+
+// fileWatcher waits for changes in a directory.
+type fileWatcher interface {
+	// wait returns once something may have changed in the directory, or after timeout.
+	wait(ctx context.Context, timeout time.Duration) error
+	close() error
+}
+
+type fileProbe struct {
+	path    string
+	match   *regexp.Regexp
+	watcher fileWatcher
+	stale   time.Time // modification time of the file left by a previous run, if any
+}
+
+func newFileProbe(b *Benchmark) (Probe, error) {
+	if len(b.Target) == 0 {
+		return nil, errors.New("the file mode needs a path as target")
+	}
+	probe := &fileProbe{path: b.Target}
+	if len(b.File.Match) > 0 {
+		match, err := regexp.Compile(b.File.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file content pattern: %v", err)
+		}
+		probe.match = match
+	}
+	return probe, nil
+}
+
+// Setup watches the directory of the file before the server starts, so that no change is missed.
+// A file left by a previous run must be modified again to be ready.
+func (p *fileProbe) Setup(ctx context.Context) error {
+	p.stale = time.Time{}
+	if info, err := os.Stat(p.path); err == nil {
+		p.stale = info.ModTime()
+	}
+	watcher, err := newFileWatcher(filepath.Dir(p.path))
+	if err != nil {
+		return err
+	}
+	p.watcher = watcher
+	return nil
+}
+
+func (p *fileProbe) Teardown() error {
+	return p.watcher.close()
+}
+
+// Check succeeds when the file is ready, or waits for it to change before failing.
+func (p *fileProbe) Check(ctx context.Context) (ProbeResult, error) {
+	err := p.ready()
+	if err == nil {
+		return ProbeResult{}, nil
+	}
+	if waitErr := p.watcher.wait(ctx, fileRecheckInterval); waitErr != nil {
+		return ProbeResult{}, waitErr
+	}
+	return ProbeResult{}, err
+}
+
+func (p *fileProbe) ready() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	if !p.stale.IsZero() && !info.ModTime().After(p.stale) {
+		return fmt.Errorf("%s has not changed since the previous run", p.path)
+	}
+	if p.match == nil {
+		return nil
+	}
+	content, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+	if !p.match.Match(content) {
+		return fmt.Errorf("the content of %s does not match %s", p.path, p.match)
+	}
+	return nil
+}
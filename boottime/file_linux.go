@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+)
+
+// inotifyWatcher waits for inotify events of a directory. Directories that do not exist are
+// rechecked periodically.
+type inotifyWatcher struct {
+	events *os.File
+	buffer []byte
+}
+
+func newFileWatcher(dir string) (fileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	mask := uint32(syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_ATTRIB)
+	if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil && err != syscall.ENOENT {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("inotify_add_watch", err)
+	}
+	// A non-blocking descriptor goes through the runtime poller, which supports read deadlines.
+	return &inotifyWatcher{events: os.NewFile(uintptr(fd), "inotify"), buffer: make([]byte, 4096)}, nil
+}
+
+func (w *inotifyWatcher) wait(ctx context.Context, timeout time.Duration) error {
+	w.events.SetReadDeadline(time.Now().Add(timeout))
+	stop := context.AfterFunc(ctx, func() {
+		w.events.SetReadDeadline(time.Now())
+	})
+	defer stop()
+	_, err := w.events.Read(w.buffer)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil && !os.IsTimeout(err) {
+		return err
+	}
+	return nil
+}
+
+func (w *inotifyWatcher) close() error {
+	return w.events.Close()
+}
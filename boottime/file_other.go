@@ -0,0 +1,51 @@
+//go:build !linux
+
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"time"
+)
+
+// filePollInterval is how often files are checked without inotify.
+const filePollInterval = 10 * time.Millisecond
+
+// pollWatcher checks files periodically, where inotify is not available.
+type pollWatcher struct{}
+
+func newFileWatcher(dir string) (fileWatcher, error) {
+	return pollWatcher{}, nil
+}
+
+func (pollWatcher) wait(ctx context.Context, timeout time.Duration) error {
+	if timeout > filePollInterval {
+		timeout = filePollInterval
+	}
+	return sleep(ctx, timeout)
+}
+
+func (pollWatcher) close() error {
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"time"
+)
+
+func init() {
+	RegisterExporter("gitlab-metrics", newGitLabMetricsExporter)
+	RegisterExporter("badge", newBadgeExporter)
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// summaryMetrics returns the names and values of the metrics summarizing results for CI servers:
+// statistics of the boot time in milliseconds, and the numbers of runs and failed runs.
+func summaryMetrics(results *Results) [][2]string {
+	runs := results.Measured()
+	statistics := ComputeStatistics(Durations(runs))
+	if statistics == nil {
+		statistics = &Statistics{}
+	}
+	ms := func(d time.Duration) string { return fmt.Sprintf("%.3f", millis(d)) }
+	return [][2]string{
+		{"boot_time_median_ms", ms(statistics.Median)},
+		{"boot_time_mean_ms", ms(statistics.Mean)},
+		{"boot_time_p95_ms", ms(statistics.Percentiles["95"])},
+		{"boot_time_min_ms", ms(statistics.Min)},
+		{"boot_time_max_ms", ms(statistics.Max)},
+		{"boot_time_stddev_ms", ms(statistics.StdDev)},
+		{"boot_runs", strconv.Itoa(len(runs))},
+		{"boot_failed_runs", strconv.Itoa(failedRuns(results))},
+	}
+}
+
+// newGitLabMetricsExporter writes a GitLab metrics report, usually metrics.txt, declared as the
+// artifacts:reports:metrics of a job so that merge requests show how the boot time changed.
+// Metrics are labelled with the scenario, if any.
+func newGitLabMetricsExporter(destination string) (Exporter, error) {
+	return ExporterFunc(func(results *Results) error {
+		labels := scenarioLabels(results)
+		return writeTo(destination, func(w io.Writer) error {
+			for _, metric := range summaryMetrics(results) {
+				if _, err := fmt.Fprintf(w, "%s%s %s\n", metric[0], labels, metric[1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}), nil
+}
+
+func failedRuns(results *Results) int {
+	failed := 0
+	for _, run := range results.Runs {
+		if !run.Dry && run.Failed() {
+			failed++
+		}
+	}
+	return failed
+}
+
+// badgeCharWidth approximates the width of the characters of badges in pixels, as the text is
+// rendered in an 11px sans-serif font.
+const badgeCharWidth = 7
+
+// newBadgeExporter writes an SVG badge with the median boot time and the relative standard
+// deviation, as in "boot: 840ms ±5%", to publish to pages or embed in a README.
+func newBadgeExporter(destination string) (Exporter, error) {
+	return ExporterFunc(func(results *Results) error {
+		label := "boot"
+		if len(results.Scenario) > 0 {
+			label += " " + results.Scenario
+		}
+		value, color := "no runs", "#9f9f9f"
+		if statistics := ComputeStatistics(Durations(results.Measured())); statistics != nil {
+			value, color = formatBadgeDuration(statistics.Median), "#007ec6"
+			if statistics.Mean > 0 {
+				value += fmt.Sprintf(" ±%.0f%%", 100*float64(statistics.StdDev)/float64(statistics.Mean))
+			}
+		}
+		return writeTo(destination, func(w io.Writer) error {
+			_, err := io.WriteString(w, badgeSVG(label, value, color))
+			return err
+		})
+	}), nil
+}
+
+// formatBadgeDuration formats d in milliseconds under 10 seconds, and in seconds otherwise.
+func formatBadgeDuration(d time.Duration) string {
+	if d < 10*time.Second {
+		return fmt.Sprintf("%.0fms", millis(d))
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
+
+func badgeSVG(label, value, color string) string {
+	labelWidth := len([]rune(label))*badgeCharWidth + 10
+	valueWidth := len([]rune(value))*badgeCharWidth + 10
+	width := labelWidth + valueWidth
+	label, value = html.EscapeString(label), html.EscapeString(value)
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[7]d" y="14">%[4]s</text>
+<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[8]d" y="14">%[5]s</text>
+</g>
+</svg>
+`, width, labelWidth, valueWidth, label, value, color, labelWidth/2, labelWidth+valueWidth/2)
+}
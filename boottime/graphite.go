@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterExporter("graphite", newGraphiteExporter)
+	RegisterExporter("statsd", newStatsDExporter)
+}
+
+// metricsEndpoint is the destination of the graphite and statsd exporters, a URL such as
+// tcp://graphite:2003?prefix=ci.boot&tags=env:ci&runs=true.
+type metricsEndpoint struct {
+	network, address string
+	prefix           string
+	tags             [][2]string // names and values
+	runs             bool        // whether each measured run is sent too
+}
+
+func parseMetricsEndpoint(exporter, destination, network string, port int) (*metricsEndpoint, error) {
+	if len(destination) == 0 {
+		return nil, fmt.Errorf("the %s exporter needs an address, as in %s=%s://localhost:%d?prefix=boot", exporter, exporter, network, port)
+	}
+	if !strings.Contains(destination, "://") {
+		destination = network + "://" + destination
+	}
+	u, err := url.Parse(destination)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s address: %v", exporter, err)
+	}
+	if u.Scheme != "tcp" && u.Scheme != "udp" {
+		return nil, fmt.Errorf("invalid %s address, expected tcp:// or udp://: %s", exporter, destination)
+	}
+	endpoint := &metricsEndpoint{network: u.Scheme, address: u.Host, prefix: u.Query().Get("prefix")}
+	if len(u.Port()) == 0 {
+		endpoint.address = net.JoinHostPort(u.Hostname(), strconv.Itoa(port))
+	}
+	if tags := u.Query().Get("tags"); len(tags) > 0 {
+		for _, tag := range strings.Split(tags, ",") {
+			i := strings.IndexByte(tag, ':')
+			if i <= 0 {
+				return nil, fmt.Errorf("invalid %s tag, expected name:value: %s", exporter, tag)
+			}
+			endpoint.tags = append(endpoint.tags, [2]string{tag[:i], tag[i+1:]})
+		}
+	}
+	if runs := u.Query().Get("runs"); len(runs) > 0 {
+		if endpoint.runs, err = strconv.ParseBool(runs); err != nil {
+			return nil, fmt.Errorf("invalid %s runs option: %s", exporter, runs)
+		}
+	}
+	return endpoint, nil
+}
+
+// name returns the name of a metric of the results of a scenario, with the prefix.
+func (e *metricsEndpoint) name(scenario, metric string) string {
+	var parts []string
+	for _, part := range []string{e.prefix, scenario, metric} {
+		if len(part) > 0 {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// send sends lines of metrics, one per datagram over UDP as some servers do not accept several.
+func (e *metricsEndpoint) send(lines []string) error {
+	conn, err := net.DialTimeout(e.network, e.address, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	payloads := lines
+	if e.network == "tcp" {
+		payloads = []string{strings.Join(lines, "")}
+	}
+	for _, payload := range payloads {
+		if _, err := conn.Write([]byte(payload)); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	return conn.Close()
+}
+
+// newGraphiteExporter sends the metrics summarizing results to Graphite with its plaintext
+// protocol, at the time the benchmark started, and the duration of each measured run at the time
+// it started with the runs option. Tags are appended to the names as in Graphite tagged series.
+func newGraphiteExporter(destination string) (Exporter, error) {
+	endpoint, err := parseMetricsEndpoint("graphite", destination, "tcp", 2003)
+	if err != nil {
+		return nil, err
+	}
+	var tags string
+	for _, tag := range endpoint.tags {
+		tags += ";" + tag[0] + "=" + tag[1]
+	}
+	return ExporterFunc(func(results *Results) error {
+		var lines []string
+		for _, metric := range summaryMetrics(results) {
+			lines = append(lines, fmt.Sprintf("%s%s %s %d\n", endpoint.name(results.Scenario, metric[0]), tags, metric[1], results.StartedAt.Unix()))
+		}
+		if endpoint.runs {
+			for _, run := range results.Measured() {
+				lines = append(lines, fmt.Sprintf("%s%s %.3f %d\n", endpoint.name(results.Scenario, "boot_time_ms"), tags, millis(run.Duration), run.StartedAt.Unix()))
+			}
+		}
+		return endpoint.send(lines)
+	}), nil
+}
+
+// newStatsDExporter sends the metrics summarizing results to StatsD as gauges, and the duration of
+// each measured run as a timing with the runs option. Tags are sent in the DogStatsD format.
+func newStatsDExporter(destination string) (Exporter, error) {
+	endpoint, err := parseMetricsEndpoint("statsd", destination, "udp", 8125)
+	if err != nil {
+		return nil, err
+	}
+	var tags string
+	for i, tag := range endpoint.tags {
+		if i == 0 {
+			tags = "|#"
+		} else {
+			tags += ","
+		}
+		tags += tag[0] + ":" + tag[1]
+	}
+	return ExporterFunc(func(results *Results) error {
+		var lines []string
+		for _, metric := range summaryMetrics(results) {
+			lines = append(lines, fmt.Sprintf("%s:%s|g%s\n", endpoint.name(results.Scenario, metric[0]), metric[1], tags))
+		}
+		if endpoint.runs {
+			for _, run := range results.Measured() {
+				lines = append(lines, fmt.Sprintf("%s:%.3f|ms%s\n", endpoint.name(results.Scenario, "boot_time"), millis(run.Duration), tags))
+			}
+		}
+		return endpoint.send(lines)
+	}), nil
+}
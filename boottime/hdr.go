@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+func init() {
+	RegisterExporter("hdr", newHdrExporter)
+}
+
+// newHdrExporter writes the percentile distribution of the measured runs in milliseconds, in the
+// format of the outputPercentileDistribution of HdrHistogram, to plot with the HdrHistogram plotter
+// and compare with the latency distributions of other tools.
+func newHdrExporter(destination string) (Exporter, error) {
+	return ExporterFunc(func(results *Results) error {
+		durations := Durations(results.Measured())
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		return writeTo(destination, func(w io.Writer) error {
+			if _, err := fmt.Fprintf(w, "%12s %14s %10s %14s\n\n", "Value", "Percentile", "TotalCount", "1/(1-Percentile)"); err != nil {
+				return err
+			}
+			n := len(durations)
+			for i, d := range durations {
+				if i+1 < n && durations[i+1] == d {
+					continue
+				}
+				p := float64(i+1) / float64(n)
+				var err error
+				if i+1 < n {
+					_, err = fmt.Fprintf(w, "%12.3f %2.12f %10d %14.2f\n", millis(d), p, i+1, 1/(1-p))
+				} else {
+					_, err = fmt.Fprintf(w, "%12.3f %2.12f %10d\n", millis(d), p, i+1)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			statistics := ComputeStatistics(durations)
+			if statistics == nil {
+				statistics = &Statistics{}
+			}
+			_, err := fmt.Fprintf(w, "#[Mean    = %12.3f, StdDeviation   = %12.3f]\n#[Max     = %12.3f, Total count    = %12d]\n",
+				millis(statistics.Mean), millis(statistics.StdDev), millis(statistics.Max), n)
+			return err
+		})
+	}), nil
+}
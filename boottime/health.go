@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// HealthOptions configures the health-groups mode, where the target is the base URL of a server
+// exposing separate health groups, and the server is ready once all of them are up.
+type HealthOptions struct {
+	// Framework selects the health groups exposed by a framework, see HealthFrameworks.
+	Framework string `yaml:"framework"`
+	// Groups maps group names to their paths or URLs, relative to the target. They are added to
+	// those of the framework, and replace them on name clashes.
+	Groups  map[string]string `yaml:"groups"`
+	Timeout Duration          `yaml:"timeout"` // timeout of each health request, none when zero, see RemoteProbeTimeout
+}
+
+// Health groups of the frameworks, also used as phase names.
+const (
+	StartupGroup   = "startup"   // the application has started
+	LivenessGroup  = "liveness"  // the application does not need to be restarted
+	ReadinessGroup = "readiness" // the application accepts traffic
+)
+
+// HealthFrameworks lists the paths of the health groups of well-known frameworks.
+var HealthFrameworks = map[string]map[string]string{
+	"spring-boot": {
+		LivenessGroup:  "/actuator/health/liveness",
+		ReadinessGroup: "/actuator/health/readiness",
+	},
+	"quarkus": {
+		StartupGroup:   "/q/health/started",
+		LivenessGroup:  "/q/health/live",
+		ReadinessGroup: "/q/health/ready",
+	},
+}
+
+func init() {
+	RegisterProbe("health-groups", newHealthGroupsProbe)
+}
+
+type healthGroup struct {
+	name string
+	url  string
+	up   bool
+}
+
+type healthGroupsProbe struct {
+	groups []*healthGroup
+	client *http.Client
+}
+
+func newHealthGroupsProbe(b *Benchmark) (Probe, error) {
+	paths := map[string]string{}
+	if len(b.Health.Framework) > 0 {
+		defaults, found := HealthFrameworks[b.Health.Framework]
+		if !found {
+			return nil, fmt.Errorf("unknown health framework: %s", b.Health.Framework)
+		}
+		for name, path := range defaults {
+			paths[name] = path
+		}
+	}
+	for name, path := range b.Health.Groups {
+		paths[name] = path
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("the health-groups mode needs a framework or health groups")
+	}
+	base, err := url.Parse(b.Target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target: %v", err)
+	}
+	probe := &healthGroupsProbe{client: &http.Client{Timeout: b.probeTimeout(time.Duration(b.Health.Timeout))}}
+	for name, path := range paths {
+		ref, err := url.Parse(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path of health group %s: %v", name, err)
+		}
+		probe.groups = append(probe.groups, &healthGroup{name: name, url: base.ResolveReference(ref).String()})
+	}
+	sort.Slice(probe.groups, func(i, j int) bool {
+		return groupRank(probe.groups[i].name) < groupRank(probe.groups[j].name) ||
+			groupRank(probe.groups[i].name) == groupRank(probe.groups[j].name) && probe.groups[i].name < probe.groups[j].name
+	})
+	return probe, nil
+}
+
+// groupRank orders the well-known groups as they are expected to come up, before the others.
+func groupRank(name string) int {
+	switch name {
+	case StartupGroup:
+		return 0
+	case LivenessGroup:
+		return 1
+	case ReadinessGroup:
+		return 2
+	}
+	return 3
+}
+
+func (p *healthGroupsProbe) Setup(ctx context.Context) error {
+	for _, group := range p.groups {
+		group.up = false
+	}
+	return nil
+}
+
+func (p *healthGroupsProbe) Teardown() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
+
+// Check queries the groups that are not up yet, and reports each group coming up as a phase.
+func (p *healthGroupsProbe) Check(ctx context.Context) (ProbeResult, error) {
+	var result ProbeResult
+	var pending []string
+	for _, group := range p.groups {
+		if group.up {
+			continue
+		}
+		if err := p.check(ctx, group.url); err != nil {
+			if ctx.Err() != nil {
+				return result, err
+			}
+			pending = append(pending, group.name)
+			continue
+		}
+		group.up = true
+		result.Reached = append(result.Reached, group.name)
+	}
+	if len(pending) > 0 {
+		return result, fmt.Errorf("health groups not up: %v", pending)
+	}
+	return result, nil
+}
+
+func (p *healthGroupsProbe) check(ctx context.Context, target string) error {
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportedMode is the mode of results imported from samples measured by other tools.
+const ImportedMode = "imported"
+
+// Formats of samples that ImportSamples reads.
+const (
+	// CSVSamples are rows of a duration, or of a scenario name and a duration. A first row that
+	// does not parse is a header.
+	CSVSamples = "csv"
+	// JSONSamples are an array of durations, or an object mapping scenario names to arrays of durations.
+	JSONSamples = "json"
+	// HyperfineSamples are exports of hyperfine --export-json, with one scenario per command.
+	HyperfineSamples = "hyperfine"
+)
+
+// ImportSamples reads samples measured by other tools and turns each set into results whose runs
+// are the samples. Durations are numbers in unit, or strings such as 250ms. Samples without a
+// scenario name are given the scenario name.
+func ImportSamples(r io.Reader, format string, unit time.Duration, scenario string) ([]*Results, error) {
+	var named map[string][]time.Duration
+	var order []string
+	var commands map[string]string
+	var err error
+	switch format {
+	case CSVSamples:
+		named, order, err = readCSVSamples(r, unit, scenario)
+	case JSONSamples:
+		named, order, err = readJSONSamples(r, unit, scenario)
+	case HyperfineSamples:
+		named, order, commands, err = readHyperfineSamples(r, scenario)
+	default:
+		return nil, fmt.Errorf("unknown samples format: %s (expected %s, %s or %s)", format, CSVSamples, JSONSamples, HyperfineSamples)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("no sample found")
+	}
+	all := make([]*Results, 0, len(order))
+	for _, name := range order {
+		results := &Results{SchemaVersion: SchemaVersion, Scenario: name, Mode: ImportedMode, Command: commands[name], StartedAt: time.Now()}
+		for i, duration := range named[name] {
+			results.Runs = append(results.Runs, Run{
+				Index:    i,
+				Duration: duration,
+				Phases:   []Phase{{Name: ReadyPhase, Offset: duration}},
+			})
+		}
+		all = append(all, results)
+	}
+	return all, nil
+}
+
+// parseSample parses a duration given as a number in unit, or as a string such as 250ms.
+func parseSample(s string, unit time.Duration) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if value, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(value * float64(unit)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+func readCSVSamples(r io.Reader, unit time.Duration, scenario string) (map[string][]time.Duration, []string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	named := map[string][]time.Duration{}
+	var order []string
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return named, order, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		name, value := scenario, record[0]
+		if len(record) > 1 {
+			name, value = record[0], record[1]
+		}
+		duration, err := parseSample(value, unit)
+		if err != nil {
+			if line == 1 {
+				continue // header
+			}
+			return nil, nil, fmt.Errorf("line %d: invalid duration %q", line, value)
+		}
+		if _, found := named[name]; !found {
+			order = append(order, name)
+		}
+		named[name] = append(named[name], duration)
+	}
+}
+
+func readJSONSamples(r io.Reader, unit time.Duration, scenario string) (map[string][]time.Duration, []string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	var raw map[string][]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var samples []json.RawMessage
+		if err := json.Unmarshal(data, &samples); err != nil {
+			return nil, nil, fmt.Errorf("expected an array of durations or an object of arrays of durations: %v", err)
+		}
+		raw = map[string][]json.RawMessage{scenario: samples}
+	}
+	named := map[string][]time.Duration{}
+	order := make([]string, 0, len(raw))
+	for name, samples := range raw {
+		for _, sample := range samples {
+			var value interface{}
+			if err := json.Unmarshal(sample, &value); err != nil {
+				return nil, nil, err
+			}
+			duration, err := parseSample(fmt.Sprint(value), unit)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: invalid duration %s", name, sample)
+			}
+			named[name] = append(named[name], duration)
+		}
+		order = append(order, name)
+	}
+	sort.Strings(order)
+	return named, order, nil
+}
+
+// hyperfineExport is the part of hyperfine --export-json output holding the samples.
+type hyperfineExport struct {
+	Results []struct {
+		Command string    `json:"command"`
+		Times   []float64 `json:"times"` // in seconds
+	} `json:"results"`
+}
+
+func readHyperfineSamples(r io.Reader, scenario string) (map[string][]time.Duration, []string, map[string]string, error) {
+	var export hyperfineExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid hyperfine export: %v", err)
+	}
+	named := map[string][]time.Duration{}
+	commands := map[string]string{}
+	var order []string
+	for _, result := range export.Results {
+		name := result.Command
+		if len(export.Results) == 1 && len(scenario) > 0 {
+			name = scenario
+		}
+		if _, found := named[name]; !found {
+			order = append(order, name)
+		}
+		commands[name] = result.Command
+		for _, seconds := range result.Times {
+			named[name] = append(named[name], time.Duration(seconds*float64(time.Second)))
+		}
+	}
+	return named, order, commands, nil
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"sync"
+)
+
+// interleaving lets the runs of benchmarks take turns, so that only one of them is active at any
+// time: a benchmark holds the turn from the start of Benchmark.Run to the end of its first run,
+// then for each of its later runs.
+type interleaving struct {
+	mu    sync.Mutex
+	turns []chan struct{} // by benchmark
+	done  []bool
+}
+
+// RunInterleaved runs benchmarks with their runs interleaved, one run of each in turn, so that
+// changes of the host during the session, such as thermal throttling or background jobs, affect
+// them alike. The results and errors are those of Benchmark.Run, by benchmark.
+func RunInterleaved(ctx context.Context, benchmarks []*Benchmark) ([]*Results, []error) {
+	l := &interleaving{turns: make([]chan struct{}, len(benchmarks)), done: make([]bool, len(benchmarks))}
+	for i := range l.turns {
+		l.turns[i] = make(chan struct{}, 1)
+	}
+	results := make([]*Results, len(benchmarks))
+	errs := make([]error, len(benchmarks))
+	var wg sync.WaitGroup
+	for i, b := range benchmarks {
+		interleaved := *b
+		interleaved.interleaving, interleaved.turn = l, i
+		wg.Add(1)
+		go func(i int, b *Benchmark) {
+			defer wg.Done()
+			results[i], errs[i] = b.Run(ctx)
+		}(i, &interleaved)
+	}
+	if len(benchmarks) > 0 {
+		l.turns[0] <- struct{}{}
+	}
+	wg.Wait()
+	return results, errs
+}
+
+// waitTurn waits until b may run, at once when it is not interleaved.
+func (b *Benchmark) waitTurn(ctx context.Context) error {
+	if b.interleaving == nil {
+		return nil
+	}
+	select {
+	case <-b.interleaving.turns[b.turn]:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// passTurn gives the turn to the next benchmark that is not done, which is b itself when it is
+// the last one.
+func (b *Benchmark) passTurn(done bool) {
+	l := b.interleaving
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.done[b.turn] = done
+	for k := 1; k <= len(l.turns); k++ {
+		next := (b.turn + k) % len(l.turns)
+		if !l.done[next] {
+			l.turns[next] <- struct{}{}
+			return
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+func init() {
+	RegisterExporter("jmeter", newJMeterExporter)
+}
+
+type jmeterResults struct {
+	XMLName xml.Name       `xml:"testResults"`
+	Version string         `xml:"version,attr"`
+	Samples []jmeterSample `xml:"httpSample"`
+}
+
+type jmeterSample struct {
+	Elapsed   int64  `xml:"t,attr"`  // in milliseconds
+	Timestamp int64  `xml:"ts,attr"` // in milliseconds since the epoch
+	Success   bool   `xml:"s,attr"`
+	Label     string `xml:"lb,attr"`
+	Code      string `xml:"rc,attr"`
+	Message   string `xml:"rm,attr"`
+}
+
+// newJMeterExporter writes the measured runs as the samples of a JMeter XML results file, which the
+// Jenkins Performance plugin and other JMeter tooling read. Samples are labelled with the scenario,
+// or boot, and failed runs are failed samples.
+func newJMeterExporter(destination string) (Exporter, error) {
+	return ExporterFunc(func(results *Results) error {
+		label := results.Scenario
+		if len(label) == 0 {
+			label = "boot"
+		}
+		doc := jmeterResults{Version: "1.2"}
+		for _, run := range results.Runs {
+			if run.Dry {
+				continue
+			}
+			sample := jmeterSample{
+				Elapsed:   run.Duration.Milliseconds(),
+				Timestamp: unixNano(run.StartedAt) / 1e6,
+				Success:   !run.Failed(),
+				Label:     label,
+				Code:      "200",
+				Message:   "OK",
+			}
+			if run.Failed() {
+				sample.Code, sample.Message = run.ErrorCategory, Redact(run.Error)
+			}
+			doc.Samples = append(doc.Samples, sample)
+		}
+		return writeTo(destination, func(w io.Writer) error {
+			if _, err := io.WriteString(w, xml.Header); err != nil {
+				return err
+			}
+			encoder := xml.NewEncoder(w)
+			encoder.Indent("", "  ")
+			if err := encoder.Encode(doc); err != nil {
+				return err
+			}
+			_, err := io.WriteString(w, "\n")
+			return err
+		})
+	}), nil
+}
@@ -0,0 +1,273 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Host describes the machine running a benchmark.
+type Host struct {
+	Name   string `json:"name,omitempty"`
+	OS     string `json:"os"`
+	Arch   string `json:"arch"`
+	CPUs   int    `json:"cpus"`
+	Kernel string `json:"kernel,omitempty"` // release of the kernel, when known
+	// CPUScore is the score of the host on the CPUScoreWorkload, when measured.
+	CPUScore         float64 `json:"cpu_score,omitempty"`
+	CPUScoreWorkload string  `json:"cpu_score_workload,omitempty"`
+}
+
+// CurrentHost describes the machine running the program.
+func CurrentHost() Host {
+	host := Host{OS: runtime.GOOS, Arch: runtime.GOARCH, CPUs: runtime.NumCPU()}
+	host.Name, _ = os.Hostname()
+	if release, err := ioutil.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		host.Kernel = strings.TrimSpace(string(release))
+	}
+	return host
+}
+
+// Fingerprint identifies the setup of a benchmark on the current host, so that only results of
+// the exact same setup get compared. It covers the definition of the benchmark, except its name,
+// documentation, number of runs and callbacks, and the host.
+func (b *Benchmark) Fingerprint() string {
+	var docker *DockerOptions
+	if b.Launcher == "docker" {
+		docker = &b.Docker
+	}
+	var kubernetes *KubernetesOptions
+	if b.Launcher == "kubernetes" || b.Mode == "kubernetes-ready" {
+		kubernetes = &b.Kubernetes
+	}
+	definition := struct {
+		Mode, Target, Command           string
+		Args, Env                       []string
+		Launcher, Checkpoint            string
+		Deploy                          DeployOptions
+		DryRuns                         int
+		Pause                           time.Duration
+		HTTP                            HTTPOptions
+		TCP                             TCPOptions
+		Prom                            PromOptions
+		Health                          HealthOptions
+		Callback                        CallbackOptions
+		File                            FileOptions
+		LogFile                         LogFileOptions
+		MaxProbeRate                    float64
+		ReadyAfterRequests              int
+		WatchPorts                      map[string]string
+		LingeringSockets, UpgradeSignal string
+		CollectJVMMetrics               bool
+		Host                            Host
+		// Fields added later are omitted when empty, so that existing fingerprints do not change.
+		Settle          bool             `json:",omitempty"`
+		Systemd         SystemdOptions   `json:",omitempty"`
+		ReservedCPUs    int              `json:",omitempty"`
+		ReadOnlyRootfs  bool             `json:",omitempty"`
+		Capabilities    string           `json:",omitempty"`
+		Seccomp         string           `json:",omitempty"`
+		AutoTarget      bool             `json:",omitempty"`
+		ShutdownSignal  string           `json:",omitempty"`
+		ShutdownGrace   time.Duration    `json:",omitempty"`
+		PollInterval    time.Duration    `json:",omitempty"`
+		PollBackoff     float64          `json:",omitempty"`
+		PollMaxInterval time.Duration    `json:",omitempty"`
+		StableFor       time.Duration    `json:",omitempty"`
+		CrashRecovery   bool             `json:",omitempty"`
+		Events          []LifecycleEvent `json:",omitempty"`
+		LogMatchStream  string           `json:",omitempty"`
+		Milestones      []LifecycleEvent `json:",omitempty"`
+		WorkDir         string           `json:",omitempty"`
+		StdinFile       string           `json:",omitempty"`
+		CloseStdin      bool             `json:",omitempty"`
+		Docker          *DockerOptions   `json:",omitempty"`
+
+		Kubernetes *KubernetesOptions `json:",omitempty"`
+	}{
+		b.Mode, b.Target, b.Command, b.Args, b.Env, b.Launcher, b.Checkpoint, b.Deploy, b.DryRuns, b.Pause,
+		b.HTTP, b.TCP, b.Prom, b.Health, b.Callback, b.File, b.LogFile, b.MaxProbeRate, b.ReadyAfterRequests,
+		b.WatchPorts, b.LingeringSockets, b.UpgradeSignal, b.CollectJVMMetrics, CurrentHost(),
+		b.Settle, b.Systemd, b.ReservedCPUs, b.ReadOnlyRootfs, b.Capabilities, b.Seccomp, b.AutoTarget,
+		b.ShutdownSignal, b.ShutdownGrace, b.PollInterval, b.PollBackoff, b.PollMaxInterval,
+		b.StableFor, b.CrashRecovery, b.Events,
+		b.LogMatch.Stream, b.Milestones,
+		b.WorkDir, b.StdinFile, b.CloseStdin, docker, kubernetes,
+	}
+	data, _ := json.Marshal(definition) // maps are encoded with sorted keys
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// JournalEntry is an entry of a benchmark journal.
+type JournalEntry struct {
+	Fingerprint string     `json:"fingerprint"` // see Benchmark.Fingerprint
+	RecordedAt  time.Time  `json:"recorded_at"`
+	Host        Host       `json:"host"`
+	Commit      string     `json:"commit,omitempty"`       // of the benchmarked server, when known
+	CommittedAt *time.Time `json:"committed_at,omitempty"` // date of the commit, when known
+	Results     *Results   `json:"results"`
+}
+
+// Time is the time of the entry in the history of the server: the date of its commit when known,
+// as for the entries backfilled by a sweep of old commits, and otherwise the time it was recorded.
+func (e JournalEntry) Time() time.Time {
+	if e.CommittedAt != nil {
+		return *e.CommittedAt
+	}
+	return e.RecordedAt
+}
+
+// NewJournalEntry creates the journal entry of results of a benchmark.
+func NewJournalEntry(b *Benchmark, results *Results) JournalEntry {
+	return JournalEntry{Fingerprint: b.Fingerprint(), RecordedAt: time.Now(), Host: CurrentHost(), Results: results}
+}
+
+// AppendJournal appends an entry to the journal at path, a file of JSON lines that is created
+// along with its directory if needed. Registered secrets are redacted from the journal.
+func AppendJournal(path string, entry JournalEntry) error {
+	data, err := json.Marshal(RedactValue(entry))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	unlock, err := lockJournal(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadJournal calls handle with each entry of the journal at path, in the order they were added.
+// A journal that does not exist has no entry, and entries without a valid fingerprint, as when
+// edited by hand, are rejected.
+func ReadJournal(path string, handle func(entry JournalEntry) error) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	for n := 1; ; n++ {
+		var entry JournalEntry
+		if err := decoder.Decode(&entry); err == io.EOF {
+			return nil
+		} else if err == io.ErrUnexpectedEOF {
+			// An interrupted append left a partial line, keep the complete entries.
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !validFingerprint(entry.Fingerprint) {
+			return fmt.Errorf("%s: entry %d has an invalid fingerprint: %q", path, n, entry.Fingerprint)
+		}
+		if err := handle(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// validFingerprint tells whether s is a fingerprint as computed by Benchmark.Fingerprint.
+func validFingerprint(s string) bool {
+	decoded, err := hex.DecodeString(s)
+	return err == nil && len(decoded) == sha256.Size
+}
+
+// lockJournal locks the journal at path against concurrent appends and rewrites, through a lock
+// file next to it since rewrites replace the journal file.
+func lockJournal(path string) (func(), error) {
+	return lockFile(path + ".lock")
+}
+
+// RewriteJournal replaces the entries of the journal at path with those that rewrite returns,
+// through a temporary file renamed over the journal so that it is never left half written. The
+// journal stays locked from reading its entries to the rename, so that no entry appended meanwhile
+// is lost. A journal that does not exist is left as is.
+func RewriteJournal(path string, rewrite func(entries []JournalEntry) []JournalEntry) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	unlock, err := lockJournal(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	var entries []JournalEntry
+	err = ReadJournal(path, func(entry JournalEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	entries = rewrite(entries)
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err == nil {
+			_, err = file.Write(append(data, '\n'))
+		}
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return err
+		}
+	}
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JVMMetrics holds HotSpot counters read once the server became ready.
+type JVMMetrics struct {
+	LoadedClasses int64         `json:"loaded_classes"` // including classes from the shared archive
+	JITTime       time.Duration `json:"jit_time_ns"`    // spent by the JIT compilers
+	GCPauses      int64         `json:"gc_pauses"`      // collections of all collectors
+	GCTime        time.Duration `json:"gc_time_ns"`     // spent in collections
+}
+
+// collectJVMMetrics reads the performance counters of the JVM of a process with jcmd.
+func collectJVMMetrics(ctx context.Context, pid int) (*JVMMetrics, error) {
+	jcmd, err := jcmdPath()
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, jcmd, strconv.Itoa(pid), "PerfCounter.print")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("jcmd failed: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parsePerfCounters(out)
+}
+
+// jcmdPath looks for jcmd in the path, then in JAVA_HOME.
+func jcmdPath() (string, error) {
+	if path, err := exec.LookPath("jcmd"); err == nil {
+		return path, nil
+	}
+	if home := os.Getenv("JAVA_HOME"); len(home) > 0 {
+		path := filepath.Join(home, "bin", "jcmd")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("jcmd not found in the path nor in JAVA_HOME")
+}
+
+// parsePerfCounters extracts metrics from the output of jcmd PerfCounter.print, made of
+// name=value lines where times are expressed in ticks of sun.os.hrt.frequency.
+func parsePerfCounters(out []byte) (*JVMMetrics, error) {
+	counters := map[string]int64{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		i := strings.IndexByte(line, '=')
+		if i <= 0 {
+			continue
+		}
+		if value, err := strconv.ParseInt(strings.TrimSpace(line[i+1:]), 10, 64); err == nil {
+			counters[strings.TrimSpace(line[:i])] = value
+		}
+	}
+	frequency := counters["sun.os.hrt.frequency"]
+	if frequency <= 0 {
+		return nil, fmt.Errorf("no high-resolution timer frequency in the JVM counters")
+	}
+	ticks := func(n int64) time.Duration {
+		return time.Duration(float64(n) / float64(frequency) * float64(time.Second))
+	}
+	metrics := &JVMMetrics{
+		LoadedClasses: counters["java.cls.loadedClasses"] + counters["java.cls.sharedLoadedClasses"],
+		JITTime:       ticks(counters["sun.ci.totalTime"]),
+	}
+	for name, value := range counters {
+		if !strings.HasPrefix(name, "sun.gc.collector.") {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(name, ".invocations"):
+			metrics.GCPauses += value
+		case strings.HasSuffix(name, ".time"):
+			metrics.GCTime += ticks(value)
+		}
+	}
+	return metrics, nil
+}
@@ -0,0 +1,243 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KubernetesOptions configures the kubernetes launcher and the kubernetes-ready mode.
+type KubernetesOptions struct {
+	// Context and Namespace select the cluster and namespace of kubectl, its defaults when empty.
+	Context   string `yaml:"context"`
+	Namespace string `yaml:"namespace"`
+	// Resource is the workload of the manifest, as in deployment/app or pod/app, whose readiness the
+	// kubernetes-ready mode waits for and that PortForward forwards to.
+	Resource string `yaml:"resource"`
+	// PortForward are local ports forwarded to Resource during the runs, as in 8080:8080, so that
+	// the server can also be probed from the host, as with the http-get mode.
+	PortForward []string `yaml:"port_forward"`
+}
+
+// portForwardRetryInterval is how long the kubernetes launcher waits before forwarding ports
+// again, as forwarding fails until a pod runs.
+const portForwardRetryInterval = 100 * time.Millisecond
+
+func init() {
+	RegisterLauncher("kubernetes", newKubernetesLauncher)
+	RegisterProbe("kubernetes-ready", newKubernetesReadyProbe)
+}
+
+func (o KubernetesOptions) kubectlArgs(args ...string) []string {
+	var global []string
+	if len(o.Context) > 0 {
+		global = append(global, "--context", o.Context)
+	}
+	if len(o.Namespace) > 0 {
+		global = append(global, "--namespace", o.Namespace)
+	}
+	return append(global, args...)
+}
+
+// kubernetesLauncher applies the manifest given as command with kubectl apply, the arguments being
+// extra kubectl apply options, and deletes what it created after each run, waiting for its pods to
+// be gone so that the next run starts cold. The run is measured from applying the manifest.
+type kubernetesLauncher struct {
+	manifest    string
+	args        []string
+	env         []string
+	options     KubernetesOptions
+	applied     bool
+	stopForward context.CancelFunc
+	forwarding  sync.WaitGroup
+}
+
+func newKubernetesLauncher(b *Benchmark) (Launcher, error) {
+	if _, err := exec.LookPath("kubectl"); err != nil {
+		return nil, fmt.Errorf("the kubernetes launcher needs the kubectl command: %v", err)
+	}
+	if len(b.Kubernetes.PortForward) > 0 && len(b.Kubernetes.Resource) == 0 {
+		return nil, errors.New("forwarding ports needs the resource to forward to")
+	}
+	return &kubernetesLauncher{manifest: b.Command, args: b.Args, env: b.Env, options: b.Kubernetes}, nil
+}
+
+func (l *kubernetesLauncher) kubectl(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "kubectl", l.options.kubectlArgs(args...)...)
+	if len(l.env) > 0 {
+		cmd.Env = append(os.Environ(), l.env...)
+	}
+	return cmd
+}
+
+func (l *kubernetesLauncher) Start(ctx context.Context, stdout, stderr io.Writer) error {
+	cmd := l.kubectl(ctx, append([]string{"apply", "--filename", l.manifest}, l.args...)...)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	l.applied = true // even a failed apply may have created some resources
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("kubectl apply failed: %v", err)
+	}
+	if len(l.options.PortForward) > 0 {
+		var forwardCtx context.Context
+		forwardCtx, l.stopForward = context.WithCancel(ctx)
+		l.forwarding.Add(1)
+		go l.forward(forwardCtx)
+	}
+	return nil
+}
+
+// forward forwards the ports until ctx is done, again whenever forwarding stops, as when the
+// resource has no running pod yet.
+func (l *kubernetesLauncher) forward(ctx context.Context) {
+	defer l.forwarding.Done()
+	for ctx.Err() == nil {
+		l.kubectl(ctx, append([]string{"port-forward", l.options.Resource}, l.options.PortForward...)...).Run()
+		select {
+		case <-ctx.Done():
+		case <-time.After(portForwardRetryInterval):
+		}
+	}
+}
+
+// Pid returns 0 since the server runs in the cluster.
+func (l *kubernetesLauncher) Pid() int {
+	return 0
+}
+
+// Signal does nothing, the resources of the manifest are deleted by Cleanup.
+func (l *kubernetesLauncher) Signal(sig os.Signal) error {
+	return nil
+}
+
+func (l *kubernetesLauncher) Wait() (Termination, error) {
+	return Termination{}, nil
+}
+
+func (l *kubernetesLauncher) Cleanup() error {
+	if l.stopForward != nil {
+		l.stopForward()
+		l.forwarding.Wait()
+	}
+	if !l.applied {
+		return nil
+	}
+	cmd := l.kubectl(context.Background(), "delete", "--filename", l.manifest, "--ignore-not-found", "--wait", "--cascade=foreground")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("kubectl delete failed: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// kubernetesReadyProbe checks the readiness of a workload with kubectl: the Ready condition of a
+// pod, or all the replicas of the latest generation of other workloads, as deployments and
+// stateful sets, being ready.
+type kubernetesReadyProbe struct {
+	resource string
+	options  KubernetesOptions
+	env      []string
+}
+
+func newKubernetesReadyProbe(b *Benchmark) (Probe, error) {
+	if len(b.Kubernetes.Resource) == 0 {
+		return nil, errors.New("the kubernetes-ready mode needs the resource to wait for, as in deployment/app")
+	}
+	return &kubernetesReadyProbe{resource: b.Kubernetes.Resource, options: b.Kubernetes, env: b.Env}, nil
+}
+
+func (p *kubernetesReadyProbe) Setup(ctx context.Context) error {
+	return nil
+}
+
+func (p *kubernetesReadyProbe) Teardown() error {
+	return nil
+}
+
+// kubernetesWorkload is what the kubernetes-ready mode reads of a workload.
+type kubernetesWorkload struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Generation int64 `json:"generation"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas *int32 `json:"replicas"`
+	} `json:"spec"`
+	Status struct {
+		ObservedGeneration int64 `json:"observedGeneration"`
+		ReadyReplicas      int32 `json:"readyReplicas"`
+		Conditions         []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+func (w *kubernetesWorkload) ready() error {
+	if w.Kind == "Pod" {
+		for _, condition := range w.Status.Conditions {
+			if condition.Type == "Ready" && condition.Status == "True" {
+				return nil
+			}
+		}
+		return errors.New("the pod is not ready")
+	}
+	replicas := int32(1)
+	if w.Spec.Replicas != nil {
+		replicas = *w.Spec.Replicas
+	}
+	if w.Status.ObservedGeneration < w.Metadata.Generation {
+		return fmt.Errorf("the %s has not been reconciled yet", strings.ToLower(w.Kind))
+	}
+	if w.Status.ReadyReplicas < replicas {
+		return fmt.Errorf("%d of %d replicas are ready", w.Status.ReadyReplicas, replicas)
+	}
+	return nil
+}
+
+func (p *kubernetesReadyProbe) Check(ctx context.Context) (ProbeResult, error) {
+	cmd := exec.CommandContext(ctx, "kubectl", p.options.kubectlArgs("get", p.resource, "--output", "json")...)
+	if len(p.env) > 0 {
+		cmd.Env = append(os.Environ(), p.env...)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return ProbeResult{}, fmt.Errorf("kubectl get failed: %v %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return ProbeResult{}, err
+	}
+	var workload kubernetesWorkload
+	if err := json.Unmarshal(out, &workload); err != nil {
+		return ProbeResult{}, fmt.Errorf("unexpected kubectl output: %v", err)
+	}
+	return ProbeResult{}, workload.ready()
+}
@@ -0,0 +1,303 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Launcher runs the server of a benchmark in some execution environment.
+//
+// A new launcher is created for each run. Start spawns the server and forwards its output to stdout
+// and stderr when they are not nil, Signal and Wait control its termination, and Cleanup releases
+// whatever the launcher allocated, even when Start failed.
+type Launcher interface {
+	Start(ctx context.Context, stdout, stderr io.Writer) error
+	// Pid returns the process identifier of the server on the local host, or 0 when not applicable.
+	Pid() int
+	Signal(sig os.Signal) error
+	Wait() (Termination, error)
+	Cleanup() error
+}
+
+// Termination tells how a launched server ended.
+type Termination struct {
+	Exit      *ExitStatus // nil when unknown
+	Resources Resources
+}
+
+// LauncherFactory creates a launcher for a run of a benchmark.
+type LauncherFactory func(b *Benchmark) (Launcher, error)
+
+var (
+	launchersMu sync.RWMutex
+	launchers   = make(map[string]LauncherFactory)
+)
+
+// RegisterLauncher makes a launcher available by name, replacing any launcher with the same name.
+func RegisterLauncher(name string, factory LauncherFactory) {
+	launchersMu.Lock()
+	defer launchersMu.Unlock()
+	launchers[name] = factory
+}
+
+// LauncherNames returns the sorted names of the registered launchers.
+func LauncherNames() []string {
+	launchersMu.RLock()
+	defer launchersMu.RUnlock()
+	names := make([]string, 0, len(launchers))
+	for name := range launchers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultLauncher is the launcher used when a benchmark does not specify one.
+const DefaultLauncher = "exec"
+
+func (b *Benchmark) launcherFactory() (LauncherFactory, error) {
+	name := b.Launcher
+	if len(name) == 0 {
+		name = DefaultLauncher
+	}
+	launchersMu.RLock()
+	factory, found := launchers[name]
+	launchersMu.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("unknown launcher: %s (available: %s)", name, strings.Join(LauncherNames(), ", "))
+	}
+	return factory, nil
+}
+
+func init() {
+	RegisterLauncher("exec", newExecLauncher)
+	RegisterLauncher("shell", newShellLauncher)
+}
+
+// processLauncher runs a local process.
+type processLauncher struct {
+	name string
+	args []string
+	env  []string
+	dir  string // working directory, that of the benchmark when empty
+	cpus string // list of CPUs to run on with taskset, any when empty
+	// stdinFile is fed to the standard input of the process, which is closed when closeStdin is set.
+	stdinFile  string
+	closeStdin bool
+	// readOnlyRootfs runs the process with a read-only root filesystem.
+	readOnlyRootfs bool
+	// restrict spawns the process with restricted capabilities or seccomp, when not nil.
+	restrict func(start func() error) error
+	cmd      *exec.Cmd
+	group    processGroup
+}
+
+func newExecLauncher(b *Benchmark) (Launcher, error) {
+	return newProcessLauncher(b, b.Command, b.Args), nil
+}
+
+// newProcessLauncher runs name with args in the working directory, standard input and sandbox of b.
+func newProcessLauncher(b *Benchmark, name string, args []string) *processLauncher {
+	l := &processLauncher{
+		name:       name,
+		args:       args,
+		env:        b.Env,
+		dir:        b.WorkDir,
+		stdinFile:  b.StdinFile,
+		closeStdin: b.CloseStdin,
+	}
+	return sandboxed(b, l)
+}
+
+// checkProcessSettings checks the working directory and standard input settings.
+func (b *Benchmark) checkProcessSettings() error {
+	if len(b.WorkDir) > 0 {
+		info, err := os.Stat(b.WorkDir)
+		if err != nil {
+			return fmt.Errorf("invalid working directory: %v", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("the working directory %s is not a directory", b.WorkDir)
+		}
+	}
+	if len(b.StdinFile) > 0 {
+		if b.CloseStdin {
+			return errors.New("the standard input cannot be both fed from a file and closed")
+		}
+		file, err := os.Open(b.StdinFile)
+		if err != nil {
+			return fmt.Errorf("invalid standard input file: %v", err)
+		}
+		file.Close()
+	}
+	return nil
+}
+
+// sandboxed applies the sandbox settings of b to l, see sandboxLaunchers.
+func sandboxed(b *Benchmark, l *processLauncher) *processLauncher {
+	l.readOnlyRootfs = b.ReadOnlyRootfs
+	if b.restricted() {
+		l.restrict = b.startRestricted
+	}
+	return l
+}
+
+// newShellLauncher runs the command as a shell command line, with the arguments appended.
+func newShellLauncher(b *Benchmark) (Launcher, error) {
+	line := b.Command
+	for _, arg := range b.Args {
+		line += " " + shellQuote(arg)
+	}
+	return newProcessLauncher(b, "/bin/sh", []string{"-c", line}), nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// outputWaitDelay bounds how long Wait keeps copying the output of a process once it exited,
+// since processes it spawned may keep the output pipes open.
+const outputWaitDelay = time.Second
+
+// closedStdinCommand runs name with args with its standard input closed, the shell executes the
+// command in place so that the process remains the server.
+func closedStdinCommand(name string, args []string) (string, []string) {
+	return "/bin/sh", append([]string{"-c", `exec "$@" <&-`, "sh", name}, args...)
+}
+
+func (l *processLauncher) Start(ctx context.Context, stdout, stderr io.Writer) error {
+	name, args := l.name, l.args
+	if l.closeStdin {
+		name, args = closedStdinCommand(name, args)
+	}
+	if l.readOnlyRootfs {
+		name, args = readOnlyRootfsCommand(name, args)
+	}
+	if len(l.cpus) > 0 {
+		// taskset executes the command in place, so the process remains the server.
+		name, args = "taskset", append([]string{"--cpu-list", l.cpus, name}, args...)
+	}
+	l.cmd = exec.CommandContext(ctx, name, args...)
+	l.cmd.Stdout = stdout
+	l.cmd.Stderr = stderr
+	if len(l.env) > 0 {
+		l.cmd.Env = append(os.Environ(), l.env...)
+	}
+	if len(l.dir) > 0 {
+		dir, err := filepath.Abs(l.dir)
+		if err != nil {
+			return err
+		}
+		l.cmd.Dir = dir
+		if l.cmd.Env != nil {
+			// PWD is only updated along with the directory when the environment is inherited.
+			l.cmd.Env = append(l.cmd.Env, "PWD="+dir)
+		}
+	}
+	if len(l.stdinFile) > 0 {
+		stdin, err := os.Open(l.stdinFile)
+		if err != nil {
+			return err
+		}
+		// The process gets a descriptor of its own, so that the file can be closed once it started.
+		defer stdin.Close()
+		l.cmd.Stdin = stdin
+	}
+	l.cmd.WaitDelay = outputWaitDelay
+	l.cmd.Cancel = func() error {
+		return l.Signal(os.Kill)
+	}
+	start := l.cmd.Start
+	if l.restrict != nil {
+		start = func() error {
+			return l.restrict(l.cmd.Start)
+		}
+	}
+	// The process runs in a group of its own, so that the processes it spawns, as wrapper scripts
+	// do, are stopped along with it.
+	return l.startInGroup(start)
+}
+
+func (l *processLauncher) Pid() int {
+	if l.cmd == nil || l.cmd.Process == nil {
+		return 0
+	}
+	return l.cmd.Process.Pid
+}
+
+// Signal signals the group of the process, see signalGroup.
+func (l *processLauncher) Signal(sig os.Signal) error {
+	if l.cmd == nil || l.cmd.Process == nil {
+		return errors.New("the process has not been started")
+	}
+	if l.signalGroup(sig) == nil {
+		return nil
+	}
+	return l.cmd.Process.Signal(sig)
+}
+
+func (l *processLauncher) Wait() (Termination, error) {
+	if l.cmd == nil || l.cmd.Process == nil {
+		return Termination{}, errors.New("the process has not been started")
+	}
+	err := l.cmd.Wait()
+	state := l.cmd.ProcessState
+	if state == nil {
+		return Termination{}, err
+	}
+	termination := Termination{
+		Exit:      &ExitStatus{Code: state.ExitCode()},
+		Resources: Resources{UserCPU: state.UserTime(), SystemCPU: state.SystemTime()},
+	}
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		termination.Exit.Signal = status.Signal().String()
+	} else {
+		termination.Exit.Signal = l.group.terminationSignal()
+	}
+	return termination, nil
+}
+
+// Cleanup kills the processes left in the group of the process, such as a server that a wrapper
+// script started in the background before exiting, so that they do not hold the port of the server
+// during the next run.
+func (l *processLauncher) Cleanup() error {
+	if l.cmd == nil || l.cmd.Process == nil {
+		return nil
+	}
+	if err := l.killGroup(); err != nil {
+		return fmt.Errorf("unable to kill the processes left by the server: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+//go:build !windows
+
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"os"
+	"syscall"
+)
+
+// processGroup is the process group that a process leads.
+type processGroup struct{}
+
+func (l *processLauncher) startInGroup(start func() error) error {
+	l.cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return start()
+}
+
+// signalGroup signals the process group, which holds the processes spawned by the process unless
+// they moved to groups of their own, as daemons do.
+func (l *processLauncher) signalGroup(sig os.Signal) error {
+	unixSig, ok := sig.(syscall.Signal)
+	if !ok {
+		return syscall.EINVAL
+	}
+	return syscall.Kill(-l.cmd.Process.Pid, unixSig)
+}
+
+func (l *processLauncher) killGroup() error {
+	if err := syscall.Kill(-l.cmd.Process.Pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
+
+// terminationSignal returns no signal, the exit status of a process telling the signal that
+// terminated it.
+func (g processGroup) terminationSignal() string {
+	return ""
+}
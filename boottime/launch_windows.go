@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// processGroup is the Job Object that holds a process and the processes it spawns.
+type processGroup struct {
+	job    windows.Handle
+	killed bool // whether the job was terminated, see terminationSignal
+}
+
+func (l *processLauncher) startInGroup(start func() error) error {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return err
+	}
+	// Closing the last handle to the job kills its processes, should Cleanup not be called.
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+			LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE,
+		},
+	}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		windows.CloseHandle(job)
+		return err
+	}
+	// The process starts suspended and only runs once in the job, so that every process it spawns
+	// belongs to the job too.
+	l.cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: windows.CREATE_NEW_PROCESS_GROUP | windows.CREATE_SUSPENDED}
+	if err := start(); err != nil {
+		windows.CloseHandle(job)
+		return err
+	}
+	if err := assignAndResume(job, uint32(l.cmd.Process.Pid)); err != nil {
+		l.cmd.Process.Kill()
+		l.cmd.Wait()
+		windows.CloseHandle(job)
+		return err
+	}
+	l.group = processGroup{job: job}
+	return nil
+}
+
+// assignAndResume assigns a suspended process to a job, then resumes its threads.
+func assignAndResume(job windows.Handle, pid uint32) error {
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, pid)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(process)
+	if err := windows.AssignProcessToJobObject(job, process); err != nil {
+		return err
+	}
+	// The handle of the main thread is closed once the process started, it is found among the
+	// threads of the system.
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPTHREAD, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(snapshot)
+	entry := windows.ThreadEntry32{Size: uint32(unsafe.Sizeof(windows.ThreadEntry32{}))}
+	for err = windows.Thread32First(snapshot, &entry); err == nil; err = windows.Thread32Next(snapshot, &entry) {
+		if entry.OwnerProcessID != pid {
+			continue
+		}
+		thread, err := windows.OpenThread(windows.THREAD_SUSPEND_RESUME, false, entry.ThreadID)
+		if err != nil {
+			return err
+		}
+		_, err = windows.ResumeThread(thread)
+		windows.CloseHandle(thread)
+		if err != nil {
+			return err
+		}
+	}
+	if err != windows.ERROR_NO_MORE_FILES {
+		return err
+	}
+	return nil
+}
+
+// signalGroup terminates the job on a kill, Windows delivering no other signal.
+func (l *processLauncher) signalGroup(sig os.Signal) error {
+	if sig != os.Kill && sig != syscall.SIGKILL {
+		return errors.New("only kill signals are supported on Windows")
+	}
+	l.group.killed = true
+	if l.group.job == 0 {
+		return errors.New("the process is not in a job")
+	}
+	return windows.TerminateJobObject(l.group.job, 1)
+}
+
+func (l *processLauncher) killGroup() error {
+	if l.group.job == 0 {
+		return nil
+	}
+	l.group.killed = true
+	err := windows.TerminateJobObject(l.group.job, 1)
+	windows.CloseHandle(l.group.job)
+	l.group.job = 0
+	return err
+}
+
+// terminationSignal returns the kill signal once the job was terminated, as the exit code of its
+// processes does not tell a kill from a crash on Windows.
+func (g processGroup) terminationSignal() string {
+	if g.killed {
+		return os.Kill.String()
+	}
+	return ""
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLevel is the severity of a log entry.
+type LogLevel int
+
+// Log levels, from the most to the least verbose.
+const (
+	DebugLevel LogLevel = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var logLevelNames = map[LogLevel]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+}
+
+// ParseLogLevel returns the log level for a name among debug, info, warn and error.
+func ParseLogLevel(name string) (LogLevel, error) {
+	for level, levelName := range logLevelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+	return InfoLevel, fmt.Errorf("unknown log level: %s", name)
+}
+
+// Logger writes leveled log entries, either as text lines or as one JSON object per line.
+// Fields are passed as alternating keys and values, as in logger.Info("booted", "pid", 1234).
+// A nil *Logger discards everything.
+type Logger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level LogLevel
+	json  bool
+}
+
+// NewLogger creates a logger writing entries of at least the given level to out,
+// in the text or json format.
+func NewLogger(out io.Writer, level LogLevel, format string) (*Logger, error) {
+	switch format {
+	case "text":
+		return &Logger{out: out, level: level}, nil
+	case "json":
+		return &Logger{out: out, level: level, json: true}, nil
+	}
+	return nil, fmt.Errorf("unknown log format: %s", format)
+}
+
+// DefaultLogger writes text entries of at least the info level to the standard error stream.
+func DefaultLogger() *Logger {
+	return &Logger{out: os.Stderr, level: InfoLevel}
+}
+
+// Debug logs at the debug level.
+func (l *Logger) Debug(msg string, fields ...interface{}) { l.write(DebugLevel, msg, fields) }
+
+// Info logs at the info level.
+func (l *Logger) Info(msg string, fields ...interface{}) { l.write(InfoLevel, msg, fields) }
+
+// Warn logs at the warn level.
+func (l *Logger) Warn(msg string, fields ...interface{}) { l.write(WarnLevel, msg, fields) }
+
+// Error logs at the error level.
+func (l *Logger) Error(msg string, fields ...interface{}) { l.write(ErrorLevel, msg, fields) }
+
+func (l *Logger) write(level LogLevel, msg string, fields []interface{}) {
+	if l == nil || level < l.level {
+		return
+	}
+	now := time.Now()
+	msg = Redact(msg)
+	var line string
+	if l.json {
+		line = jsonLogLine(now, level, msg, fields)
+	} else {
+		line = textLogLine(now, level, msg, fields)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.out, line)
+}
+
+func fieldValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return Redact(v.Error())
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return Redact(v.String())
+	case string:
+		return Redact(v)
+	}
+	return v
+}
+
+func jsonLogLine(now time.Time, level LogLevel, msg string, fields []interface{}) string {
+	entry := map[string]interface{}{
+		"time":  now.Format(time.RFC3339Nano),
+		"level": logLevelNames[level],
+		"msg":   msg,
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		entry[fmt.Sprint(fields[i])] = fieldValue(fields[i+1])
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"level": "error", "msg": "unable to encode log entry", "error": err.Error()})
+	}
+	return string(data) + "\n"
+}
+
+func textLogLine(now time.Time, level LogLevel, msg string, fields []interface{}) string {
+	parts := []string{now.Format("2006-01-02 15:04:05.000"), strings.ToUpper(logLevelNames[level]), msg}
+	pairs := make([]string, 0, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", fields[i], fieldValue(fields[i+1])))
+	}
+	return strings.Join(append(parts, pairs...), " ") + "\n"
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// LogFileOptions configures the logfile mode, where the server is ready once a line matching a
+// pattern is appended to the log file given as target, or to the journal of a systemd unit.
+type LogFileOptions struct {
+	Pattern string `yaml:"pattern"` // regular expression matched by the readiness line
+	Unit    string `yaml:"unit"`    // systemd unit whose journal is tailed instead of a file
+}
+
+func init() {
+	RegisterProbe("logfile", newLogFileProbe)
+}
+
+func newLogFileProbe(b *Benchmark) (Probe, error) {
+	if len(b.LogFile.Pattern) == 0 {
+		return nil, errors.New("the logfile mode needs a readiness pattern")
+	}
+	pattern, err := regexp.Compile(b.LogFile.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid readiness pattern: %v", err)
+	}
+	if len(b.LogFile.Unit) > 0 {
+		return &journalProbe{unit: b.LogFile.Unit, pattern: pattern}, nil
+	}
+	if len(b.Target) == 0 {
+		return nil, errors.New("the logfile mode needs a log file as target, or a systemd unit")
+	}
+	return &logFileProbe{path: b.Target, pattern: pattern}, nil
+}
+
+// logFileProbe tails a log file from where it ended when the run started.
+type logFileProbe struct {
+	path    string
+	pattern *regexp.Regexp
+	watcher fileWatcher
+	offset  int64
+	partial []byte // last line, until it is complete
+}
+
+func (p *logFileProbe) Setup(ctx context.Context) error {
+	p.offset, p.partial = 0, nil
+	if info, err := os.Stat(p.path); err == nil {
+		p.offset = info.Size()
+	}
+	watcher, err := newFileWatcher(filepath.Dir(p.path))
+	if err != nil {
+		return err
+	}
+	p.watcher = watcher
+	return nil
+}
+
+func (p *logFileProbe) Teardown() error {
+	return p.watcher.close()
+}
+
+// Check reads the lines appended since the previous check, or waits for the file to change.
+func (p *logFileProbe) Check(ctx context.Context) (ProbeResult, error) {
+	found, err := p.scan()
+	if err != nil && !os.IsNotExist(err) {
+		return ProbeResult{}, err
+	}
+	if found {
+		return ProbeResult{}, nil
+	}
+	if err := p.watcher.wait(ctx, fileRecheckInterval); err != nil {
+		return ProbeResult{}, err
+	}
+	return ProbeResult{}, fmt.Errorf("no line of %s matches %s", p.path, p.pattern)
+}
+
+func (p *logFileProbe) scan() (bool, error) {
+	file, err := os.Open(p.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() < p.offset {
+		p.offset, p.partial = 0, nil // truncated or rotated
+	}
+	if _, err := file.Seek(p.offset, io.SeekStart); err != nil {
+		return false, err
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return false, err
+	}
+	p.offset += int64(len(content))
+	content = append(p.partial, content...)
+	end := bytes.LastIndexByte(content, '\n')
+	p.partial = append([]byte(nil), content[end+1:]...)
+	for _, line := range bytes.Split(content[:end+1], []byte("\n")) {
+		if p.pattern.Match(line) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// journalProbe follows the journal of a systemd unit with journalctl, from the moment the run started.
+type journalProbe struct {
+	unit    string
+	pattern *regexp.Regexp
+	cmd     *exec.Cmd
+	lines   chan string
+}
+
+func (p *journalProbe) Setup(ctx context.Context) error {
+	since := time.Now().Format("2006-01-02 15:04:05.000000")
+	p.cmd = exec.Command("journalctl", "--follow", "--output=cat", "--unit="+p.unit, "--since="+since)
+	stdout, err := p.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := p.cmd.Start(); err != nil {
+		return fmt.Errorf("unable to follow the journal: %v", err)
+	}
+	p.lines = make(chan string, 64)
+	go func(lines chan<- string) {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}(p.lines)
+	return nil
+}
+
+func (p *journalProbe) Teardown() error {
+	p.cmd.Process.Kill()
+	for range p.lines {
+	}
+	p.cmd.Wait()
+	return nil
+}
+
+// Check waits for the next line of the journal.
+func (p *journalProbe) Check(ctx context.Context) (ProbeResult, error) {
+	select {
+	case line, open := <-p.lines:
+		if !open {
+			return ProbeResult{}, errors.New("journalctl stopped")
+		}
+		if p.pattern.MatchString(line) {
+			return ProbeResult{}, nil
+		}
+		return ProbeResult{}, fmt.Errorf("no line of the journal of %s matches %s", p.unit, p.pattern)
+	case <-ctx.Done():
+		return ProbeResult{}, ctx.Err()
+	}
+}
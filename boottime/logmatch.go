@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// LogMatchOptions configures the log-match mode, where the target is a regular expression and the
+// server is ready once it writes a matching line to its standard output or error.
+type LogMatchOptions struct {
+	Stream string `yaml:"stream"` // Stdout or Stderr to only watch one of them, both when empty
+}
+
+func init() {
+	RegisterProbe("log-match", newLogMatchProbe)
+}
+
+// logMatchRecheckInterval is how long a check waits for a matching line before failing, so that
+// attempts keep being reported while the server boots.
+const logMatchRecheckInterval = 100 * time.Millisecond
+
+// logMatchProbe watches the output of the server for a line matching its pattern.
+type logMatchProbe struct {
+	pattern *regexp.Regexp
+	stream  string
+	mu      sync.Mutex
+	matched chan struct{} // closed once a line matched
+	done    bool
+}
+
+func newLogMatchProbe(b *Benchmark) (Probe, error) {
+	if len(b.Target) == 0 {
+		return nil, errors.New("the log-match mode needs a regular expression as target, as in 'Started .* in'")
+	}
+	pattern, err := regexp.Compile(b.Target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid readiness pattern: %v", err)
+	}
+	switch b.LogMatch.Stream {
+	case "", Stdout, Stderr:
+	default:
+		return nil, fmt.Errorf("unknown stream: %s (expected %s or %s)", b.LogMatch.Stream, Stdout, Stderr)
+	}
+	return &logMatchProbe{pattern: pattern, stream: b.LogMatch.Stream}, nil
+}
+
+func (p *logMatchProbe) Setup(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.matched, p.done = make(chan struct{}), false
+	return nil
+}
+
+func (p *logMatchProbe) Teardown() error { return nil }
+
+func (p *logMatchProbe) Output(line OutputLine) {
+	if len(p.stream) > 0 && line.Stream != p.stream {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.done && p.matched != nil && p.pattern.MatchString(line.Text) {
+		close(p.matched)
+		p.done = true
+	}
+}
+
+// Check waits for a matching line for a while.
+func (p *logMatchProbe) Check(ctx context.Context) (ProbeResult, error) {
+	p.mu.Lock()
+	matched := p.matched
+	p.mu.Unlock()
+	timer := time.NewTimer(logMatchRecheckInterval)
+	defer timer.Stop()
+	select {
+	case <-matched:
+		return ProbeResult{}, nil
+	case <-ctx.Done():
+		return ProbeResult{}, ctx.Err()
+	case <-timer.C:
+		return ProbeResult{}, fmt.Errorf("no output line matches %s", p.pattern)
+	}
+}
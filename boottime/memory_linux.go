@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readMemory sums the VmRSS and VmHWM lines of /proc/<pid>/status over the process and its live
+// descendants, as readCPUTime does.
+func readMemory(pid int) (*Memory, error) {
+	stats, err := readProcStats()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := processTree(stats, pid)
+	if err != nil {
+		return nil, err
+	}
+	memory := &Memory{}
+	for _, id := range tree {
+		process, err := readProcessMemory(id)
+		if err != nil {
+			if id == pid {
+				return nil, err
+			}
+			continue // the descendant exited
+		}
+		memory.ReadyRSS += process.ReadyRSS
+		memory.PeakRSS += process.PeakRSS
+	}
+	return memory, nil
+}
+
+// readProcessMemory reads the VmRSS and VmHWM lines of /proc/<pid>/status.
+func readProcessMemory(pid int) (*Memory, error) {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	memory := &Memory{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[2] != "kB" {
+			continue
+		}
+		var target *int64
+		switch fields[0] {
+		case "VmRSS:":
+			target = &memory.ReadyRSS
+		case "VmHWM:":
+			target = &memory.PeakRSS
+		default:
+			continue
+		}
+		kilobytes, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected status line: %q", scanner.Text())
+		}
+		*target = kilobytes * 1024
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if memory.ReadyRSS == 0 {
+		return nil, fmt.Errorf("no resident set size in the status of process %d", pid)
+	}
+	return memory, nil
+}
@@ -0,0 +1,65 @@
+//go:build !linux && !windows
+
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// readMemory sums the resident set sizes of the process and its live descendants with ps, which
+// has no peak.
+func readMemory(pid int) (*Memory, error) {
+	out, err := exec.Command("ps", "-A", "-o", "pid=,ppid=,rss=").Output()
+	if err != nil {
+		return nil, fmt.Errorf("ps failed: %v", err)
+	}
+	rss := map[int]int64{}
+	children := map[int][]int{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected ps output: %q", line)
+		}
+		var values [3]int64 // pid, ppid and rss in kilobytes
+		for i, field := range fields {
+			if values[i], err = strconv.ParseInt(field, 10, 64); err != nil {
+				return nil, fmt.Errorf("unexpected ps output: %q", line)
+			}
+		}
+		rss[int(values[0])] = values[2] * 1024
+		children[int(values[1])] = append(children[int(values[1])], int(values[0]))
+	}
+	if _, found := rss[pid]; !found {
+		return nil, fmt.Errorf("no such process: %d", pid)
+	}
+	memory := &Memory{}
+	for tree := []int{pid}; len(tree) > 0; tree = append(tree[1:], children[tree[0]]...) {
+		memory.ReadyRSS += rss[tree[0]]
+	}
+	return memory, nil
+}
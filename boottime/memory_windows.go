@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// processMemoryCounters is the PROCESS_MEMORY_COUNTERS structure of GetProcessMemoryInfo.
+type processMemoryCounters struct {
+	Size                       uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+var procGetProcessMemoryInfo = windows.NewLazySystemDLL("psapi.dll").NewProc("GetProcessMemoryInfo")
+
+// readMemory sums the working sets and their peaks of the process and its live descendants.
+func readMemory(pid int) (*Memory, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(snapshot)
+	children := map[uint32][]uint32{}
+	entry := windows.ProcessEntry32{Size: uint32(unsafe.Sizeof(windows.ProcessEntry32{}))}
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		// The idle process is its own parent.
+		if entry.ProcessID != entry.ParentProcessID {
+			children[entry.ParentProcessID] = append(children[entry.ParentProcessID], entry.ProcessID)
+		}
+	}
+	if err != windows.ERROR_NO_MORE_FILES {
+		return nil, err
+	}
+	memory := &Memory{}
+	for tree := []uint32{uint32(pid)}; len(tree) > 0; tree = append(tree[1:], children[tree[0]]...) {
+		counters, err := readProcessMemory(tree[0])
+		if err != nil {
+			if tree[0] == uint32(pid) {
+				return nil, err
+			}
+			continue // the descendant exited
+		}
+		memory.ReadyRSS += int64(counters.WorkingSetSize)
+		memory.PeakRSS += int64(counters.PeakWorkingSetSize)
+	}
+	return memory, nil
+}
+
+func readProcessMemory(pid uint32) (*processMemoryCounters, error) {
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION|windows.PROCESS_VM_READ, false, pid)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(process)
+	counters := &processMemoryCounters{Size: uint32(unsafe.Sizeof(processMemoryCounters{}))}
+	if ok, _, err := procGetProcessMemoryInfo.Call(uintptr(process), uintptr(unsafe.Pointer(counters)), uintptr(counters.Size)); ok == 0 {
+		return nil, err
+	}
+	return counters, nil
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Misconfiguration tells why the first run of a benchmark suggests that the benchmark measures
+// something else than the boot of the server.
+type Misconfiguration struct {
+	Run         Run
+	Symptom     string   // what was observed
+	Causes      []string // likely causes
+	Suggestions []string // settings that may help
+}
+
+// ErrMisconfigured is returned when OnMisconfiguration stops a benchmark.
+var ErrMisconfigured = errors.New("the benchmark looks misconfigured")
+
+// instantReadiness is the duration under which the first run being ready is suspicious, as no
+// server boots that fast.
+const instantReadiness = time.Millisecond
+
+// misconfiguration inspects the first run of a benchmark, and returns nil when it looks fine.
+func (s *session) misconfiguration(run Run) *Misconfiguration {
+	var checks []string
+	if port := targetPort(s.Target); port > 0 {
+		checks = append(checks, fmt.Sprintf("check what listens on port %d, as with lsof -i :%d", port, port))
+	}
+	if run.LingeringSockets["LISTEN"] > 0 {
+		return &Misconfiguration{
+			Run:     run,
+			Symptom: fmt.Sprintf("a server already listened on the port of the target %s before the process was spawned", s.Target),
+			Causes: []string{
+				"another server uses the port, such as a server left by a previous benchmark",
+				"the server is started outside the benchmark, as by a service manager",
+			},
+			Suggestions: append(checks, "stop the other server, or benchmark the server on another port"),
+		}
+	}
+	if run.Duration < instantReadiness {
+		return &Misconfiguration{
+			Run:     run,
+			Symptom: fmt.Sprintf("the target %s was ready %s after the process was spawned", s.Target, run.Duration),
+			Causes: []string{
+				"another server already listens on the port, such as a server left by a previous benchmark",
+				"the target is not the address of the benchmarked server",
+			},
+			Suggestions: append(checks, "check --target against the address the server listens to"),
+		}
+	}
+	if run.Exit != nil && len(run.Exit.Signal) == 0 && run.Shutdown == nil && !remoteLaunchers[s.Launcher] {
+		return &Misconfiguration{
+			Run:     run,
+			Symptom: fmt.Sprintf("the process exited by itself with code %d, yet the target %s was ready", run.Exit.Code, s.Target),
+			Causes: []string{
+				"the executable is a wrapper script that starts the server in the background and exits",
+				"the server failed to start, as when the port is already in use, while another server answers on the target",
+			},
+			Suggestions: append(checks,
+				"run the server in the foreground, as with exec in the last line of a wrapper script",
+				"use --launcher deploy for commands that start servers elsewhere and exit"),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Environment of the test binary when it runs as a test server, see serveTest.
+const (
+	testServerVariable = "BOOTTIME_TEST_SERVER" // behavior of the test server
+	testDelayVariable  = "BOOTTIME_TEST_DELAY"  // delay of the test server
+	testPathVariable   = "BOOTTIME_TEST_PATH"   // file written by the test server
+	testListenVariable = "BOOTTIME_TEST_LISTEN" // address the test server listens to
+)
+
+const (
+	testModeDelay       = 200 * time.Millisecond
+	testModeTolerance   = 250 * time.Millisecond // covers the time to spawn the test server
+	testServerLifetime  = time.Minute            // until killed at the end of the run
+	testServerReadyLine = "server started"
+)
+
+func TestMain(m *testing.M) {
+	if behavior := os.Getenv(testServerVariable); len(behavior) > 0 {
+		if err := serveTest(behavior); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// serveTest runs the test binary as a server that gets ready for a mode after the delay, or after
+// twice the delay for the readiness group of the health behavior.
+func serveTest(behavior string) error {
+	delay, err := time.ParseDuration(os.Getenv(testDelayVariable))
+	if err != nil {
+		return err
+	}
+	path, address := os.Getenv(testPathVariable), os.Getenv(testListenVariable)
+	started := time.Now()
+	after := func(d time.Duration) bool { return time.Since(started) >= d }
+	switch behavior {
+	case "file", "logfile", "log-match", "callback":
+		time.Sleep(delay)
+	case "prom-metric", "health-groups":
+		// Listening at once, the server only reports being ready after the delay.
+		mux := http.NewServeMux()
+		mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+			ready := 0
+			if after(delay) {
+				ready = 1
+			}
+			fmt.Fprintf(w, "# TYPE app_ready gauge\napp_ready %d\n", ready)
+		})
+		health := func(d time.Duration) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				if !after(d) {
+					http.Error(w, `{"status":"DOWN"}`, http.StatusServiceUnavailable)
+					return
+				}
+				io.WriteString(w, `{"status":"UP"}`)
+			}
+		}
+		mux.HandleFunc("/started", health(delay))
+		mux.HandleFunc("/ready", health(2*delay))
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return err
+		}
+		return http.Serve(listener, mux)
+	default:
+		return fmt.Errorf("unknown test server behavior: %s", behavior)
+	}
+	switch behavior {
+	case "file":
+		if err := os.WriteFile(path, []byte("ready\n"), 0644); err != nil {
+			return err
+		}
+	case "logfile":
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(file, "booting\n%s in %s\n", testServerReadyLine, time.Since(started))
+		file.Close()
+	case "log-match":
+		fmt.Printf("booting\n%s in %s\n", testServerReadyLine, time.Since(started))
+	case "callback":
+		resp, err := http.Post(os.Getenv(CallbackURLVariable), "text/plain", nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+	time.Sleep(testServerLifetime)
+	return nil
+}
+
+// testModeCase is a mode and how to detect the readiness of the test server with it, at the
+// expected multiple of the delay.
+type testModeCase struct {
+	mode     string
+	expected time.Duration
+	setup    func(b *Benchmark, path, address string)
+}
+
+var testModeCases = []testModeCase{
+	{
+		mode:     "file",
+		expected: testModeDelay,
+		setup: func(b *Benchmark, path, address string) {
+			b.Target, b.File.Match = path, "ready"
+		},
+	},
+	{
+		mode:     "logfile",
+		expected: testModeDelay,
+		setup: func(b *Benchmark, path, address string) {
+			b.Target, b.LogFile.Pattern = path, testServerReadyLine
+		},
+	},
+	{
+		mode:     "log-match",
+		expected: testModeDelay,
+		setup: func(b *Benchmark, path, address string) {
+			b.Target, b.LogMatch.Stream = testServerReadyLine, Stdout
+		},
+	},
+	{
+		mode:     "callback",
+		expected: testModeDelay,
+		setup:    func(b *Benchmark, path, address string) {},
+	},
+	{
+		mode:     "prom-metric",
+		expected: testModeDelay,
+		setup: func(b *Benchmark, path, address string) {
+			b.Target, b.Prom.Condition = "http://"+address+"/metrics", "app_ready == 1"
+		},
+	},
+	{
+		mode:     "health-groups",
+		expected: 2 * testModeDelay,
+		setup: func(b *Benchmark, path, address string) {
+			b.Target = "http://" + address + "/"
+			b.Health.Groups = map[string]string{StartupGroup: "started", ReadinessGroup: "ready"}
+		},
+	},
+}
+
+func TestModes(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range testModeCases {
+		t.Run(c.mode, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "server.out")
+			address := freeTestAddress(t)
+			b := &Benchmark{
+				Mode:    c.mode,
+				Command: executable,
+				Env: []string{testServerVariable + "=" + c.mode, testDelayVariable + "=" + testModeDelay.String(),
+					testPathVariable + "=" + path, testListenVariable + "=" + address},
+				DryRuns:          1,
+				Runs:             3,
+				RunTimeout:       10 * time.Second,
+				LingeringSockets: IgnoreLingeringSockets,
+			}
+			c.setup(b, path, address)
+			results, err := b.Run(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, run := range results.Measured() {
+				if run.Duration < c.expected || run.Duration > c.expected+testModeTolerance {
+					t.Errorf("run %d detected ready after %s, expected between %s and %s", run.Index, run.Duration, c.expected, c.expected+testModeTolerance)
+				}
+			}
+		})
+	}
+}
+
+// TestHealthGroupPhases checks that each health group coming up is marked as a phase.
+func TestHealthGroupPhases(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := freeTestAddress(t)
+	b := &Benchmark{
+		Mode:    "health-groups",
+		Target:  "http://" + address + "/",
+		Command: executable,
+		Env: []string{testServerVariable + "=health-groups", testDelayVariable + "=" + testModeDelay.String(),
+			testListenVariable + "=" + address},
+		Health:           HealthOptions{Groups: map[string]string{StartupGroup: "started", ReadinessGroup: "ready"}},
+		Runs:             1,
+		RunTimeout:       10 * time.Second,
+		LingeringSockets: IgnoreLingeringSockets,
+	}
+	results, err := b.Run(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets := map[string]time.Duration{}
+	for _, phase := range results.Runs[0].Phases {
+		offsets[phase.Name] = phase.Offset
+	}
+	for group, expected := range map[string]time.Duration{StartupGroup: testModeDelay, ReadinessGroup: 2 * testModeDelay} {
+		offset, found := offsets[group]
+		if !found {
+			t.Errorf("no phase for the %s group, got %v", group, results.Runs[0].Phases)
+		} else if offset < expected || offset > expected+testModeTolerance {
+			t.Errorf("the %s group came up after %s, expected between %s and %s", group, offset, expected, expected+testModeTolerance)
+		}
+	}
+}
+
+// freeTestAddress returns a local address with a port that is free at the moment.
+func freeTestAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
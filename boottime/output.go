@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// Names of the output streams of a server.
+const (
+	Stdout = "stdout"
+	Stderr = "stderr"
+)
+
+// OutputLine is a line written by the server of a run.
+type OutputLine struct {
+	Dry    bool
+	Run    int    // index of the run among the runs of the same kind
+	Stream string // Stdout or Stderr
+	Text   string // without the line terminator
+	// Offset is when the line was complete, relative to the moment the process was spawned.
+	Offset time.Duration
+}
+
+// lineWriter calls emit with each complete line written to it.
+type lineWriter struct {
+	mu      sync.Mutex
+	pending []byte
+	emit    func(text string, offset time.Duration)
+	start   time.Time
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(bytes.TrimSuffix(w.pending[:i], []byte("\r"))), time.Since(w.start))
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush emits the last line when it has no terminator.
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) > 0 {
+		w.emit(string(w.pending), time.Since(w.start))
+		w.pending = nil
+	}
+}
+
+// outputTailLines is how many of the last lines of the standard error of a server are kept.
+const outputTailLines = 20
+
+// outputTail keeps the last lines written to a stream.
+type outputTail struct {
+	mu   sync.Mutex
+	tail []string
+}
+
+func (t *outputTail) add(text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.tail) == outputTailLines {
+		t.tail = append(t.tail[:0], t.tail[1:]...)
+	}
+	t.tail = append(t.tail, text)
+}
+
+func (t *outputTail) lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.tail...)
+}
+
+// outputWriters returns the writers forwarding the output of a run spawned at start to b.OnOutput
+// and to the probes watching it, checking it for refused writes when refused is not nil and keeping
+// the last lines of the standard error in tail when it is not nil, or nil writers discarding the
+// output when there is nothing to do with it.
+func (b *Benchmark) outputWriters(spec runSpec, start time.Time, refused *refusedWrites, tail *outputTail, watchers []OutputProbe) (stdout, stderr *lineWriter) {
+	onOutput := b.OnOutput
+	if spec.calibration {
+		onOutput = nil
+	}
+	if onOutput == nil && refused == nil && tail == nil && len(watchers) == 0 {
+		return nil, nil
+	}
+	writer := func(stream string) *lineWriter {
+		return &lineWriter{start: start, emit: func(text string, offset time.Duration) {
+			if refused != nil {
+				refused.check(text)
+			}
+			if tail != nil && stream == Stderr {
+				tail.add(text)
+			}
+			line := OutputLine{Dry: spec.dry, Run: spec.index, Stream: stream, Text: text, Offset: offset}
+			for _, watcher := range watchers {
+				watcher.Output(line)
+			}
+			if onOutput != nil {
+				onOutput(line)
+			}
+		}}
+	}
+	return writer(Stdout), writer(Stderr)
+}
+
+func writerOrNil(w *lineWriter) io.Writer {
+	if w == nil {
+		return nil
+	}
+	return w
+}
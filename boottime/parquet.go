@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRun is a row of the parquet exporter, with the columns of the csv exporter followed by
+// the main observables of the run.
+type parquetRun struct {
+	Scenario      string         `parquet:"scenario,dict"`
+	Dry           bool           `parquet:"dry"`
+	Index         int64          `parquet:"index"`
+	StartedAt     int64          `parquet:"started_at,timestamp(nanosecond)"`
+	Duration      int64          `parquet:"duration_ns"`
+	Attempts      int64          `parquet:"probe_attempts"`
+	Target        string         `parquet:"target,optional,dict"`
+	UserCPU       int64          `parquet:"user_cpu_ns"`
+	SystemCPU     int64          `parquet:"system_cpu_ns"`
+	MemoryPeak    *int64         `parquet:"memory_peak_bytes,optional"`
+	ExitCode      *int64         `parquet:"exit_code,optional"`
+	ExitSignal    string         `parquet:"exit_signal,optional,dict"`
+	Phases        []parquetPhase `parquet:"phases,list"`
+	Error         string         `parquet:"error,optional"`
+	ErrorCategory string         `parquet:"error_category,optional,dict"`
+}
+
+type parquetPhase struct {
+	Name   string `parquet:"name,dict"`
+	Offset int64  `parquet:"offset_ns"`
+}
+
+// newParquetExporter writes one row per run, dry runs first, as a snappy-compressed Parquet file
+// that DuckDB, Spark and the like query without parsing JSON.
+func newParquetExporter(destination string) (Exporter, error) {
+	return ExporterFunc(func(results *Results) error {
+		return writeTo(destination, func(w io.Writer) error {
+			writer := parquet.NewGenericWriter[parquetRun](w, parquet.Compression(&parquet.Snappy))
+			rows := make([]parquetRun, len(results.Runs))
+			for i, run := range results.Runs {
+				rows[i] = newParquetRun(results.Scenario, run)
+			}
+			if _, err := writer.Write(rows); err != nil {
+				return err
+			}
+			return writer.Close()
+		})
+	}), nil
+}
+
+func newParquetRun(scenario string, run Run) parquetRun {
+	row := parquetRun{
+		Scenario:      scenario,
+		Dry:           run.Dry,
+		Index:         int64(run.Index),
+		StartedAt:     unixNano(run.StartedAt),
+		Duration:      int64(run.Duration),
+		Attempts:      int64(run.Attempts),
+		Target:        Redact(run.Target),
+		UserCPU:       int64(run.Resources.UserCPU),
+		SystemCPU:     int64(run.Resources.SystemCPU),
+		Phases:        make([]parquetPhase, len(run.Phases)),
+		Error:         Redact(run.Error),
+		ErrorCategory: run.ErrorCategory,
+	}
+	if run.Resources.MemoryPeak > 0 {
+		row.MemoryPeak = &run.Resources.MemoryPeak
+	}
+	if run.Exit != nil {
+		code := int64(run.Exit.Code)
+		row.ExitCode, row.ExitSignal = &code, run.Exit.Signal
+	}
+	for i, phase := range run.Phases {
+		row.Phases[i] = parquetPhase{Name: phase.Name, Offset: int64(phase.Offset)}
+	}
+	return row
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+func init() {
+	RegisterExporter("vega-lite", newVegaLiteExporter)
+	RegisterExporter("gnuplot", newGnuplotExporter)
+}
+
+// plotPoint is a successful run as plotted, numbered from 1 in the order of the runs.
+type plotPoint struct {
+	Scenario   string  `json:"scenario"`
+	Run        int     `json:"run"`
+	Kind       string  `json:"kind"` // dry or measured
+	DurationMS float64 `json:"duration_ms"`
+	StartedAt  string  `json:"started_at"`
+}
+
+func plotPoints(results *Results) []plotPoint {
+	points := []plotPoint{}
+	for i, run := range results.Runs {
+		if run.Failed() {
+			continue
+		}
+		kind := "measured"
+		if run.Dry {
+			kind = "dry"
+		}
+		points = append(points, plotPoint{results.Scenario, i + 1, kind, millis(run.Duration), run.StartedAt.Format("2006-01-02T15:04:05.000Z07:00")})
+	}
+	return points
+}
+
+func plotTitle(results *Results) string {
+	if len(results.Scenario) > 0 {
+		return "Boot times of " + results.Scenario
+	}
+	return "Boot times"
+}
+
+// newVegaLiteExporter writes a Vega-Lite specification plotting the boot time of every successful
+// run, with the runs as inline data, to tweak with the Vega editor or to render with vl2svg.
+func newVegaLiteExporter(destination string) (Exporter, error) {
+	return ExporterFunc(func(results *Results) error {
+		type field map[string]interface{}
+		spec := map[string]interface{}{
+			"$schema":     "https://vega.github.io/schema/vega-lite/v5.json",
+			"title":       plotTitle(results),
+			"description": "Written by time-to-boot-server, dry runs included",
+			"width":       600,
+			"height":      300,
+			"data":        field{"values": plotPoints(results)},
+			"mark":        field{"type": "point", "filled": true, "tooltip": true},
+			"encoding": field{
+				"x":     field{"field": "run", "type": "quantitative", "title": "Run"},
+				"y":     field{"field": "duration_ms", "type": "quantitative", "title": "Boot time (ms)", "scale": field{"zero": false}},
+				"color": field{"field": "scenario", "type": "nominal", "title": "Scenario"},
+				"shape": field{"field": "kind", "type": "nominal", "title": "Run"},
+			},
+		}
+		data, err := json.MarshalIndent(spec, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeTo(destination, func(w io.Writer) error {
+			_, err := w.Write(append(data, '\n'))
+			return err
+		})
+	}), nil
+}
+
+// newGnuplotExporter writes a gnuplot script plotting the boot time of every successful run, with
+// the runs in a data block.
+func newGnuplotExporter(destination string) (Exporter, error) {
+	return ExporterFunc(func(results *Results) error {
+		return writeTo(destination, func(w io.Writer) error {
+			fmt.Fprintf(w, "# %s, written by time-to-boot-server\n", plotTitle(results))
+			fmt.Fprintln(w, "# Render it with gnuplot -p, or uncomment the terminal and output below.")
+			fmt.Fprintln(w, "$runs << EOD")
+			fmt.Fprintln(w, "# run dry duration_ms")
+			for _, point := range plotPoints(results) {
+				dry := 0
+				if point.Kind == "dry" {
+					dry = 1
+				}
+				fmt.Fprintf(w, "%d %d %s\n", point.Run, dry, strconv.FormatFloat(point.DurationMS, 'f', 3, 64))
+			}
+			fmt.Fprintln(w, "EOD")
+			fmt.Fprintln(w, "# set terminal svg size 800,400")
+			fmt.Fprintln(w, "# set output 'boot-time.svg'")
+			fmt.Fprintln(w, "# set logscale y")
+			fmt.Fprintf(w, "set title %s\n", strconv.Quote(plotTitle(results)))
+			fmt.Fprintln(w, "set xlabel 'Run'")
+			fmt.Fprintln(w, "set ylabel 'Boot time (ms)'")
+			fmt.Fprintln(w, "set grid")
+			fmt.Fprintln(w, "set key outside")
+			fmt.Fprintln(w, "plot $runs using 1:($2 == 1 ? $3 : NaN) with points pointtype 6 title 'dry runs', \\")
+			_, err := fmt.Fprintln(w, "     $runs using 1:($2 == 0 ? $3 : NaN) with linespoints pointtype 7 title 'runs'")
+			return err
+		})
+	}), nil
+}
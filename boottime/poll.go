@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultPollMaxInterval bounds the interval between probe attempts grown by the backoff, when the
+// benchmark does not set a bound.
+const DefaultPollMaxInterval = time.Second
+
+func (b *Benchmark) checkPolling() error {
+	if b.PollInterval < 0 || b.PollMaxInterval < 0 {
+		return errors.New("the polling intervals must not be negative")
+	}
+	if b.PollBackoff != 0 && b.PollBackoff < 1 {
+		return errors.New("the polling backoff factor must be at least 1")
+	}
+	if b.PollBackoff > 1 && b.PollInterval == 0 {
+		return errors.New("the polling backoff needs a polling interval to grow from")
+	}
+	if b.PollMaxInterval > 0 && b.PollMaxInterval < b.PollInterval {
+		return errors.New("the maximum polling interval must not be shorter than the polling interval")
+	}
+	return nil
+}
+
+// probeInterval returns the minimum time between the starts of consecutive probe attempts at a
+// probe rate, unlimited when zero, and the polling interval.
+func (b *Benchmark) probeInterval(probeRate float64) time.Duration {
+	interval := b.PollInterval
+	if probeRate > 0 {
+		if rateInterval := time.Duration(float64(time.Second) / probeRate); rateInterval > interval {
+			interval = rateInterval
+		}
+	}
+	return interval
+}
+
+// backoff returns the interval following a failed attempt made after interval, grown by the
+// backoff factor up to the maximum polling interval.
+func (b *Benchmark) backoff(interval time.Duration) time.Duration {
+	if b.PollBackoff <= 1 {
+		return interval
+	}
+	max := b.PollMaxInterval
+	if max == 0 {
+		max = DefaultPollMaxInterval
+	}
+	if max < interval {
+		return interval // set by a probe rate
+	}
+	next := time.Duration(float64(interval) * b.PollBackoff)
+	if next > max {
+		return max
+	}
+	return next
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PortPhasePrefix prefixes the names of the phases marking when watched ports started accepting
+// connections, as in port:admin.
+const PortPhasePrefix = "port:"
+
+const (
+	portDialTimeout = 100 * time.Millisecond
+	portRetryPause  = time.Millisecond
+)
+
+// portWatcher records when ports start accepting connections, until it is stopped.
+type portWatcher struct {
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	offsets map[string]time.Duration
+}
+
+func watchPorts(ports map[string]string, start time.Time) *portWatcher {
+	w := &portWatcher{stop: make(chan struct{}), offsets: make(map[string]time.Duration)}
+	for name, address := range ports {
+		w.wg.Add(1)
+		go w.watch(name, address, start)
+	}
+	return w
+}
+
+func (w *portWatcher) watch(name, address string, start time.Time) {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+		conn, err := net.DialTimeout("tcp", address, portDialTimeout)
+		if err == nil {
+			offset := time.Since(start)
+			conn.Close()
+			w.mu.Lock()
+			w.offsets[name] = offset
+			w.mu.Unlock()
+			return
+		}
+		time.Sleep(portRetryPause)
+	}
+}
+
+// done stops watching and marks the phases of the ports that accepted connections, in order.
+func (w *portWatcher) done(run *Run) {
+	close(w.stop)
+	w.wg.Wait()
+	var phases []Phase
+	for name, offset := range w.offsets {
+		phases = append(phases, Phase{Name: PortPhasePrefix + name, Offset: offset})
+	}
+	sort.Slice(phases, func(i, j int) bool { return phases[i].Offset < phases[j].Offset })
+	for _, phase := range phases {
+		run.mark(phase.Name, phase.Offset)
+	}
+}
+
+// PortOrder returns the names of the watched ports of a run, in the order they started accepting
+// connections.
+func (r *Run) PortOrder() []string {
+	var names []string
+	for _, phase := range r.Phases {
+		if strings.HasPrefix(phase.Name, PortPhasePrefix) {
+			names = append(names, strings.TrimPrefix(phase.Name, PortPhasePrefix))
+		}
+	}
+	return names
+}
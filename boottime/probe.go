@@ -0,0 +1,431 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Probe checks whether a server is ready to serve.
+//
+// A benchmark calls Setup before spawning the process of each run, then Check until it succeeds,
+// and finally Teardown once the run is over, so that probes may keep state such as connections.
+type Probe interface {
+	Setup(ctx context.Context) error
+	// Check makes an attempt to reach the server, returning a nil error when it is ready.
+	Check(ctx context.Context) (ProbeResult, error)
+	Teardown() error
+}
+
+// ServerEnvironment is implemented by probes that the server must know about, such as the address
+// of a listener waiting for the server to call back.
+type ServerEnvironment interface {
+	// Placeholders returns values replacing placeholders, such as {callback}, in the command,
+	// arguments and environment of the server.
+	Placeholders() map[string]string
+	// Environment returns variables added to the environment of the server, as KEY=value.
+	Environment() []string
+}
+
+// OutputProbe is implemented by probes that watch the output of the server, whose lines they get
+// from the moment it is spawned.
+type OutputProbe interface {
+	Output(line OutputLine)
+}
+
+// ProbeResult holds what a probe observed during an attempt.
+type ProbeResult struct {
+	Latency time.Duration `json:"latency_ns"`                  // how long the attempt took, measured by the benchmark when left empty
+	Phases  []Phase       `json:"phases,omitempty"`            // probe-specific points, relative to the start of the attempt
+	Reused  bool          `json:"reused_connection,omitempty"` // whether the attempt reused a connection of a previous one
+	// Reached names the phases of the run that the attempt observed, even when it failed.
+	// The benchmark marks them at the end of the attempt, unless they have been reached before.
+	Reached []string `json:"reached,omitempty"`
+	// Generation identifies the generation of server processes that answered, when the mode can
+	// tell, so that hot upgrades can be detected.
+	Generation string `json:"generation,omitempty"`
+}
+
+// Attempt describes a probe attempt made during a run.
+type Attempt struct {
+	Dry    bool
+	Run    int           // index of the run among the runs of the same kind
+	Number int           // from 1
+	Offset time.Duration // relative to the moment the process was spawned
+	ProbeResult
+	Err error // nil when the attempt succeeded
+}
+
+// ProbeFactory creates the probe of a mode for a benchmark.
+type ProbeFactory func(b *Benchmark) (Probe, error)
+
+var (
+	probesMu sync.RWMutex
+	probes   = make(map[string]ProbeFactory)
+)
+
+// RegisterProbe makes a probe available as a mode, replacing any probe with the same mode name.
+func RegisterProbe(mode string, factory ProbeFactory) {
+	probesMu.Lock()
+	defer probesMu.Unlock()
+	probes[mode] = factory
+}
+
+// Modes returns the sorted names of the registered probe modes.
+func Modes() []string {
+	probesMu.RLock()
+	defer probesMu.RUnlock()
+	names := make([]string, 0, len(probes))
+	for name := range probes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkModes checks that the modes of the benchmark and of its milestones and events are registered.
+func (b *Benchmark) checkModes() error {
+	modes := []string{b.Mode}
+	for _, event := range append(append([]LifecycleEvent{}, b.Milestones...), b.Events...) {
+		if len(event.Mode) > 0 {
+			modes = append(modes, event.Mode)
+		}
+	}
+	for _, mode := range modes {
+		probesMu.RLock()
+		_, found := probes[mode]
+		probesMu.RUnlock()
+		if !found {
+			return fmt.Errorf("unknown mode: %s (available: %s)", mode, strings.Join(Modes(), ", "))
+		}
+	}
+	return nil
+}
+
+func (b *Benchmark) probe() (Probe, error) {
+	probesMu.RLock()
+	factory, found := probes[b.Mode]
+	probesMu.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("unknown mode: %s (available: %s)", b.Mode, strings.Join(Modes(), ", "))
+	}
+	return factory(b)
+}
+
+func init() {
+	RegisterProbe("http-get", newHTTPGetProbe)
+	RegisterProbe("tcp-connect", newTCPConnectProbe)
+}
+
+// HTTPOptions configures the http-get mode.
+//
+// Header values and the bearer token may reference secrets, see ResolveSecret.
+type HTTPOptions struct {
+	Timeout     Duration          `yaml:"timeout"`      // timeout of each request, none when zero, see RemoteProbeTimeout
+	Headers     map[string]string `yaml:"headers"`      // headers added to each request
+	BearerToken string            `yaml:"bearer_token"` // sent as an Authorization header when not empty
+	Connection  string            `yaml:"connection"`   // ReuseConnections or NewConnections, ReuseConnections when empty
+	// GenerationHeader is a response header identifying the generation of the server, such as a
+	// version, that reveals hot upgrades.
+	GenerationHeader string `yaml:"generation_header"`
+
+	// ExpectStatus lists the response statuses telling that the server is ready, as comma-separated
+	// codes and ranges such as 200-299,302, 200 when empty.
+	ExpectStatus string `yaml:"expect_status" json:",omitempty"`
+	// Redirects is FollowRedirects or KeepRedirects, FollowRedirects when empty.
+	Redirects string `yaml:"redirects" json:",omitempty"`
+
+	// TLS options of https targets. Insecure skips the verification of the server certificate, CACert
+	// is a PEM bundle of the authorities trusted instead of those of the system, ClientCert and
+	// ClientKey are the PEM files of a client certificate, and ServerName overrides the name sent with
+	// SNI and verified in the certificate, the host of the target when empty.
+	Insecure   bool   `yaml:"insecure" json:",omitempty"`
+	CACert     string `yaml:"ca_cert" json:",omitempty"`
+	ClientCert string `yaml:"client_cert" json:",omitempty"`
+	ClientKey  string `yaml:"client_key" json:",omitempty"`
+	ServerName string `yaml:"server_name" json:",omitempty"`
+}
+
+// Redirect handling policies of the http-get mode.
+const (
+	FollowRedirects = "follow" // follow redirects, the status of the final response telling whether the server is ready
+	KeepRedirects   = "keep"   // check the status of redirects themselves, which ExpectStatus may accept
+)
+
+// statusRange is an inclusive range of response statuses.
+type statusRange struct{ min, max int }
+
+// parseExpectedStatuses parses comma-separated statuses and ranges, as in 200-299,302.
+func parseExpectedStatuses(s string) ([]statusRange, error) {
+	if len(strings.TrimSpace(s)) == 0 {
+		return []statusRange{{200, 200}}, nil
+	}
+	var ranges []statusRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		bounds := strings.SplitN(part, "-", 2)
+		low, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		high := low
+		if err == nil && len(bounds) == 2 {
+			high, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+		}
+		if err != nil || low < 100 || high > 599 || low > high {
+			return nil, fmt.Errorf("invalid expected status, expected codes or ranges as in 200-299,302: %s", part)
+		}
+		ranges = append(ranges, statusRange{low, high})
+	}
+	return ranges, nil
+}
+
+func expectedStatus(ranges []statusRange, status int) bool {
+	for _, r := range ranges {
+		if status >= r.min && status <= r.max {
+			return true
+		}
+	}
+	return false
+}
+
+// tlsConfig returns the TLS configuration of the options, nil when they are all empty.
+func (o HTTPOptions) tlsConfig() (*tls.Config, error) {
+	if !o.Insecure && len(o.CACert) == 0 && len(o.ClientCert) == 0 && len(o.ClientKey) == 0 && len(o.ServerName) == 0 {
+		return nil, nil
+	}
+	config := &tls.Config{InsecureSkipVerify: o.Insecure, ServerName: o.ServerName}
+	if len(o.CACert) > 0 {
+		pem, err := ioutil.ReadFile(o.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the CA bundle: %v", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in the CA bundle %s", o.CACert)
+		}
+	}
+	if len(o.ClientCert) > 0 || len(o.ClientKey) > 0 {
+		if len(o.ClientCert) == 0 || len(o.ClientKey) == 0 {
+			return nil, fmt.Errorf("a client certificate requires both a certificate and a key")
+		}
+		cert, err := tls.LoadX509KeyPair(o.ClientCert, o.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// Connection handling policies of the http-get mode, once a server accepted a first connection.
+const (
+	ReuseConnections = "reuse" // keep connections alive across the attempts of a run
+	NewConnections   = "new"   // open a new connection for each attempt, including its TLS handshake
+)
+
+// TCPOptions configures the tcp-connect mode.
+type TCPOptions struct {
+	Timeout Duration `yaml:"timeout"` // timeout of each connection attempt, none when zero, see RemoteProbeTimeout
+}
+
+// sensitiveHeaders are the headers whose values are always treated as secrets.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "X-Auth-Token"}
+
+// nopLifecycle provides empty Setup and Teardown methods to stateless probes.
+type nopLifecycle struct{}
+
+func (nopLifecycle) Setup(ctx context.Context) error { return nil }
+func (nopLifecycle) Teardown() error                 { return nil }
+
+type tcpConnectProbe struct {
+	nopLifecycle
+	target string
+	dialer net.Dialer
+}
+
+func newTCPConnectProbe(b *Benchmark) (Probe, error) {
+	return &tcpConnectProbe{target: b.Target, dialer: net.Dialer{Timeout: b.probeTimeout(time.Duration(b.TCP.Timeout))}}, nil
+}
+
+func (p *tcpConnectProbe) Check(ctx context.Context) (ProbeResult, error) {
+	conn, err := p.dialer.DialContext(ctx, "tcp", p.target)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	return ProbeResult{}, conn.Close()
+}
+
+type httpGetProbe struct {
+	target    string
+	header    http.Header
+	timeout   time.Duration
+	keepAlive bool
+	tls       *tls.Config // nil for the default configuration
+	expect    []statusRange
+	redirects bool
+	client    *http.Client
+
+	generationHeader string
+}
+
+func newHTTPGetProbe(b *Benchmark) (Probe, error) {
+	var keepAlive bool
+	switch b.HTTP.Connection {
+	case "", ReuseConnections:
+		keepAlive = true
+	case NewConnections:
+		keepAlive = false
+	default:
+		return nil, fmt.Errorf("unknown connection policy: %s (expected %s or %s)", b.HTTP.Connection, ReuseConnections, NewConnections)
+	}
+	var redirects bool
+	switch b.HTTP.Redirects {
+	case "", FollowRedirects:
+		redirects = true
+	case KeepRedirects:
+		redirects = false
+	default:
+		return nil, fmt.Errorf("unknown redirect policy: %s (expected %s or %s)", b.HTTP.Redirects, FollowRedirects, KeepRedirects)
+	}
+	expect, err := parseExpectedStatuses(b.HTTP.ExpectStatus)
+	if err != nil {
+		return nil, err
+	}
+	if target, err := url.Parse(b.Target); err == nil && target.User != nil {
+		if password, set := target.User.Password(); set {
+			RegisterSecret(password)
+		}
+	}
+	header := make(http.Header)
+	for name, value := range b.HTTP.Headers {
+		resolved, err := ResolveSecret(value)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %v", name, err)
+		}
+		for _, sensitive := range sensitiveHeaders {
+			if strings.EqualFold(name, sensitive) {
+				RegisterSecret(resolved)
+			}
+		}
+		header.Set(name, resolved)
+	}
+	if len(b.HTTP.BearerToken) > 0 {
+		token, err := ResolveSecret(b.HTTP.BearerToken)
+		if err != nil {
+			return nil, fmt.Errorf("bearer token: %v", err)
+		}
+		RegisterSecret(token)
+		header.Set("Authorization", "Bearer "+token)
+	}
+	tlsConfig, err := b.HTTP.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &httpGetProbe{target: b.Target, header: header, timeout: b.probeTimeout(time.Duration(b.HTTP.Timeout)), keepAlive: keepAlive, tls: tlsConfig, expect: expect, redirects: redirects, generationHeader: b.HTTP.GenerationHeader}, nil
+}
+
+// Setup creates a client with its own connection pool, so that no connection outlives a run.
+func (p *httpGetProbe) Setup(ctx context.Context) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = !p.keepAlive
+	if p.tls != nil {
+		transport.TLSClientConfig = p.tls.Clone()
+	}
+	p.client = &http.Client{Timeout: p.timeout, Transport: transport}
+	if !p.redirects {
+		p.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return nil
+}
+
+func (p *httpGetProbe) Teardown() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
+
+// HTTP probe phases, relative to the start of an attempt.
+const (
+	ConnectedPhase    = "connected"     // the TCP connection has been established
+	TLSHandshakePhase = "tls-handshake" // the TLS handshake of an https target is complete
+	FirstBytePhase    = "first-byte"    // the first byte of the response has been received
+	BodyReadPhase     = "body-read"     // the whole response body has been consumed
+)
+
+func (p *httpGetProbe) Check(ctx context.Context) (ProbeResult, error) {
+	var result ProbeResult
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				result.Phases = append(result.Phases, Phase{Name: ConnectedPhase, Offset: time.Since(start)})
+			}
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err == nil {
+				result.Phases = append(result.Phases, Phase{Name: TLSHandshakePhase, Offset: time.Since(start)})
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			result.Reused = info.Reused
+		},
+		GotFirstResponseByte: func() {
+			result.Phases = append(result.Phases, Phase{Name: FirstBytePhase, Offset: time.Since(start)})
+		},
+	}
+	req, err := http.NewRequest("GET", p.target, nil)
+	if err != nil {
+		return result, err
+	}
+	for name, values := range p.header {
+		req.Header[name] = values
+	}
+	resp, err := p.client.Do(req.WithContext(httptrace.WithClientTrace(ctx, trace)))
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	if !expectedStatus(p.expect, resp.StatusCode) {
+		return result, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	if len(p.generationHeader) > 0 {
+		result.Generation = resp.Header.Get(p.generationHeader)
+	}
+	if _, err := ioutil.ReadAll(resp.Body); err != nil {
+		return result, err
+	}
+	result.Phases = append(result.Phases, Phase{Name: BodyReadPhase, Offset: time.Since(start)})
+	return result, nil
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// readProcStats reads /proc/<pid>/stat for every process, keeping the fields that follow the
+// command name, from the state, field 3.
+func readProcStats() (map[int][]string, error) {
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	stats := map[int][]string{}
+	for _, entry := range entries {
+		id, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", id))
+		if err != nil {
+			continue // the process exited
+		}
+		// The command name is in parentheses and may contain spaces.
+		end := strings.LastIndexByte(string(content), ')')
+		if end < 0 {
+			continue
+		}
+		if fields := strings.Fields(string(content[end+1:])); len(fields) >= 15 {
+			stats[id] = fields
+		}
+	}
+	return stats, nil
+}
+
+// processTree returns pid and its live descendants from the stats of every process, or an error
+// when pid is not among them.
+func processTree(stats map[int][]string, pid int) ([]int, error) {
+	if _, found := stats[pid]; !found {
+		return nil, fmt.Errorf("no such process: %d", pid)
+	}
+	children := map[int][]int{}
+	for id, fields := range stats {
+		if ppid, err := strconv.Atoi(fields[1]); err == nil {
+			children[ppid] = append(children[ppid], id)
+		}
+	}
+	tree := []int{pid}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i]]...)
+	}
+	return tree, nil
+}
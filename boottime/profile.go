@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Profile presets the settings of benchmarks for a kind of machine, encoding good practices.
+type Profile struct {
+	Description string
+	DryRuns     int
+	Runs        int
+	Pause       time.Duration
+	Settle      bool
+	// MinRuns is the number of measured runs under which results are not conclusive.
+	MinRuns int
+	// Strict stops benchmarks with too few runs or that look misconfigured, rather than warning.
+	Strict bool
+}
+
+// settleSupported tells whether settle detection works on this platform.
+var settleSupported = runtime.GOOS == "linux"
+
+// Profiles are the available profiles, by name.
+var Profiles = map[string]Profile{
+	"ci": {
+		Description: "shared CI runners: short pauses and a moderate number of runs",
+		DryRuns:     2,
+		Runs:        10,
+		Pause:       2 * time.Second,
+		MinRuns:     5,
+	},
+	"laptop": {
+		Description: "laptops, which throttle when hot: long pauses to cool down and settle",
+		DryRuns:     3,
+		Runs:        20,
+		Pause:       15 * time.Second,
+		Settle:      settleSupported,
+		MinRuns:     10,
+	},
+	"rig": {
+		Description: "dedicated machines, for publishable results: many runs and strict checks",
+		DryRuns:     5,
+		Runs:        50,
+		Pause:       10 * time.Second,
+		Settle:      settleSupported,
+		MinRuns:     30,
+		Strict:      true,
+	},
+}
+
+// ProfileNames returns the sorted names of the profiles.
+func ProfileNames() []string {
+	names := make([]string, 0, len(Profiles))
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupProfile returns the profile with the given name.
+func LookupProfile(name string) (Profile, error) {
+	profile, found := Profiles[name]
+	if !found {
+		return Profile{}, fmt.Errorf("unknown profile: %s (available: %s)", name, strings.Join(ProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// settings returns the settings of configuration files that the profile presets.
+func (p Profile) settings() map[interface{}]interface{} {
+	return map[interface{}]interface{}{
+		"dry_runs": p.DryRuns,
+		"runs":     p.Runs,
+		"pause":    p.Pause.String(),
+		"settle":   p.Settle,
+	}
+}
+
+// checkProfile enforces the number of runs of the profile of a benchmark, if any.
+func (b *Benchmark) checkProfile() error {
+	if len(b.Profile) == 0 {
+		return nil
+	}
+	profile, err := LookupProfile(b.Profile)
+	if err != nil {
+		return err
+	}
+	if b.Runs < profile.MinRuns {
+		if profile.Strict {
+			return fmt.Errorf("the %s profile requires at least %d runs, not %d", b.Profile, profile.MinRuns, b.Runs)
+		}
+		b.Logger.Warn("too few runs for conclusive results", "profile", b.Profile, "runs", b.Runs, "min_runs", profile.MinRuns)
+	}
+	return nil
+}
+
+// strict tells whether the profile of the benchmark is strict.
+func (b *Benchmark) strict() bool {
+	return Profiles[b.Profile].Strict
+}
@@ -0,0 +1,282 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PromOptions configures the prom-metric mode, where the target is the URL of a Prometheus
+// metrics endpoint and the server is ready once a metric satisfies a condition.
+type PromOptions struct {
+	// Condition compares a metric with a value, as in app_ready == 1 or
+	// http_server_started{port="8080"} >= 1. The operators are ==, !=, <, <=, > and >=.
+	// Labels select the samples having at least these label values, and the condition holds
+	// when any selected sample satisfies it.
+	Condition string   `yaml:"condition"`
+	Timeout   Duration `yaml:"timeout"` // timeout of each scrape, none when zero, see RemoteProbeTimeout
+	// GenerationMetric is a metric whose value identifies the generation of the server, such as a
+	// start time, that reveals hot upgrades.
+	GenerationMetric string `yaml:"generation_metric"`
+}
+
+func init() {
+	RegisterProbe("prom-metric", newPromMetricProbe)
+}
+
+type promCondition struct {
+	metric   string
+	labels   map[string]string
+	operator string
+	value    float64
+}
+
+var promConditionPattern = regexp.MustCompile(`^\s*([a-zA-Z_:][a-zA-Z0-9_:]*)\s*(\{[^}]*\})?\s*(==|!=|<=|>=|<|>)\s*(\S+)\s*$`)
+
+func parsePromCondition(s string) (*promCondition, error) {
+	parts := promConditionPattern.FindStringSubmatch(s)
+	if parts == nil {
+		return nil, fmt.Errorf("invalid metric condition, expected as in 'metric{label=\"value\"} >= 1': %s", s)
+	}
+	value, err := strconv.ParseFloat(parts[4], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metric condition value: %s", parts[4])
+	}
+	condition := &promCondition{metric: parts[1], operator: parts[3], value: value, labels: map[string]string{}}
+	if len(parts[2]) > 0 {
+		if condition.labels, err = parsePromLabels(parts[2][1 : len(parts[2])-1]); err != nil {
+			return nil, err
+		}
+	}
+	return condition, nil
+}
+
+func (c *promCondition) holds(sample promSample) bool {
+	if sample.name != c.metric {
+		return false
+	}
+	for key, value := range c.labels {
+		if sample.labels[key] != value {
+			return false
+		}
+	}
+	switch c.operator {
+	case "==":
+		return sample.value == c.value
+	case "!=":
+		return sample.value != c.value
+	case "<":
+		return sample.value < c.value
+	case "<=":
+		return sample.value <= c.value
+	case ">":
+		return sample.value > c.value
+	case ">=":
+		return sample.value >= c.value
+	}
+	return false
+}
+
+type promSample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// parsePromSample parses a sample line of the Prometheus text exposition format.
+func parsePromSample(line string) (promSample, error) {
+	var sample promSample
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return sample, fmt.Errorf("invalid sample: %s", line)
+	}
+	sample.name, line = line[:end], line[end:]
+	sample.labels = map[string]string{}
+	if strings.HasPrefix(line, "{") {
+		closing := closingBrace(line)
+		if closing < 0 {
+			return sample, fmt.Errorf("invalid sample labels: %s", line)
+		}
+		labels, err := parsePromLabels(line[1:closing])
+		if err != nil {
+			return sample, err
+		}
+		sample.labels, line = labels, line[closing+1:]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return sample, fmt.Errorf("sample without value: %s", sample.name)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return sample, fmt.Errorf("invalid sample value of %s: %s", sample.name, fields[0])
+	}
+	sample.value = value
+	return sample, nil
+}
+
+// closingBrace returns the index of the brace closing the labels that s starts with,
+// ignoring braces in quoted label values, or -1.
+func closingBrace(s string) int {
+	quoted, escaped := false, false
+	for i, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == '}' && !quoted:
+			return i
+		}
+	}
+	return -1
+}
+
+// parsePromLabels parses label pairs, as in a="b",c="d".
+func parsePromLabels(s string) (map[string]string, error) {
+	labels := map[string]string{}
+	s = strings.TrimSpace(s)
+	for len(s) > 0 {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || len(s) < eq+2 || s[eq+1] != '"' {
+			return nil, fmt.Errorf("invalid labels: %s", s)
+		}
+		key := strings.TrimSpace(s[:eq])
+		rest := s[eq+2:]
+		var value strings.Builder
+		i, closed := 0, false
+		for ; i < len(rest); i++ {
+			c := rest[i]
+			if c == '\\' && i+1 < len(rest) {
+				i++
+				switch rest[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(rest[i])
+				}
+				continue
+			}
+			if c == '"' {
+				closed = true
+				break
+			}
+			value.WriteByte(c)
+		}
+		if !closed {
+			return nil, fmt.Errorf("unterminated label value: %s", s)
+		}
+		labels[key] = value.String()
+		s = strings.TrimLeft(strings.TrimSpace(rest[i+1:]), ",")
+		s = strings.TrimSpace(s)
+	}
+	return labels, nil
+}
+
+type promMetricProbe struct {
+	nopLifecycle
+	target     string
+	condition  *promCondition
+	generation string
+	client     *http.Client
+}
+
+func newPromMetricProbe(b *Benchmark) (Probe, error) {
+	if len(b.Prom.Condition) == 0 {
+		return nil, fmt.Errorf("the prom-metric mode needs a metric condition")
+	}
+	condition, err := parsePromCondition(b.Prom.Condition)
+	if err != nil {
+		return nil, err
+	}
+	return &promMetricProbe{target: b.Target, condition: condition, generation: b.Prom.GenerationMetric, client: &http.Client{Timeout: b.probeTimeout(time.Duration(b.Prom.Timeout))}}, nil
+}
+
+func (p *promMetricProbe) Check(ctx context.Context) (ProbeResult, error) {
+	req, err := http.NewRequest("GET", p.target, nil)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	req.Header.Set("Accept", "text/plain")
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return ProbeResult{}, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	var result ProbeResult
+	err = p.scan(resp.Body, &result)
+	return result, err
+}
+
+// scan looks for a sample satisfying the condition, and for the generation metric if any.
+func (p *promMetricProbe) scan(r io.Reader, result *ProbeResult) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	found, holds := false, false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, p.condition.metric) && (len(p.generation) == 0 || !strings.HasPrefix(line, p.generation)) {
+			continue
+		}
+		sample, err := parsePromSample(line)
+		if err != nil {
+			continue
+		}
+		if sample.name == p.generation && len(result.Generation) == 0 {
+			result.Generation = strconv.FormatFloat(sample.value, 'g', -1, 64)
+		}
+		if sample.name == p.condition.metric {
+			found = true
+			holds = holds || p.condition.holds(sample)
+		}
+		if holds && (len(p.generation) == 0 || len(result.Generation) > 0) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if holds {
+		return nil
+	}
+	if !found {
+		return fmt.Errorf("metric not found: %s", p.condition.metric)
+	}
+	return fmt.Errorf("metric condition not met: %s", p.condition.metric)
+}
@@ -0,0 +1,276 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Types of the events of a session archive.
+const (
+	SessionEvent = "session" // the benchmark started, Results holds its settings but no run
+	AttemptEvent = "attempt" // a probe attempt was made
+	OutputEvent  = "output"  // the server wrote a line
+	RunEvent     = "run"     // a run completed
+	ResultsEvent = "results" // the benchmark completed, Results holds everything
+)
+
+// Event is an entry of a session archive.
+type Event struct {
+	Time    time.Time        `json:"time"`
+	Type    string           `json:"type"`
+	Attempt *RecordedAttempt `json:"attempt,omitempty"`
+	Output  *RecordedOutput  `json:"output,omitempty"`
+	Run     *Run             `json:"run,omitempty"`
+	Results *Results         `json:"results,omitempty"`
+}
+
+// RecordedAttempt is the archived form of an Attempt.
+type RecordedAttempt struct {
+	Dry    bool          `json:"dry"`
+	Run    int           `json:"run"`
+	Number int           `json:"number"`
+	Offset time.Duration `json:"offset_ns"`
+	ProbeResult
+	Error string `json:"error,omitempty"`
+}
+
+// RecordedOutput is the archived form of an OutputLine.
+type RecordedOutput struct {
+	Dry    bool          `json:"dry"`
+	Run    int           `json:"run"`
+	Stream string        `json:"stream"`
+	Text   string        `json:"text"`
+	Offset time.Duration `json:"offset_ns"` // relative to the moment the process was spawned
+}
+
+// Recorder writes every event of a benchmark to an archive, a gzip-compressed file of JSON lines.
+// Registered secrets are redacted from the archive.
+type Recorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	gz      *gzip.Writer
+	session *Results // settings not recorded yet
+	err     error
+}
+
+// NewRecorder creates an archive at path.
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	return &Recorder{file: file, gz: gz}, nil
+}
+
+func (r *Recorder) record(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.session != nil {
+		session := r.session
+		r.session = nil
+		r.write(Event{Type: SessionEvent, Results: session})
+	}
+	if event.Type != SessionEvent {
+		r.write(event)
+	}
+}
+
+func (r *Recorder) write(event Event) {
+	if r.err != nil {
+		return
+	}
+	event.Time = time.Now()
+	data, err := json.Marshal(RedactValue(event))
+	if err != nil {
+		r.err = err
+		return
+	}
+	_, r.err = r.gz.Write(append(data, '\n'))
+}
+
+// Attach records the settings of b, then hooks into b to record its events.
+// Callbacks that were already set on b are still called.
+func (r *Recorder) Attach(b *Benchmark) {
+	// The settings are recorded along with the first event, once the secrets they may reference
+	// have been resolved by the benchmark and can be redacted.
+	r.mu.Lock()
+	r.session = b.newResults()
+	r.mu.Unlock()
+
+	onRun, onAttempt, onOutput := b.OnRun, b.OnAttempt, b.OnOutput
+	b.OnRun = func(run Run) {
+		r.record(Event{Type: RunEvent, Run: &run})
+		if onRun != nil {
+			onRun(run)
+		}
+	}
+	b.OnAttempt = func(attempt Attempt) {
+		recorded := &RecordedAttempt{
+			Dry:         attempt.Dry,
+			Run:         attempt.Run,
+			Number:      attempt.Number,
+			Offset:      attempt.Offset,
+			ProbeResult: attempt.ProbeResult,
+		}
+		if attempt.Err != nil {
+			recorded.Error = attempt.Err.Error()
+		}
+		r.record(Event{Type: AttemptEvent, Attempt: recorded})
+		if onAttempt != nil {
+			onAttempt(attempt)
+		}
+	}
+	b.OnOutput = func(line OutputLine) {
+		r.record(Event{Type: OutputEvent, Output: &RecordedOutput{Dry: line.Dry, Run: line.Run, Stream: line.Stream, Text: line.Text, Offset: line.Offset}})
+		if onOutput != nil {
+			onOutput(line)
+		}
+	}
+}
+
+// Close records the final results when not nil, and closes the archive.
+func (r *Recorder) Close(results *Results) error {
+	if results != nil {
+		r.record(Event{Type: ResultsEvent, Results: results})
+	} else {
+		r.record(Event{Type: SessionEvent})
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.gz.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// ReadEvents calls handle with each event of the archive at path, in order.
+func ReadEvents(path string, handle func(event Event) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(gz)
+	for {
+		var event Event
+		if err := decoder.Decode(&event); err == io.EOF {
+			return nil
+		} else if err == io.ErrUnexpectedEOF {
+			// The session was interrupted before the archive was closed, keep what was written.
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := handle(event); err != nil {
+			return err
+		}
+	}
+}
+
+// LogMarker turns the first line of the server output of each run that matches Pattern into a
+// phase named Phase, see Replay.
+type LogMarker struct {
+	Phase   string
+	Pattern *regexp.Regexp
+}
+
+// Replay returns the results recorded in the archive at path. When the recorded session did not
+// complete, the results are rebuilt from the runs that were recorded.
+// The markers add phases to the runs from their recorded output, there is no need to run the
+// benchmark again for that.
+func Replay(path string, markers ...LogMarker) (*Results, error) {
+	var session, final *Results
+	var runs []Run
+	var output []*RecordedOutput
+	err := ReadEvents(path, func(event Event) error {
+		switch event.Type {
+		case SessionEvent:
+			session = event.Results
+		case RunEvent:
+			if event.Run != nil {
+				runs = append(runs, *event.Run)
+			}
+		case OutputEvent:
+			if event.Output != nil && len(markers) > 0 {
+				output = append(output, event.Output)
+			}
+		case ResultsEvent:
+			final = event.Results
+		}
+		return nil
+	})
+	results := final
+	if results == nil {
+		if session == nil {
+			if err == nil {
+				err = errors.New("not a session archive: " + path)
+			}
+			return nil, err
+		}
+		results = session
+		results.Runs = runs
+	}
+	markOutputPhases(results, output, markers)
+	return results, err
+}
+
+// markOutputPhases adds the phases of markers to the runs of results, from their output.
+func markOutputPhases(results *Results, output []*RecordedOutput, markers []LogMarker) {
+	if len(output) == 0 {
+		return
+	}
+	for i := range results.Runs {
+		run := &results.Runs[i]
+		marked := false
+		for _, marker := range markers {
+			for _, line := range output {
+				if line.Dry == run.Dry && line.Run == run.Index && marker.Pattern.MatchString(line.Text) {
+					run.mark(marker.Phase, line.Offset)
+					marked = true
+					break
+				}
+			}
+		}
+		if marked {
+			sort.SliceStable(run.Phases, func(i, j int) bool { return run.Phases[i].Offset < run.Phases[j].Offset })
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Crash recovery phases, relative to the moment the process was first spawned.
+const (
+	CrashedPhase   = "crashed"   // the ready server has been killed
+	RestartedPhase = "restarted" // the server has been started again
+	RecoveredPhase = "recovered" // the restarted server is ready
+)
+
+// Recovery is the boot of a server started again after it was killed once ready, as after a crash,
+// which includes replaying journals or write-ahead logs for databases.
+type Recovery struct {
+	Duration  time.Duration `json:"duration_ns"` // from restarting the server to readiness
+	Attempts  int           `json:"probe_attempts"`
+	Resources Resources     `json:"resources"`
+	Exit      *ExitStatus   `json:"exit,omitempty"`
+}
+
+func (b *Benchmark) checkCrashRecovery() error {
+	if !b.CrashRecovery {
+		return nil
+	}
+	if remoteLaunchers[b.Launcher] {
+		return fmt.Errorf("the crash recovery of servers of the %s launcher cannot be measured, as they run elsewhere", b.Launcher)
+	}
+	if len(b.UpgradeSignal) > 0 {
+		return fmt.Errorf("the crash recovery and the upgrade of the server cannot be measured in the same runs")
+	}
+	return nil
+}
+
+// recover kills the ready server and starts it again, measuring until it is ready again, and
+// returns the launcher of the restarted server.
+func (s *session) recover(ctx context.Context, spec runSpec, run *Run, launcher Launcher, start time.Time, interval time.Duration, stdout, stderr io.Writer) (Launcher, error) {
+	run.mark(CrashedPhase, time.Since(start))
+	if err := launcher.Signal(os.Kill); err != nil {
+		s.Logger.Debug("unable to kill the process", "error", err)
+	}
+	run.recordTermination(s.wait(launcher))
+	if err := launcher.Cleanup(); err != nil {
+		s.Logger.Warn("launcher cleanup failed", "error", err)
+	}
+	if err := s.probe.Teardown(); err != nil {
+		s.Logger.Warn("probe teardown failed", "error", err)
+	}
+	if err := s.probe.Setup(ctx); err != nil {
+		return launcher, fmt.Errorf("probe setup failed: %v", err)
+	}
+	restarted, err := s.launcherFactory(s.server)
+	if err != nil {
+		return launcher, err
+	}
+	if pinnable, ok := restarted.(cpuPinnable); ok && len(s.serverCPUs) > 0 {
+		pinnable.pinCPUs(s.serverCPUs)
+	}
+	restartedAt := time.Now()
+	if err := restarted.Start(ctx, stdout, stderr); err != nil {
+		return restarted, categorize(SpawnErrorCategory, fmt.Errorf("unable to restart the server: %v", err))
+	}
+	awaitCtx, stopAwait := s.watchServer(ctx, &restarted)
+	defer stopAwait(nil)
+	run.mark(RestartedPhase, restartedAt.Sub(start))
+	if auto, ok := s.probe.(*autoTargetProbe); ok {
+		auto.attach(restarted.Pid())
+	}
+	attempts, resolution := run.Attempts, run.ProbeResolution
+	_, readyAt, err := s.await(awaitCtx, spec, run, start, interval, nil)
+	run.ProbeResolution = resolution
+	run.Recovery = &Recovery{Attempts: run.Attempts - attempts}
+	var early *exitError
+	if err != nil && errors.As(context.Cause(awaitCtx), &early) {
+		err = categorize(CrashCategory, early)
+	}
+	if err != nil {
+		return restarted, err
+	}
+	run.Recovery.Duration = readyAt.Sub(restartedAt)
+	run.mark(RecoveredPhase, readyAt.Sub(start))
+	s.Logger.Debug("server recovered", "duration", run.Recovery.Duration, "attempts", run.Recovery.Attempts)
+	return restarted, nil
+}
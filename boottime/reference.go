@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReferenceDataset holds boot times published elsewhere, such as framework benchmarks, to see how
+// results stack up against them, as in:
+//
+//	{
+//	  "name": "Framework startup 2026",
+//	  "source": "https://example.com/startup-benchmarks",
+//	  "environment": {"os": "linux", "arch": "amd64", "cpus": 4, "cpu_model": "Xeon E5-2686 v4"},
+//	  "mode": "http-get",
+//	  "entries": [
+//	    {"name": "spring-boot", "median_ns": 1850000000},
+//	    {"name": "quarkus", "durations_ns": [910000000, 930000000, 905000000]}
+//	  ]
+//	}
+type ReferenceDataset struct {
+	Name        string               `json:"name"`
+	Source      string               `json:"source,omitempty"` // where the numbers come from
+	Environment ReferenceEnvironment `json:"environment"`
+	Mode        string               `json:"mode,omitempty"` // how readiness was detected, as a mode name when possible
+	Entries     []ReferenceEntry     `json:"entries"`
+}
+
+// ReferenceEnvironment describes where reference boot times were measured, fields are empty when unknown.
+type ReferenceEnvironment struct {
+	OS       string `json:"os,omitempty"`
+	Arch     string `json:"arch,omitempty"`
+	CPUs     int    `json:"cpus,omitempty"`
+	CPUModel string `json:"cpu_model,omitempty"`
+	Notes    string `json:"notes,omitempty"`
+}
+
+// ReferenceEntry is a reference boot time, given by samples or by a median.
+type ReferenceEntry struct {
+	Name      string          `json:"name"`
+	Mode      string          `json:"mode,omitempty"` // overrides the mode of the dataset
+	Durations []time.Duration `json:"durations_ns,omitempty"`
+	Median    time.Duration   `json:"median_ns,omitempty"` // used when there are no samples
+}
+
+// MedianDuration returns the median of the samples of the entry, or its median when it has none.
+func (e ReferenceEntry) MedianDuration() time.Duration {
+	if len(e.Durations) > 0 {
+		return Median(e.Durations)
+	}
+	return e.Median
+}
+
+// LoadReferenceDataset reads a reference dataset from a file or from an http(s) URL.
+func LoadReferenceDataset(location string) (*ReferenceDataset, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		data, err = fetch(location)
+	} else {
+		data, err = ioutil.ReadFile(location)
+	}
+	if err != nil {
+		return nil, err
+	}
+	dataset := &ReferenceDataset{}
+	if err := json.Unmarshal(data, dataset); err != nil {
+		return nil, fmt.Errorf("invalid reference dataset %s: %v", location, err)
+	}
+	if len(dataset.Entries) == 0 {
+		return nil, fmt.Errorf("the reference dataset %s has no entry", location)
+	}
+	for _, entry := range dataset.Entries {
+		if entry.MedianDuration() <= 0 {
+			return nil, fmt.Errorf("the entry %q of the reference dataset %s has no boot time", entry.Name, location)
+		}
+	}
+	return dataset, nil
+}
+
+func fetch(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s replied with status %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Mismatches lists the differences between the environment of the dataset and how results were
+// measured, which make comparisons less meaningful.
+func (d *ReferenceDataset) Mismatches(results *Results) []string {
+	var mismatches []string
+	env := d.Environment
+	if env == (ReferenceEnvironment{}) {
+		mismatches = append(mismatches, "the reference dataset does not describe its environment")
+	}
+	if results.Host == nil {
+		mismatches = append(mismatches, "the results do not describe their host")
+	} else {
+		host := results.Host
+		if len(env.OS) > 0 && env.OS != host.OS {
+			mismatches = append(mismatches, fmt.Sprintf("operating system: %s for the reference, %s for the results", env.OS, host.OS))
+		}
+		if len(env.Arch) > 0 && env.Arch != host.Arch {
+			mismatches = append(mismatches, fmt.Sprintf("architecture: %s for the reference, %s for the results", env.Arch, host.Arch))
+		}
+		if env.CPUs > 0 && env.CPUs != host.CPUs {
+			mismatches = append(mismatches, fmt.Sprintf("CPUs: %d for the reference, %d for the results", env.CPUs, host.CPUs))
+		}
+	}
+	modes := map[string]bool{}
+	for _, entry := range d.Entries {
+		mode := entry.Mode
+		if len(mode) == 0 {
+			mode = d.Mode
+		}
+		if len(mode) == 0 {
+			modes["unknown"] = true
+		} else if mode != results.Mode {
+			modes[mode] = true
+		}
+	}
+	if modes["unknown"] {
+		mismatches = append(mismatches, "the reference dataset does not tell how readiness was detected")
+		delete(modes, "unknown")
+	}
+	if len(modes) > 0 {
+		names := make([]string, 0, len(modes))
+		for mode := range modes {
+			names = append(names, mode)
+		}
+		sort.Strings(names)
+		mismatches = append(mismatches, fmt.Sprintf("readiness: detected with %s for the reference, %s for the results", strings.Join(names, ", "), results.Mode))
+	}
+	return mismatches
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterLauncher("crac", newCRaCLauncher)
+	RegisterLauncher("criu", newCRIULauncher)
+}
+
+// newCRaCLauncher restores a JVM from a CRaC checkpoint, where the command is the java executable
+// and the arguments are passed after -XX:CRaCRestoreFrom.
+func newCRaCLauncher(b *Benchmark) (Launcher, error) {
+	if len(b.Checkpoint) == 0 {
+		return nil, errors.New("the crac launcher needs a checkpoint directory")
+	}
+	args := append([]string{"-XX:CRaCRestoreFrom=" + b.Checkpoint}, b.Args...)
+	return &processLauncher{name: b.Command, args: args, env: b.Env}, nil
+}
+
+// criuLauncher restores a process tree with criu, where the command is the criu executable and the
+// arguments are extra options of criu restore.
+//
+// criu stays the parent of the restored tree, so signals go to the restored process, whose
+// identifier criu writes to a file once the restore is done.
+type criuLauncher struct {
+	*processLauncher
+	dir     string
+	pidFile string
+}
+
+func newCRIULauncher(b *Benchmark) (Launcher, error) {
+	if len(b.Checkpoint) == 0 {
+		return nil, errors.New("the criu launcher needs a checkpoint directory")
+	}
+	dir, err := ioutil.TempDir("", "time-to-boot-server-criu")
+	if err != nil {
+		return nil, err
+	}
+	pidFile := filepath.Join(dir, "restored.pid")
+	args := append([]string{"restore", "--images-dir", b.Checkpoint, "--shell-job", "--pidfile", pidFile}, b.Args...)
+	return &criuLauncher{processLauncher: &processLauncher{name: b.Command, args: args, env: b.Env}, dir: dir, pidFile: pidFile}, nil
+}
+
+// restoredPid returns the identifier of the restored process, or 0 while it is not restored.
+func (l *criuLauncher) restoredPid() int {
+	content, err := ioutil.ReadFile(l.pidFile)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+func (l *criuLauncher) Pid() int {
+	if pid := l.restoredPid(); pid > 0 {
+		return pid
+	}
+	return l.processLauncher.Pid()
+}
+
+func (l *criuLauncher) Signal(sig os.Signal) error {
+	if pid := l.restoredPid(); pid > 0 {
+		if process, err := os.FindProcess(pid); err == nil {
+			process.Signal(sig)
+		}
+	}
+	return l.processLauncher.Signal(sig)
+}
+
+func (l *criuLauncher) Cleanup() error {
+	return os.RemoveAll(l.dir)
+}
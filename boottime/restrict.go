@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RuntimeDefaultSeccomp is the seccomp profile of Benchmark.Seccomp that approximates the default
+// profile of container runtimes, RuntimeDefault on Kubernetes: system calls that it refuses to
+// containers without extra capabilities fail with EPERM, io_uring included.
+const RuntimeDefaultSeccomp = "runtime-default"
+
+// Capability sets of Benchmark.Capabilities, besides lists of names.
+const (
+	NoCapabilities      = "none"    // as with drop: [ALL] on Kubernetes
+	DefaultCapabilities = "default" // the capabilities that container runtimes grant by default
+)
+
+// capabilityNames are the names of the Linux capabilities, by number.
+var capabilityNames = []string{
+	"CHOWN", "DAC_OVERRIDE", "DAC_READ_SEARCH", "FOWNER", "FSETID", "KILL", "SETGID", "SETUID",
+	"SETPCAP", "LINUX_IMMUTABLE", "NET_BIND_SERVICE", "NET_BROADCAST", "NET_ADMIN", "NET_RAW",
+	"IPC_LOCK", "IPC_OWNER", "SYS_MODULE", "SYS_RAWIO", "SYS_CHROOT", "SYS_PTRACE", "SYS_PACCT",
+	"SYS_ADMIN", "SYS_BOOT", "SYS_NICE", "SYS_RESOURCE", "SYS_TIME", "SYS_TTY_CONFIG", "MKNOD",
+	"LEASE", "AUDIT_WRITE", "AUDIT_CONTROL", "SETFCAP", "MAC_OVERRIDE", "MAC_ADMIN", "SYSLOG",
+	"WAKE_ALARM", "BLOCK_SUSPEND", "AUDIT_READ", "PERFMON", "BPF", "CHECKPOINT_RESTORE",
+}
+
+// defaultCapabilities are the capabilities that container runtimes grant by default.
+var defaultCapabilities = []string{
+	"CHOWN", "DAC_OVERRIDE", "FSETID", "FOWNER", "MKNOD", "NET_RAW", "SETGID", "SETUID", "SETFCAP",
+	"SETPCAP", "NET_BIND_SERVICE", "SYS_CHROOT", "KILL", "AUDIT_WRITE",
+}
+
+// runtimeDefaultDenied are the system calls refused by RuntimeDefaultSeccomp, those of them that
+// do not exist on the architecture are ignored. Filters on arguments, such as the namespace flags
+// of clone, are not reproduced.
+var runtimeDefaultDenied = []string{
+	"acct", "add_key", "bpf", "clock_adjtime", "clock_settime", "create_module", "delete_module",
+	"finit_module", "fsconfig", "fsmount", "fsopen", "fspick", "get_kernel_syms", "get_mempolicy",
+	"init_module", "io_uring_enter", "io_uring_register", "io_uring_setup", "ioperm", "iopl", "kcmp",
+	"kexec_file_load", "kexec_load", "keyctl", "lookup_dcookie", "mbind", "mount", "move_mount",
+	"move_pages", "name_to_handle_at", "nfsservctl", "open_by_handle_at", "open_tree",
+	"perf_event_open", "pivot_root", "query_module", "quotactl", "reboot", "request_key",
+	"set_mempolicy", "setns", "settimeofday", "swapoff", "swapon", "_sysctl", "syslog", "umount2",
+	"unshare", "uselib", "userfaultfd", "ustat", "vhangup",
+}
+
+// restricted tells whether the server runs with dropped capabilities or a seccomp profile.
+func (b *Benchmark) restricted() bool {
+	return len(b.Capabilities) > 0 || len(b.Seccomp) > 0
+}
+
+// checkRestrictions tells whether the server can run with the capabilities and seccomp profile of
+// the benchmark.
+func (b *Benchmark) checkRestrictions() error {
+	if !b.restricted() {
+		return nil
+	}
+	launcher := b.Launcher
+	if len(launcher) == 0 {
+		launcher = DefaultLauncher
+	}
+	if !sandboxLaunchers[launcher] {
+		return fmt.Errorf("the %s launcher does not support restricting capabilities or seccomp (supported: exec, shell)", launcher)
+	}
+	if b.ReadOnlyRootfs {
+		return errors.New("a read-only root filesystem cannot be combined with restricted capabilities or seccomp, which forbid mounting it")
+	}
+	if _, err := parseCapabilities(b.Capabilities); err != nil {
+		return err
+	}
+	if len(b.Seccomp) > 0 && b.Seccomp != RuntimeDefaultSeccomp {
+		return fmt.Errorf("unknown seccomp profile: %s (expected %s)", b.Seccomp, RuntimeDefaultSeccomp)
+	}
+	return checkRestrictionsSupport(b.Seccomp)
+}
+
+// parseCapabilities returns the numbers of the capabilities to keep: none, the default ones, or a
+// comma-separated list of names such as NET_BIND_SERVICE or cap_net_raw.
+func parseCapabilities(spec string) ([]int, error) {
+	var names []string
+	switch spec {
+	case "", NoCapabilities:
+	case DefaultCapabilities:
+		names = defaultCapabilities
+	default:
+		names = strings.Split(spec, ",")
+	}
+	numbers := make([]int, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "CAP_")
+		number := -1
+		for i, known := range capabilityNames {
+			if known == name {
+				number = i
+			}
+		}
+		if number < 0 {
+			return nil, fmt.Errorf("unknown capability: %s", name)
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"errors"
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// Values of the kernel interfaces restricting processes.
+const (
+	prSetNoNewPrivs     = 38
+	prCapbsetDrop       = 24
+	prSetSeccomp        = 22
+	seccompModeFilter   = 2
+	seccompRetAllow     = 0x7fff0000
+	seccompRetErrno     = 0x00050000
+	capabilityVersion3  = 0x20080522
+	bpfLoadWordAbsolute = 0x20 // BPF_LD | BPF_W | BPF_ABS
+	bpfJumpEqual        = 0x15 // BPF_JMP | BPF_JEQ | BPF_K
+	bpfReturn           = 0x06 // BPF_RET | BPF_K
+)
+
+type sockFilter struct {
+	code uint16
+	jt   uint8
+	jf   uint8
+	k    uint32
+}
+
+type sockFprog struct {
+	len    uint16
+	filter *sockFilter
+}
+
+type capHeader struct {
+	version uint32
+	pid     int32
+}
+
+type capData struct {
+	effective, permitted, inheritable uint32
+}
+
+func checkRestrictionsSupport(seccomp string) error {
+	if len(seccomp) > 0 && seccompArch == 0 {
+		return errors.New("seccomp profiles are not supported on " + runtime.GOARCH)
+	}
+	return nil
+}
+
+// startRestricted calls start, which spawns the server, from a thread restricted to the
+// capabilities and seccomp profile of b: processes inherit them from the thread that spawned them.
+func (b *Benchmark) startRestricted(start func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		// The restrictions of the thread cannot be lifted, so it is not unlocked: it ends along
+		// with the goroutine rather than running other goroutines.
+		runtime.LockOSThread()
+		if err := b.restrictThread(); err != nil {
+			done <- err
+			return
+		}
+		done <- start()
+	}()
+	return <-done
+}
+
+// restrictThread restricts the calling thread. Privileges cannot be gained back on execution,
+// through setuid or file capabilities, as with allowPrivilegeEscalation: false on Kubernetes.
+func (b *Benchmark) restrictThread() error {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return errno
+	}
+	if len(b.Capabilities) > 0 {
+		kept, err := parseCapabilities(b.Capabilities)
+		if err != nil {
+			return err
+		}
+		if err := restrictCapabilities(kept); err != nil {
+			return err
+		}
+	}
+	if b.Seccomp == RuntimeDefaultSeccomp {
+		return installSeccompFilter(runtimeDefaultDenied)
+	}
+	return nil
+}
+
+// restrictCapabilities drops the capabilities of the thread, and those it may get on execution,
+// besides kept.
+func restrictCapabilities(kept []int) error {
+	var mask uint64
+	for _, cap := range kept {
+		mask |= 1 << uint(cap)
+	}
+	header := capHeader{version: capabilityVersion3}
+	var data [2]capData
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_CAPGET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return errno
+	}
+	// Without CAP_SETPCAP the bounding set cannot be changed, but there is no capability to lose.
+	if data[0].effective&(1<<8) != 0 {
+		last := len(capabilityNames) - 1
+		if content, err := ioutil.ReadFile("/proc/sys/kernel/cap_last_cap"); err == nil {
+			if n, err := strconv.Atoi(strings.TrimSpace(string(content))); err == nil {
+				last = n
+			}
+		}
+		for cap := 0; cap <= last; cap++ {
+			if mask&(1<<uint(cap)) != 0 {
+				continue
+			}
+			if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prCapbsetDrop, uintptr(cap), 0); errno != 0 {
+				return errno
+			}
+		}
+	}
+	for i := range data {
+		word := uint32(mask >> uint(32*i))
+		data[i].effective &= word
+		data[i].permitted &= word
+		data[i].inheritable &= word
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// installSeccompFilter makes the denied system calls fail with EPERM on the calling thread.
+func installSeccompFilter(denied []string) error {
+	var numbers []uint32
+	for _, name := range denied {
+		if number, found := seccompSyscalls[name]; found {
+			numbers = append(numbers, number)
+		}
+	}
+	n := len(numbers)
+	// The program checks the architecture, then the system call number, then allows or denies.
+	program := []sockFilter{
+		{code: bpfLoadWordAbsolute, k: 4}, // arch of seccomp_data
+		{code: bpfJumpEqual, k: seccompArch, jf: uint8(n + 1)},
+		{code: bpfLoadWordAbsolute, k: 0}, // nr of seccomp_data
+	}
+	for i, number := range numbers {
+		program = append(program, sockFilter{code: bpfJumpEqual, k: number, jt: uint8(n - i)})
+	}
+	program = append(program,
+		sockFilter{code: bpfReturn, k: seccompRetAllow},
+		sockFilter{code: bpfReturn, k: seccompRetErrno | uint32(syscall.EPERM)})
+	prog := sockFprog{len: uint16(len(program)), filter: &program[0]}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetSeccomp, seccompModeFilter, uintptr(unsafe.Pointer(&prog)))
+	runtime.KeepAlive(program)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"time"
+)
+
+// SchemaVersion identifies the layout of Results, it is bumped on incompatible changes.
+const SchemaVersion = 1
+
+// Results is the outcome of a benchmark, and the single source of data that reports consume.
+//
+// Durations are encoded in nanoseconds, and the JSON field names are part of the schema.
+type Results struct {
+	SchemaVersion int       `json:"schema_version"`
+	Scenario      string    `json:"scenario,omitempty"`
+	Description   string    `json:"description,omitempty"` // what the scenario is about
+	Hypothesis    string    `json:"hypothesis,omitempty"`  // the expected outcome
+	ExpectedFail  bool      `json:"expected_fail,omitempty"`
+	Profile       string    `json:"profile,omitempty"`
+	Mode          string    `json:"mode"`
+	Target        string    `json:"target"`
+	Command       string    `json:"command"`
+	Args          []string  `json:"args"`
+	StartedAt     time.Time `json:"started_at"`
+	Host          *Host     `json:"host,omitempty"` // where the benchmark ran
+	Runs          []Run     `json:"runs"`           // dry runs first, then measured runs, in execution order
+
+	ProbeCalibration *ProbeCalibration `json:"probe_calibration,omitempty"`
+	SettleBaseline   *SystemLoad       `json:"settle_baseline,omitempty"` // sampled before the first run when settling
+	CPUReservation   *CPUReservation   `json:"cpu_reservation,omitempty"`
+	HostProfile      *HostProfile      `json:"host_profile,omitempty"` // measured by the calibrate command
+}
+
+// ProbeCalibration estimates how much probing itself delays readiness, by comparing runs probing
+// at the configured rate with runs probing at a gentle reference rate.
+//
+// Probing at the reference rate detects readiness half a probe interval late on average, so the
+// delay is the median duration at the configured rate minus the median duration at the reference
+// rate corrected by that half interval. Values close to zero or negative mean that probing has no
+// measurable impact.
+type ProbeCalibration struct {
+	ProbeRate          float64         `json:"probe_rate"` // attempts per second, 0 when unlimited
+	ReferenceProbeRate float64         `json:"reference_probe_rate"`
+	Durations          []time.Duration `json:"durations_ns"`
+	ReferenceDurations []time.Duration `json:"reference_durations_ns"`
+	Delay              time.Duration   `json:"delay_ns"`
+}
+
+func (c *ProbeCalibration) estimate() {
+	halfInterval := time.Duration(float64(time.Second) / c.ReferenceProbeRate / 2)
+	c.Delay = Median(c.Durations) - (Median(c.ReferenceDurations) - halfInterval)
+}
+
+// Run holds every observable of a single boot.
+type Run struct {
+	Dry       bool          `json:"dry"`
+	Index     int           `json:"index"` // position among the runs of the same kind, from 0
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration_ns"` // from spawning the process to readiness
+	Phases    []Phase       `json:"phases"`
+	Attempts  int           `json:"probe_attempts"`
+	Target    string        `json:"target,omitempty"` // discovered with Benchmark.AutoTarget
+	// ProbeResolution is the time between the last failed probe attempt and the first of those that
+	// made the server ready, within which it became ready.
+	ProbeResolution time.Duration `json:"probe_resolution_ns,omitempty"`
+	// Flaps counts the probe failures after which the server was no longer ready, within the
+	// stability window.
+	Flaps int `json:"flaps,omitempty"`
+	// SettleWait is how long the run waited for the system to settle after the pause.
+	SettleWait time.Duration `json:"settle_wait_ns,omitempty"`
+	// Environment describes the host right before the run.
+	Environment *EnvironmentSnapshot `json:"environment,omitempty"`
+	// LingeringSockets counts the sockets by state left on the target port when the run started.
+	LingeringSockets map[string]int    `json:"lingering_sockets,omitempty"`
+	ReadyProbe       *ProbeResult      `json:"ready_probe,omitempty"` // what the probe attempt that made the server ready observed
+	Resources        Resources         `json:"resources"`             // of the first boot with Benchmark.CrashRecovery
+	Exit             *ExitStatus       `json:"exit,omitempty"`
+	Scheduling       *Scheduling       `json:"scheduling,omitempty"`     // at readiness
+	Memory           *Memory           `json:"memory,omitempty"`         // at readiness
+	CPU              *CPUTime          `json:"cpu,omitempty"`            // until readiness
+	JVM              *JVMMetrics       `json:"jvm,omitempty"`            // when collected, at readiness
+	RefusedWrites    []string          `json:"refused_writes,omitempty"` // output lines reporting writes refused by the read-only root filesystem
+	Recovery         *Recovery         `json:"recovery,omitempty"`       // with Benchmark.CrashRecovery
+	Shutdown         *Shutdown         `json:"shutdown,omitempty"`       // unset when the server was killed at once or exited by itself
+	StderrTail       []string          `json:"stderr_tail,omitempty"`    // last lines of the standard error of a server that exited before being ready
+	Annotations      map[string]string `json:"annotations,omitempty"`
+	Error            string            `json:"error,omitempty"`          // set when the run failed
+	ErrorCategory    string            `json:"error_category,omitempty"` // see ErrorCategory
+}
+
+// Phase marks a point of a run, relative to the moment the process was spawned.
+type Phase struct {
+	Name   string        `json:"name"`
+	Offset time.Duration `json:"offset_ns"`
+}
+
+// Names of the phases recorded for every run.
+const (
+	SpawnedPhase      = "spawned"       // the process has been started
+	FirstSuccessPhase = "first-success" // a first probe succeeded, when several consecutive successes are required
+	ReadyPhase        = "ready"         // the probes that make the server ready succeeded
+	StablePhase       = "stable"        // the probes kept succeeding for the stability window, when there is one
+)
+
+// Resources holds the resources consumed by the process of a run.
+//
+// With the systemd-scope launcher, they cover every process of the scope, and the memory and I/O
+// are known as well.
+type Resources struct {
+	UserCPU    time.Duration `json:"user_cpu_ns"`
+	SystemCPU  time.Duration `json:"system_cpu_ns"`
+	MemoryPeak int64         `json:"memory_peak_bytes,omitempty"`
+	IORead     int64         `json:"io_read_bytes,omitempty"`
+	IOWrite    int64         `json:"io_write_bytes,omitempty"`
+}
+
+// ExitStatus tells how the process of a run terminated.
+type ExitStatus struct {
+	Code   int    `json:"code"`             // -1 when terminated by a signal
+	Signal string `json:"signal,omitempty"` // name of the terminating signal, if any
+}
+
+// Failed tells whether the run failed.
+func (r *Run) Failed() bool {
+	return len(r.Error) > 0
+}
+
+// Annotate attaches a free-form key and value to the run.
+func (r *Run) Annotate(key, value string) {
+	if r.Annotations == nil {
+		r.Annotations = make(map[string]string)
+	}
+	r.Annotations[key] = value
+}
+
+func (r *Run) mark(name string, offset time.Duration) {
+	r.Phases = append(r.Phases, Phase{Name: name, Offset: offset})
+}
+
+func (r *Run) reached(phase string) bool {
+	for _, p := range r.Phases {
+		if p.Name == phase {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Run) recordTermination(termination Termination) {
+	r.Exit = termination.Exit
+	r.Resources = termination.Resources
+}
+
+// Measured returns the successful measured runs, leaving out dry runs and failures.
+func (r *Results) Measured() []Run {
+	var runs []Run
+	for _, run := range r.Runs {
+		if !run.Dry && !run.Failed() {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// Reproducible returns a copy of the results without their timestamps and host names, so that
+// reports generated from the same runs on different machines are identical.
+func (r *Results) Reproducible() *Results {
+	reproducible := *r
+	reproducible.StartedAt = time.Time{}
+	if r.Host != nil {
+		host := *r.Host
+		host.Name = ""
+		reproducible.Host = &host
+	}
+	if r.HostProfile != nil {
+		profile := *r.HostProfile
+		profile.MeasuredAt, profile.Host.Name = time.Time{}, ""
+		reproducible.HostProfile = &profile
+	}
+	if r.Runs != nil {
+		reproducible.Runs = make([]Run, len(r.Runs))
+	}
+	for i, run := range r.Runs {
+		run.StartedAt = time.Time{}
+		reproducible.Runs[i] = run
+	}
+	return &reproducible
+}
+
+// unixNano returns t as nanoseconds since the Unix epoch, or 0 for the zero time of reproducible results.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// Durations returns the durations of runs.
+func Durations(runs []Run) []time.Duration {
+	durations := make([]time.Duration, len(runs))
+	for i := range runs {
+		durations[i] = runs[i].Duration
+	}
+	return durations
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ReadOnlyRootfsCategory is the category of run failures of servers that reported writes refused
+// by the read-only root filesystem, see Benchmark.ReadOnlyRootfs.
+const ReadOnlyRootfsCategory = "read-only-rootfs"
+
+// maxRefusedWrites bounds the lines kept in Run.RefusedWrites.
+const maxRefusedWrites = 10
+
+// sandboxLaunchers are the launchers that can run servers with a read-only root filesystem, dropped
+// capabilities or a seccomp profile.
+var sandboxLaunchers = map[string]bool{"exec": true, "shell": true}
+
+// checkReadOnlyRootfs tells whether the server can run with a read-only root filesystem.
+func (b *Benchmark) checkReadOnlyRootfs() error {
+	if !b.ReadOnlyRootfs {
+		return nil
+	}
+	launcher := b.Launcher
+	if len(launcher) == 0 {
+		launcher = DefaultLauncher
+	}
+	if !sandboxLaunchers[launcher] {
+		return fmt.Errorf("the %s launcher does not support a read-only root filesystem (supported: exec, shell)", launcher)
+	}
+	if runtime.GOOS != "linux" {
+		return errors.New("a read-only root filesystem is only supported on Linux")
+	}
+	if _, err := exec.LookPath("unshare"); err != nil {
+		return fmt.Errorf("a read-only root filesystem needs unshare: %v", err)
+	}
+	return nil
+}
+
+// readOnlyRootfsCommand wraps a command so that it runs in a mount namespace of its own, where the
+// root filesystem is remounted read-only. Other mounts, such as /proc, /dev or a /tmp tmpfs, keep
+// their modes, as the volumes of a container would.
+//
+// Unprivileged benchmarks get a user namespace too, where they keep their user identifier. Both
+// unshare and the shell execute the command in place, so the process remains the server.
+func readOnlyRootfsCommand(name string, args []string) (string, []string) {
+	unshare := []string{"--mount", "--propagation", "private"}
+	if os.Geteuid() != 0 {
+		unshare = append(unshare, "--user", "--map-current-user")
+	}
+	unshare = append(unshare, "--", "/bin/sh", "-c", `mount -o remount,bind,ro / && exec "$0" "$@"`, name)
+	return "unshare", append(unshare, args...)
+}
+
+// refusedWrites collects the lines of the server output that report writes refused by a
+// read-only filesystem.
+type refusedWrites struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *refusedWrites) check(text string) {
+	if !strings.Contains(strings.ToLower(text), "read-only file system") {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.lines) < maxRefusedWrites {
+		r.lines = append(r.lines, text)
+	}
+}
+
+func (r *refusedWrites) reported() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lines
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RunLogs writes the output of each run of a benchmark to files of a directory: run-<n>.out and
+// run-<n>.err hold the standard output and error of the n-th measured run, dry-run-<n>.out and
+// dry-run-<n>.err those of dry runs. Runs writing nothing get no files, and registered secrets are
+// redacted.
+type RunLogs struct {
+	mu      sync.Mutex
+	dir     string
+	dry     bool
+	run     int
+	streams map[string]*os.File // of the current run, by stream
+	err     error
+}
+
+// NewRunLogs creates the directory when missing.
+func NewRunLogs(dir string) (*RunLogs, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &RunLogs{dir: dir}, nil
+}
+
+// Attach hooks into b to write the output of its runs. A callback that was already set on b is
+// still called.
+func (l *RunLogs) Attach(b *Benchmark) {
+	onOutput := b.OnOutput
+	b.OnOutput = func(line OutputLine) {
+		l.write(line)
+		if onOutput != nil {
+			onOutput(line)
+		}
+	}
+}
+
+func (l *RunLogs) write(line OutputLine) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+	// Runs are sequential, so the files of a run are closed once another run writes.
+	if l.streams != nil && (line.Dry != l.dry || line.Run != l.run) {
+		l.closeRun()
+	}
+	if l.streams == nil {
+		l.dry, l.run, l.streams = line.Dry, line.Run, make(map[string]*os.File, 2)
+	}
+	file, found := l.streams[line.Stream]
+	if !found {
+		name := fmt.Sprintf("run-%d", line.Run+1)
+		if line.Dry {
+			name = "dry-" + name
+		}
+		extension := ".out"
+		if line.Stream == Stderr {
+			extension = ".err"
+		}
+		if file, l.err = os.Create(filepath.Join(l.dir, name+extension)); l.err != nil {
+			return
+		}
+		l.streams[line.Stream] = file
+	}
+	_, l.err = file.WriteString(Redact(line.Text) + "\n")
+}
+
+func (l *RunLogs) closeRun() {
+	for _, file := range l.streams {
+		if err := file.Close(); err != nil && l.err == nil {
+			l.err = err
+		}
+	}
+	l.streams = nil
+}
+
+// Close closes the files of the last run, and returns the first error met writing the files.
+func (l *RunLogs) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closeRun()
+	return l.err
+}
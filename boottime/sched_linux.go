@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readScheduling sums the /proc/<pid>/task/<tid>/schedstat statistics of the threads of a process.
+func readScheduling(pid int) (*Scheduling, error) {
+	dir := fmt.Sprintf("/proc/%d/task", pid)
+	tasks, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	scheduling := &Scheduling{}
+	for _, task := range tasks {
+		content, err := ioutil.ReadFile(dir + "/" + task.Name() + "/schedstat")
+		if err != nil {
+			continue // the thread exited
+		}
+		fields := strings.Fields(string(content))
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("unexpected schedstat content: %q", content)
+		}
+		var values [3]int64
+		for i := range values {
+			if values[i], err = strconv.ParseInt(fields[i], 10, 64); err != nil {
+				return nil, err
+			}
+		}
+		scheduling.Running += time.Duration(values[0])
+		scheduling.Waiting += time.Duration(values[1])
+		scheduling.Timeslices += values[2]
+	}
+	return scheduling, nil
+}
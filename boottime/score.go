@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"crypto/sha256"
+	"time"
+)
+
+// CPUScoreWorkload describes the calibration workload of MeasureCPUScore, so that scores of
+// different versions are not compared if it ever changes.
+const CPUScoreWorkload = "sha256-1KiB-v1"
+
+const (
+	cpuScoreRounds = 5
+	cpuScoreRound  = 100 * time.Millisecond
+)
+
+// MeasureCPUScore measures how fast a single thread of the host computes SHA-256 digests of 1 KiB,
+// in thousands of digests per second. The best of several rounds is kept, as the least disturbed
+// by other processes. Boot times of different hosts can be normalized by the ratio of their scores.
+func MeasureCPUScore(ctx context.Context) (float64, error) {
+	var block [1024]byte
+	best := 0.0
+	for round := 0; round < cpuScoreRounds; round++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		digests := 0
+		start := time.Now()
+		for time.Since(start) < cpuScoreRound {
+			for i := 0; i < 64; i++ {
+				sum := sha256.Sum256(block[:])
+				block[0] = sum[0] // depend on the previous digest
+				digests++
+			}
+		}
+		if score := float64(digests) / time.Since(start).Seconds() / 1000; score > best {
+			best = score
+		}
+	}
+	return best, nil
+}
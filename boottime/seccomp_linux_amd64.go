@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+// seccompArch is AUDIT_ARCH_X86_64, the architecture of system calls.
+const seccompArch = 0xc000003e
+
+// seccompSyscalls are the numbers of the system calls of the seccomp profiles.
+var seccompSyscalls = map[string]uint32{
+	"_sysctl":           156,
+	"acct":              163,
+	"add_key":           248,
+	"bpf":               321,
+	"clock_adjtime":     305,
+	"clock_settime":     227,
+	"create_module":     174,
+	"delete_module":     176,
+	"finit_module":      313,
+	"fsconfig":          431,
+	"fsmount":           432,
+	"fsopen":            430,
+	"fspick":            433,
+	"get_kernel_syms":   177,
+	"get_mempolicy":     239,
+	"init_module":       175,
+	"io_uring_enter":    426,
+	"io_uring_register": 427,
+	"io_uring_setup":    425,
+	"ioperm":            173,
+	"iopl":              172,
+	"kcmp":              312,
+	"kexec_file_load":   320,
+	"kexec_load":        246,
+	"keyctl":            250,
+	"lookup_dcookie":    212,
+	"mbind":             237,
+	"mount":             165,
+	"move_mount":        429,
+	"move_pages":        279,
+	"name_to_handle_at": 303,
+	"nfsservctl":        180,
+	"open_by_handle_at": 304,
+	"open_tree":         428,
+	"perf_event_open":   298,
+	"pivot_root":        155,
+	"query_module":      178,
+	"quotactl":          179,
+	"reboot":            169,
+	"request_key":       249,
+	"set_mempolicy":     238,
+	"setns":             308,
+	"settimeofday":      164,
+	"swapoff":           168,
+	"swapon":            167,
+	"syslog":            103,
+	"umount2":           166,
+	"unshare":           272,
+	"uselib":            134,
+	"userfaultfd":       323,
+	"ustat":             136,
+	"vhangup":           153,
+}
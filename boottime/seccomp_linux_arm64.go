@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+// seccompArch is AUDIT_ARCH_AARCH64, the architecture of system calls.
+const seccompArch = 0xc00000b7
+
+// seccompSyscalls are the numbers of the system calls of the seccomp profiles.
+var seccompSyscalls = map[string]uint32{
+	"acct":              89,
+	"add_key":           217,
+	"bpf":               280,
+	"clock_adjtime":     266,
+	"clock_settime":     112,
+	"delete_module":     106,
+	"finit_module":      273,
+	"fsconfig":          431,
+	"fsmount":           432,
+	"fsopen":            430,
+	"fspick":            433,
+	"get_mempolicy":     236,
+	"init_module":       105,
+	"io_uring_enter":    426,
+	"io_uring_register": 427,
+	"io_uring_setup":    425,
+	"kcmp":              272,
+	"kexec_file_load":   294,
+	"kexec_load":        104,
+	"keyctl":            219,
+	"lookup_dcookie":    18,
+	"mbind":             235,
+	"mount":             40,
+	"move_mount":        429,
+	"move_pages":        239,
+	"name_to_handle_at": 264,
+	"nfsservctl":        42,
+	"open_by_handle_at": 265,
+	"open_tree":         428,
+	"perf_event_open":   241,
+	"pivot_root":        41,
+	"quotactl":          60,
+	"reboot":            142,
+	"request_key":       218,
+	"set_mempolicy":     237,
+	"setns":             268,
+	"settimeofday":      170,
+	"swapoff":           225,
+	"swapon":            224,
+	"syslog":            116,
+	"umount2":           39,
+	"unshare":           97,
+	"userfaultfd":       282,
+	"vhangup":           58,
+}
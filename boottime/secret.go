@@ -0,0 +1,208 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redacted replaces secret values in logs and reports.
+const redacted = "******"
+
+var (
+	secretsMu sync.RWMutex
+	secrets   = make(map[string]bool)
+)
+
+// RegisterSecret makes sure that value never appears in logs, reports and session archives, along
+// with its JSON escaped and percent-encoded forms since redaction applies to encoded text too.
+func RegisterSecret(value string) {
+	if len(value) == 0 {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secrets[value] = true
+	if escaped, err := json.Marshal(value); err == nil {
+		secrets[strings.Trim(string(escaped), `"`)] = true
+	}
+	secrets[url.QueryEscape(value)] = true
+	secrets[url.PathEscape(value)] = true
+	secrets[strings.TrimPrefix(url.UserPassword("", value).String(), ":")] = true
+}
+
+// redactTarget hides the password of a target URL, so that it is not kept in results.
+func redactTarget(target string) string {
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.User == nil {
+		return target
+	}
+	if _, set := parsed.User.Password(); !set {
+		return target
+	}
+	return parsed.Redacted()
+}
+
+// Redact replaces the registered secrets found in s.
+func Redact(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	if len(secrets) == 0 {
+		return s
+	}
+	// Longest secrets first, so that a secret containing another one is fully redacted.
+	values := make([]string, 0, len(secrets))
+	for value := range secrets {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		s = strings.Replace(s, value, redacted, -1)
+	}
+	return s
+}
+
+// RedactValue returns a copy of v with the registered secrets replaced in the strings it holds,
+// so that v can be encoded without them: redacting the encoded data instead could alter its
+// syntax, should a secret match a key or a number.
+func RedactValue(v interface{}) interface{} {
+	secretsMu.RLock()
+	none := len(secrets) == 0
+	secretsMu.RUnlock()
+	if none || v == nil {
+		return v
+	}
+	return redactValue(reflect.ValueOf(v)).Interface()
+}
+
+func redactValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.String:
+		redacted := reflect.New(v.Type()).Elem()
+		redacted.SetString(Redact(v.String()))
+		return redacted
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		redacted := reflect.New(v.Type().Elem())
+		redacted.Elem().Set(redactValue(v.Elem()))
+		return redacted
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		redacted := reflect.New(v.Type()).Elem()
+		redacted.Set(redactValue(v.Elem()))
+		return redacted
+	case reflect.Struct:
+		// Unexported fields, which are not encoded, are copied as they are.
+		redacted := reflect.New(v.Type()).Elem()
+		redacted.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if field := redacted.Field(i); field.CanSet() {
+				field.Set(redactValue(v.Field(i)))
+			}
+		}
+		return redacted
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+		redacted := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			redacted.Index(i).Set(redactValue(v.Index(i)))
+		}
+		return redacted
+	case reflect.Array:
+		redacted := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			redacted.Index(i).Set(redactValue(v.Index(i)))
+		}
+		return redacted
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		redacted := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for entries := v.MapRange(); entries.Next(); {
+			redacted.SetMapIndex(redactValue(entries.Key()), redactValue(entries.Value()))
+		}
+		return redacted
+	}
+	return v
+}
+
+var secretReference = regexp.MustCompile(`\$\{(env|file):([^}]+)\}`)
+
+// ResolveSecret expands the secret references of value, and registers what they resolve to as secrets.
+//
+// References are either ${env:NAME} for an environment variable or ${file:PATH} for the content of a
+// file, anywhere in value, or a whole value of the form file:PATH. Files have their trailing line
+// terminators trimmed.
+func ResolveSecret(value string) (string, error) {
+	var err error
+	if strings.HasPrefix(value, "file:") {
+		value, err = readSecretFile(strings.TrimPrefix(value, "file:"))
+		RegisterSecret(value)
+	} else {
+		value = secretReference.ReplaceAllStringFunc(value, func(reference string) string {
+			parts := secretReference.FindStringSubmatch(reference)
+			if parts[1] == "env" {
+				resolved, found := os.LookupEnv(parts[2])
+				if !found && err == nil {
+					err = fmt.Errorf("undefined environment variable in secret reference: %s", parts[2])
+				}
+				RegisterSecret(resolved)
+				return resolved
+			}
+			resolved, fileErr := readSecretFile(parts[2])
+			if fileErr != nil && err == nil {
+				err = fileErr
+			}
+			RegisterSecret(resolved)
+			return resolved
+		})
+	}
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+func readSecretFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read secret: %v", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// SystemLoad describes how busy the host is.
+type SystemLoad struct {
+	Load float64 `json:"load"`        // 1 minute load average
+	CPU  float64 `json:"cpu"`         // fraction of the CPU time spent busy, from 0 to 1
+	IO   float64 `json:"io_bytes_ps"` // bytes read and written per second on the disks
+}
+
+// longPause is the pause duration from which pauses are logged, as they may last hours when
+// booting infrastructure.
+const longPause = time.Minute
+
+// SettleTimeout bounds how long runs wait for the system to settle, the run starts anyway afterwards.
+const SettleTimeout = 5 * time.Minute
+
+// settleSampleWindow is the duration over which CPU usage and disk I/O are measured.
+const settleSampleWindow = time.Second
+
+// Margins over the baseline within which the system is considered settled.
+const (
+	settleCPUMargin     = 0.05        // of the CPU time
+	settleLoadMargin    = 0.1         // per CPU
+	settleMinLoadMargin = 0.5         // whatever the number of CPUs
+	settleIORatio       = 1.25        // of the baseline I/O rate
+	settleIOMargin      = 1024 * 1024 // bytes per second
+)
+
+// settled tells whether load is back to the baseline.
+func (load SystemLoad) settled(baseline SystemLoad) bool {
+	loadMargin := settleLoadMargin * float64(runtime.NumCPU())
+	if loadMargin < settleMinLoadMargin {
+		loadMargin = settleMinLoadMargin
+	}
+	return load.CPU <= baseline.CPU+settleCPUMargin &&
+		load.Load <= baseline.Load+loadMargin &&
+		load.IO <= baseline.IO*settleIORatio+settleIOMargin
+}
+
+// pause waits between runs: the configured pause, then until the system settles when enabled.
+// It returns how long it waited for the system to settle.
+func (s *session) pause(ctx context.Context) (time.Duration, error) {
+	if s.Pause >= longPause {
+		s.Logger.Info("pausing before the next run", "pause", s.Pause, "until", time.Now().Add(s.Pause).Format(time.RFC3339))
+	}
+	if err := sleep(ctx, s.Pause); err != nil {
+		return 0, err
+	}
+	if s.baseline == nil {
+		return 0, nil
+	}
+	start := time.Now()
+	for {
+		load, err := sampleSystemLoad(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			s.Logger.Debug("unable to sample the system load", "error", err)
+			return time.Since(start), nil
+		}
+		if load.settled(*s.baseline) {
+			waited := time.Since(start)
+			s.Logger.Debug("system settled", "waited", waited, "load", load.Load, "cpu", load.CPU, "io", load.IO)
+			return waited, nil
+		}
+		if time.Since(start) >= SettleTimeout {
+			s.Logger.Warn("the system did not settle, starting the next run anyway", "waited", time.Since(start),
+				"load", load.Load, "cpu", load.CPU, "io", load.IO)
+			return time.Since(start), nil
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sampleSystemLoad measures the load of the system over settleSampleWindow.
+func sampleSystemLoad(ctx context.Context) (SystemLoad, error) {
+	busy, total, err := readCPUTimes()
+	if err != nil {
+		return SystemLoad{}, err
+	}
+	sectors, err := readDiskSectors()
+	if err != nil {
+		return SystemLoad{}, err
+	}
+	start := time.Now()
+	if err := sleep(ctx, settleSampleWindow); err != nil {
+		return SystemLoad{}, err
+	}
+	busyAfter, totalAfter, err := readCPUTimes()
+	if err != nil {
+		return SystemLoad{}, err
+	}
+	sectorsAfter, err := readDiskSectors()
+	if err != nil {
+		return SystemLoad{}, err
+	}
+	elapsed := time.Since(start).Seconds()
+	load := SystemLoad{IO: float64(sectorsAfter-sectors) * 512 / elapsed}
+	if totalAfter > total {
+		load.CPU = float64(busyAfter-busy) / float64(totalAfter-total)
+	}
+	if load.Load, err = loadAverage(); err != nil {
+		return SystemLoad{}, err
+	}
+	return load, nil
+}
+
+// readCPUTimes returns the busy and total CPU times of /proc/stat, in clock ticks.
+func readCPUTimes() (busy, total uint64, err error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		for i, field := range fields[1:] {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, 0, err
+			}
+			total += value
+			if i != 3 && i != 4 { // idle and iowait
+				busy += value
+			}
+		}
+		return busy, total, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, fmt.Errorf("no cpu line in /proc/stat")
+}
+
+// readDiskSectors returns the sectors read and written on the disks of /proc/diskstats, skipping
+// partitions, which /sys/block does not list, so that they are not counted twice.
+func readDiskSectors() (uint64, error) {
+	file, err := os.Open("/proc/diskstats")
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	var sectors uint64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		if _, err := os.Stat("/sys/block/" + fields[2]); err != nil {
+			continue
+		}
+		read, err := strconv.ParseUint(fields[5], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		written, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		sectors += read + written
+	}
+	return sectors, scanner.Err()
+}
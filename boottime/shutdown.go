@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Defaults of the shutdown of the server at the end of each run.
+const (
+	DefaultShutdownSignal = "TERM"
+	DefaultShutdownGrace  = 10 * time.Second
+)
+
+// Shutdown tells how the server of a run was stopped.
+type Shutdown struct {
+	Signal   string        `json:"signal"`           // the first signal sent to the server
+	Duration time.Duration `json:"duration_ns"`      // from the first signal to the exit of the server
+	Killed   bool          `json:"killed,omitempty"` // the server outlived the grace period and was killed
+}
+
+func (b *Benchmark) shutdownSignal() (syscall.Signal, error) {
+	name := b.ShutdownSignal
+	if len(name) == 0 {
+		name = DefaultShutdownSignal
+	}
+	return parseSignal(name)
+}
+
+func (b *Benchmark) shutdownGrace() time.Duration {
+	if b.ShutdownGrace == 0 {
+		return DefaultShutdownGrace
+	}
+	return b.ShutdownGrace
+}
+
+func (b *Benchmark) checkShutdown() error {
+	if _, err := b.shutdownSignal(); err != nil {
+		return fmt.Errorf("invalid shutdown signal: %v", err)
+	}
+	if b.ShutdownGrace < 0 {
+		return fmt.Errorf("the shutdown grace period must not be negative")
+	}
+	return nil
+}
+
+// shutdown stops the server of a run with the shutdown signal, and kills it when it is still running
+// after the grace period, so that servers get a chance to release their ports and files.
+//
+// The servers of failed runs are killed at once, as they may not react to the signal and their exit
+// status tells whether they crashed, and so are the servers of remote launchers, whose local process
+// is only a command driving them. Servers that already exited by themselves are only waited for.
+func (s *session) shutdown(launcher Launcher, run *Run, failed bool) Termination {
+	sig, _ := s.shutdownSignal()
+	if watched, ok := launcher.(*watchedLauncher); ok && watched.hasExited() {
+		return s.wait(launcher)
+	}
+	if failed || remoteLaunchers[s.Launcher] || sig == syscall.SIGKILL {
+		if err := launcher.Signal(os.Kill); err != nil {
+			s.Logger.Debug("unable to kill the process", "error", err)
+		}
+		return s.wait(launcher)
+	}
+	signalled := time.Now()
+	run.Shutdown = &Shutdown{Signal: "SIG" + signalName(sig)}
+	if err := launcher.Signal(sig); err != nil {
+		s.Logger.Debug("unable to signal the process", "signal", sig, "error", err)
+	}
+	terminated := make(chan Termination, 1)
+	go func() {
+		terminated <- s.wait(launcher)
+	}()
+	timer := time.NewTimer(s.shutdownGrace())
+	defer timer.Stop()
+	select {
+	case termination := <-terminated:
+		run.Shutdown.Duration = time.Since(signalled)
+		return termination
+	case <-timer.C:
+	}
+	s.Logger.Warn("the server outlived the shutdown grace period, killing it", "grace", s.shutdownGrace())
+	run.Shutdown.Killed = true
+	if err := launcher.Signal(os.Kill); err != nil {
+		s.Logger.Debug("unable to kill the process", "error", err)
+	}
+	termination := <-terminated
+	run.Shutdown.Duration = time.Since(signalled)
+	return termination
+}
+
+func (s *session) wait(launcher Launcher) Termination {
+	termination, err := launcher.Wait()
+	if err != nil {
+		s.Logger.Debug("unable to wait for the process", "error", err)
+	}
+	return termination
+}
+
+// signalName returns the name of sig in signals, as in TERM.
+func signalName(sig syscall.Signal) string {
+	for name, s := range signals {
+		if s == sig {
+			return name
+		}
+	}
+	return sig.String()
+}
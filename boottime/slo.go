@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"time"
+)
+
+// SLO is an objective on the boot time of a server, returning an error describing the violation
+// when the measured runs do not meet it. The boottime/testing package checks objectives in Go tests.
+type SLO func(runs []Run) error
+
+// MedianUnder requires the median boot time to be under d.
+func MedianUnder(d time.Duration) SLO {
+	return func(runs []Run) error {
+		if m := Median(Durations(runs)); m >= d {
+			return fmt.Errorf("median boot time %s is not under %s", m, d)
+		}
+		return nil
+	}
+}
+
+// PercentileUnder requires the p-th percentile of the boot time, as in 95, to be under d.
+func PercentileUnder(p float64, d time.Duration) SLO {
+	return func(runs []Run) error {
+		if v := Percentile(Durations(runs), p); v >= d {
+			return fmt.Errorf("%gth percentile boot time %s is not under %s", p, v, d)
+		}
+		return nil
+	}
+}
+
+// MaxUnder requires every boot time to be under d.
+func MaxUnder(d time.Duration) SLO {
+	return PercentileUnder(100, d)
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// EnvironmentSnapshot describes the host right before a run, for slow runs to be correlated with
+// the conditions they ran in. Indicators that could not be read are left out.
+type EnvironmentSnapshot struct {
+	Load            *float64 `json:"load,omitempty"`                   // 1 minute load average
+	MemoryAvailable *uint64  `json:"memory_available_bytes,omitempty"` // for new processes without swapping
+	Containers      *int     `json:"running_containers,omitempty"`     // counted with docker or podman
+}
+
+// containerCountTimeout bounds how long counting containers may take, such as when the daemon does
+// not answer.
+const containerCountTimeout = 2 * time.Second
+
+// snapshotEnvironment reads the indicators of the environment of the host.
+func snapshotEnvironment(ctx context.Context) *EnvironmentSnapshot {
+	snapshot := &EnvironmentSnapshot{}
+	if load, err := loadAverage(); err == nil {
+		snapshot.Load = &load
+	}
+	if available, err := memoryAvailable(); err == nil {
+		snapshot.MemoryAvailable = &available
+	}
+	if count, err := runningContainers(ctx); err == nil {
+		snapshot.Containers = &count
+	}
+	return snapshot
+}
+
+// runningContainers counts the running containers with the first of docker and podman that is
+// installed.
+func runningContainers(ctx context.Context) (int, error) {
+	var err error
+	for _, cli := range []string{"docker", "podman"} {
+		var path string
+		if path, err = exec.LookPath(cli); err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, containerCountTimeout)
+		output, cmdErr := exec.CommandContext(ctx, path, "ps", "--quiet").Output()
+		cancel()
+		if cmdErr != nil {
+			return 0, cmdErr
+		}
+		return len(strings.Fields(string(output))), nil
+	}
+	return 0, err
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policies towards sockets lingering on the target port before a run, such as TIME_WAIT sockets of
+// the previous run, which may delay or prevent the server from listening.
+const (
+	FlagLingeringSockets   = "flag"   // record them in the run and warn
+	WaitLingeringSockets   = "wait"   // wait for them to go away, up to LingeringSocketsTimeout
+	IgnoreLingeringSockets = "ignore" // do not check
+)
+
+// LingeringSocketsTimeout bounds how long runs wait for lingering sockets, twice the usual
+// TIME_WAIT duration of Linux. Runs are flagged when sockets remain.
+const LingeringSocketsTimeout = 2 * time.Minute
+
+const lingeringSocketsInterval = 100 * time.Millisecond
+
+func checkLingeringSocketsPolicy(policy string) error {
+	switch policy {
+	case "", FlagLingeringSockets, WaitLingeringSockets, IgnoreLingeringSockets:
+		return nil
+	}
+	return fmt.Errorf("unknown lingering sockets policy: %s (expected %s, %s or %s)", policy, FlagLingeringSockets, WaitLingeringSockets, IgnoreLingeringSockets)
+}
+
+// targetPort returns the port of a URL or host:port target, or 0.
+func targetPort(target string) int {
+	if u, err := url.Parse(target); err == nil && len(u.Host) > 0 {
+		if port, err := strconv.Atoi(u.Port()); err == nil {
+			return port
+		}
+		switch u.Scheme {
+		case "http":
+			return 80
+		case "https":
+			return 443
+		}
+		return 0
+	}
+	if _, port, err := net.SplitHostPort(target); err == nil {
+		if n, err := strconv.Atoi(port); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// checkLingeringSockets applies the lingering sockets policy before a run.
+func (s *session) checkLingeringSockets(ctx context.Context, run *Run) error {
+	if s.LingeringSockets == IgnoreLingeringSockets {
+		return nil
+	}
+	port := targetPort(s.Target)
+	if port == 0 {
+		return nil
+	}
+	sockets, err := lingeringSockets(port)
+	if err != nil {
+		s.Logger.Debug("unable to check lingering sockets", "error", err)
+		return nil
+	}
+	if len(sockets) > 0 && s.LingeringSockets == WaitLingeringSockets {
+		s.Logger.Info("waiting for lingering sockets", "port", port, "sockets", formatSocketStates(sockets))
+		deadline := time.Now().Add(LingeringSocketsTimeout)
+		for len(sockets) > 0 && time.Now().Before(deadline) {
+			if err := sleep(ctx, lingeringSocketsInterval); err != nil {
+				return err
+			}
+			if sockets, err = lingeringSockets(port); err != nil {
+				return nil
+			}
+		}
+	}
+	if len(sockets) > 0 {
+		run.LingeringSockets = sockets
+		s.Logger.Warn("run starting with lingering sockets on the target port, the server may need SO_REUSEADDR to listen without delay",
+			"port", port, "sockets", formatSocketStates(sockets))
+	}
+	return nil
+}
+
+func formatSocketStates(sockets map[string]int) string {
+	var states []string
+	for state, count := range sockets {
+		states = append(states, fmt.Sprintf("%d %s", count, state))
+	}
+	sort.Strings(states)
+	return strings.Join(states, ", ")
+}
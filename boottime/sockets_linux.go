@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tcpStates names the states of /proc/net/tcp that interfere with a server listening on a port.
+var tcpStates = map[string]string{
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+// lingeringSockets counts the local sockets of a port by state, ignoring harmless states.
+func lingeringSockets(port int) (map[string]int, error) {
+	sockets := map[string]int{}
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		file, err := os.Open(table)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Scan() // header
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 4 {
+				continue
+			}
+			local := fields[1]
+			localPort, err := strconv.ParseInt(local[strings.LastIndexByte(local, ':')+1:], 16, 32)
+			if err != nil || int(localPort) != port {
+				continue
+			}
+			if state, found := tcpStates[fields[3]]; found {
+				sockets[state]++
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sockets, nil
+}
+
+// listeningAddresses returns the local addresses of the TCP sockets that a process or its
+// descendants listen on, sorted by port.
+func listeningAddresses(pid int) ([]string, error) {
+	stats, err := readProcStats()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := processTree(stats, pid)
+	if err != nil {
+		return nil, err
+	}
+	inodes, err := socketInodes(tree)
+	if err != nil {
+		return nil, err
+	}
+	var addresses []string
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		content, err := ioutil.ReadFile(table)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(content), "\n")[1:] {
+			fields := strings.Fields(line)
+			if len(fields) < 10 || fields[3] != "0A" || !inodes[fields[9]] {
+				continue
+			}
+			if address, err := parseProcAddress(fields[1]); err == nil {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	sort.SliceStable(addresses, func(i, j int) bool { return targetPort(addresses[i]) < targetPort(addresses[j]) })
+	return addresses, nil
+}
+
+// socketInodes returns the inodes of the sockets opened by processes.
+func socketInodes(pids []int) (map[string]bool, error) {
+	inodes := map[string]bool{}
+	for i, pid := range pids {
+		dir := fmt.Sprintf("/proc/%d/fd", pid)
+		fds, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			continue // the descendant exited
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(dir, fd.Name()))
+			if err == nil && strings.HasPrefix(link, "socket:[") {
+				inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] = true
+			}
+		}
+	}
+	return inodes, nil
+}
+
+// parseProcAddress parses an address of /proc/net/tcp or /proc/net/tcp6, as in 0100007F:1F90,
+// where the address is made of 32 bits words in host order.
+func parseProcAddress(s string) (string, error) {
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return "", fmt.Errorf("invalid address: %s", s)
+	}
+	raw, err := hex.DecodeString(s[:i])
+	if err != nil || len(raw)%4 != 0 {
+		return "", fmt.Errorf("invalid address: %s", s)
+	}
+	port, err := strconv.ParseUint(s[i+1:], 16, 16)
+	if err != nil {
+		return "", err
+	}
+	ip := make(net.IP, len(raw))
+	for w := 0; w < len(raw); w += 4 {
+		for b := 0; b < 4; b++ {
+			ip[w+b] = raw[w+3-b] // little endian words
+		}
+	}
+	return net.JoinHostPort(ip.String(), strconv.FormatUint(port, 10)), nil
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/montanaflynn/stats"
+)
+
+// ReportedPercentiles are the percentiles of Statistics.
+var ReportedPercentiles = []float64{75.0, 80.0, 85.0, 90.0, 95.0, 97.5, 98.0, 99.0, 99.9, 100.0}
+
+// Statistics summarize durations. Every report and exporter takes its numbers from them, so that
+// they all agree on the same runs.
+type Statistics struct {
+	Count       int                      `json:"count"`
+	Min         time.Duration            `json:"min_ns"`
+	Max         time.Duration            `json:"max_ns"`
+	Mean        time.Duration            `json:"mean_ns"`
+	GeoMean     time.Duration            `json:"geomean_ns"`
+	Median      time.Duration            `json:"median_ns"`
+	MedianCI    [2]time.Duration         `json:"median_ci_ns"`   // 95% confidence interval on the median
+	StdDev      time.Duration            `json:"std_dev_ns"`     // population standard deviation
+	Percentiles map[string]time.Duration `json:"percentiles_ns"` // by percentile, as in 97.5, see FormatPercentile
+	Outliers    struct {
+		Mild    []time.Duration `json:"mild_ns"`
+		Extreme []time.Duration `json:"extreme_ns"`
+	} `json:"outliers"`
+}
+
+// ComputeStatistics summarizes durations, nil when there is none.
+func ComputeStatistics(durations []time.Duration) *Statistics {
+	if len(durations) == 0 {
+		return nil
+	}
+	data := durationsToFloat64(durations)
+	s := &Statistics{Count: len(durations), Percentiles: make(map[string]time.Duration, len(ReportedPercentiles))}
+	min, _ := stats.Min(data)
+	max, _ := stats.Max(data)
+	mean, _ := stats.Mean(data)
+	dev, _ := stats.StandardDeviation(data)
+	geomean, _ := stats.GeometricMean(data)
+	s.Min, s.Max, s.Mean, s.Median, s.StdDev = time.Duration(min), time.Duration(max), time.Duration(mean), Median(durations), time.Duration(dev)
+	s.GeoMean, s.MedianCI = time.Duration(geomean), medianConfidenceInterval(durations)
+	for _, p := range ReportedPercentiles {
+		s.Percentiles[FormatPercentile(p)] = Percentile(durations, p)
+	}
+	outliers, _ := stats.QuartileOutliers(data)
+	s.Outliers.Mild = float64ToDurations(outliers.Mild)
+	s.Outliers.Extreme = float64ToDurations(outliers.Extreme)
+	return s
+}
+
+// Median returns the median of durations, 0 when there are none.
+func Median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	med, _ := stats.Median(durationsToFloat64(durations))
+	return time.Duration(med)
+}
+
+// Percentile returns the p-th percentile of durations, as in 95, as reported by Statistics, 0 when
+// there are none.
+func Percentile(durations []time.Duration, p float64) time.Duration {
+	r, _ := stats.Percentile(durationsToFloat64(durations), p)
+	if math.IsNaN(r) {
+		return 0
+	}
+	return time.Duration(r)
+}
+
+// FormatPercentile formats p as the keys of Statistics.Percentiles.
+func FormatPercentile(p float64) string {
+	return strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// medianConfidenceInterval returns a 95% confidence interval on the median of durations, between
+// the order statistics whose ranks come from the normal approximation of the binomial distribution,
+// rounded to the nearest rank, which assumes nothing about how boot times are distributed.
+func medianConfidenceInterval(durations []time.Duration) [2]time.Duration {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := float64(len(sorted))
+	spread := 1.96 * math.Sqrt(n) / 2
+	lower := int(math.Max(math.Round(n/2-spread), 1))
+	upper := int(math.Min(math.Round(n/2+1+spread), n))
+	return [2]time.Duration{sorted[lower-1], sorted[upper-1]}
+}
+
+func durationsToFloat64(durations []time.Duration) []float64 {
+	data := make([]float64, len(durations))
+	for i := range durations {
+		data[i] = float64(durations[i].Nanoseconds())
+	}
+	return data
+}
+
+func float64ToDurations(data []float64) []time.Duration {
+	durations := make([]time.Duration, len(data))
+	for i := range data {
+		durations[i] = time.Duration(data[i])
+	}
+	return durations
+}
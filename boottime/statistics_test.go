@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"testing"
+	"time"
+)
+
+// evenRuns returns measured runs of 110, 120, ... 200ms.
+func evenRuns() []Run {
+	var runs []Run
+	for i := 0; i < 10; i++ {
+		runs = append(runs, Run{Index: i, Duration: time.Duration(110+10*i) * time.Millisecond})
+	}
+	return runs
+}
+
+func TestComputeStatistics(t *testing.T) {
+	s := ComputeStatistics(Durations(evenRuns()))
+	for _, c := range []struct {
+		name          string
+		got, expected time.Duration
+	}{
+		{"min", s.Min, 110 * time.Millisecond},
+		{"max", s.Max, 200 * time.Millisecond},
+		{"mean", s.Mean, 155 * time.Millisecond},
+		{"median", s.Median, 155 * time.Millisecond},
+		{"stddev", s.StdDev.Round(10 * time.Microsecond), 28720 * time.Microsecond},
+		{"p95", s.Percentiles["95"], 195500 * time.Microsecond},
+		{"p100", s.Percentiles["100"], 200 * time.Millisecond},
+	} {
+		if c.got != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, c.got)
+		}
+	}
+	if ComputeStatistics(nil) != nil {
+		t.Error("expected no statistics without durations")
+	}
+}
+
+func TestMedianConfidenceInterval(t *testing.T) {
+	// The ranks of the bounds are those of the tables of the binomial distribution, as 6 and 15
+	// for 20 values.
+	for _, c := range []struct {
+		n            int
+		lower, upper int // ranks of the bounds, from 1
+	}{
+		{5, 1, 5},
+		{10, 2, 9},
+		{20, 6, 15},
+		{50, 18, 33},
+		{100, 40, 61},
+	} {
+		durations := make([]time.Duration, c.n)
+		for i := range durations {
+			durations[i] = time.Duration(c.n-i) * time.Millisecond // ranks in reverse order
+		}
+		expected := [2]time.Duration{time.Duration(c.lower) * time.Millisecond, time.Duration(c.upper) * time.Millisecond}
+		if ci := medianConfidenceInterval(durations); ci != expected {
+			t.Errorf("%d values: expected %v, got %v", c.n, expected, ci)
+		}
+	}
+}
+
+func TestGeoMean(t *testing.T) {
+	for _, c := range []struct {
+		durations []time.Duration
+		expected  time.Duration
+	}{
+		{[]time.Duration{200 * time.Millisecond}, 200 * time.Millisecond},
+		{[]time.Duration{100 * time.Millisecond, 400 * time.Millisecond}, 200 * time.Millisecond},
+		{[]time.Duration{10 * time.Millisecond, 100 * time.Millisecond, time.Second}, 100 * time.Millisecond},
+		{[]time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 8 * time.Millisecond}, 2828427 * time.Nanosecond},
+	} {
+		if geomean := ComputeStatistics(c.durations).GeoMean.Round(time.Microsecond); geomean != c.expected.Round(time.Microsecond) {
+			t.Errorf("%v: expected a geometric mean of %s, got %s", c.durations, c.expected, geomean)
+		}
+	}
+}
+
+// TestSummaryMetricsAgree checks that exporters report the statistics of the console and JSON reports.
+func TestSummaryMetricsAgree(t *testing.T) {
+	expected := map[string]string{
+		"boot_time_median_ms": "155.000",
+		"boot_time_mean_ms":   "155.000",
+		"boot_time_p95_ms":    "195.500",
+		"boot_time_min_ms":    "110.000",
+		"boot_time_max_ms":    "200.000",
+		"boot_time_stddev_ms": "28.723",
+		"boot_runs":           "10",
+		"boot_failed_runs":    "0",
+	}
+	for _, metric := range summaryMetrics(&Results{Runs: evenRuns()}) {
+		if expected[metric[0]] != metric[1] {
+			t.Errorf("%s: expected %s, got %s", metric[0], expected[metric[0]], metric[1])
+		}
+	}
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// SystemdOptions configures the systemd-scope launcher.
+type SystemdOptions struct {
+	// Properties are resource control properties of the scope, as in CPUQuota: 50% or MemoryMax: 512M.
+	Properties map[string]string `yaml:"properties"`
+	// User runs the scope in the service manager of the user rather than the system one.
+	User bool `yaml:"user"`
+}
+
+// SystemdScopePrefix starts the names of the scope units of the systemd-scope launcher, which are
+// followed by the process identifier of the benchmark and the run number, so that leftovers of an
+// interrupted benchmark can be stopped with systemctl stop 'time-to-boot-server-*.scope'.
+const SystemdScopePrefix = "time-to-boot-server-"
+
+var systemdScopes int64
+
+func init() {
+	RegisterLauncher("systemd-scope", newSystemdScopeLauncher)
+}
+
+// systemdScopeLauncher runs the server in a transient systemd scope, so that resources are
+// accounted by the cgroup of the scope, including those of the processes the server spawns, and
+// that these processes get stopped with the scope.
+//
+// systemd-run executes the server in place once the scope exists, so the process is the server.
+type systemdScopeLauncher struct {
+	*processLauncher
+	unit      string
+	user      bool
+	resources *Resources // read before the server gets killed, as the scope goes away with it
+}
+
+func newSystemdScopeLauncher(b *Benchmark) (Launcher, error) {
+	unit := fmt.Sprintf("%s%d-%d.scope", SystemdScopePrefix, os.Getpid(), atomic.AddInt64(&systemdScopes, 1))
+	args := []string{"--scope", "--quiet", "--unit=" + unit,
+		"--property=CPUAccounting=yes", "--property=MemoryAccounting=yes", "--property=IOAccounting=yes"}
+	if b.Systemd.User {
+		args = append(args, "--user")
+	}
+	names := make([]string, 0, len(b.Systemd.Properties))
+	for name := range b.Systemd.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "--property="+name+"="+b.Systemd.Properties[name])
+	}
+	args = append(append(args, "--", b.Command), b.Args...)
+	return &systemdScopeLauncher{processLauncher: &processLauncher{name: "systemd-run", args: args, env: b.Env}, unit: unit, user: b.Systemd.User}, nil
+}
+
+func (l *systemdScopeLauncher) Signal(sig os.Signal) error {
+	// The scope disappears with the server, so its resources are read before each signal that
+	// may stop it, the last reading covering the whole run.
+	if resources, err := cgroupResources(l.Pid()); err == nil {
+		l.resources = &resources
+	}
+	return l.processLauncher.Signal(sig)
+}
+
+func (l *systemdScopeLauncher) Wait() (Termination, error) {
+	termination, err := l.processLauncher.Wait()
+	if l.resources != nil {
+		termination.Resources = *l.resources
+	}
+	return termination, err
+}
+
+// Cleanup stops the scope when processes spawned by the server keep it alive.
+func (l *systemdScopeLauncher) Cleanup() error {
+	if exec.Command("systemctl", l.systemctlArgs("is-active", "--quiet", l.unit)...).Run() != nil {
+		return nil
+	}
+	if output, err := exec.Command("systemctl", l.systemctlArgs("stop", l.unit)...).CombinedOutput(); err != nil {
+		return fmt.Errorf("unable to stop %s: %v: %s", l.unit, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (l *systemdScopeLauncher) systemctlArgs(args ...string) []string {
+	if l.user {
+		return append([]string{"--user"}, args...)
+	}
+	return args
+}
+
+// cgroupResources reads the resources consumed by the cgroup (v2) of a process.
+func cgroupResources(pid int) (Resources, error) {
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return Resources{}, err
+	}
+	root := "/sys/fs/cgroup"
+	if _, err := os.Stat(filepath.Join(root, "unified")); err == nil {
+		root = filepath.Join(root, "unified") // hybrid hierarchy
+	}
+	var dir string
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			dir = filepath.Join(root, line[len("0::"):])
+		}
+	}
+	if len(dir) == 0 {
+		return Resources{}, fmt.Errorf("process %d is not in a cgroup v2 hierarchy", pid)
+	}
+	var resources Resources
+	err = scanKeyValues(filepath.Join(dir, "cpu.stat"), func(key string, value int64) {
+		switch key {
+		case "user_usec":
+			resources.UserCPU = time.Duration(value) * time.Microsecond
+		case "system_usec":
+			resources.SystemCPU = time.Duration(value) * time.Microsecond
+		}
+	})
+	if err != nil {
+		return Resources{}, err
+	}
+	if peak, err := ioutil.ReadFile(filepath.Join(dir, "memory.peak")); err == nil {
+		resources.MemoryPeak, _ = strconv.ParseInt(strings.TrimSpace(string(peak)), 10, 64)
+	}
+	ioStat, err := os.Open(filepath.Join(dir, "io.stat"))
+	if err == nil {
+		defer ioStat.Close()
+		scanner := bufio.NewScanner(ioStat)
+		for scanner.Scan() {
+			for _, field := range strings.Fields(scanner.Text())[1:] {
+				i := strings.IndexByte(field, '=')
+				if i < 0 {
+					continue
+				}
+				value, _ := strconv.ParseInt(field[i+1:], 10, 64)
+				switch field[:i] {
+				case "rbytes":
+					resources.IORead += value
+				case "wbytes":
+					resources.IOWrite += value
+				}
+			}
+		}
+	}
+	return resources, nil
+}
+
+// scanKeyValues calls fn with the lines of a file made of a key and an integer value.
+func scanKeyValues(path string, fn func(key string, value int64)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			fn(fields[0], value)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterExporter("teamcity", newTeamCityExporter)
+}
+
+// teamCityEscaper escapes the values of TeamCity service messages.
+var teamCityEscaper = strings.NewReplacer("|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]")
+
+// newTeamCityExporter writes the metrics summarizing results as TeamCity buildStatisticValue service
+// messages, to the standard output by default where TeamCity picks them up, so that builds chart
+// the boot time and can fail on it. Keys are prefixed with the scenario, if any, as in
+// api.boot_time_median_ms.
+func newTeamCityExporter(destination string) (Exporter, error) {
+	return ExporterFunc(func(results *Results) error {
+		prefix := ""
+		if len(results.Scenario) > 0 {
+			prefix = results.Scenario + "."
+		}
+		return writeTo(destination, func(w io.Writer) error {
+			for _, metric := range summaryMetrics(results) {
+				if _, err := fmt.Fprintf(w, "##teamcity[buildStatisticValue key='%s' value='%s']\n", teamCityEscaper.Replace(prefix+metric[0]), metric[1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}), nil
+}
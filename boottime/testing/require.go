@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package testing asserts boot time objectives in Go tests, as in:
+//
+//	func TestBootTime(t *testing.T) {
+//		boottesting.Require(t, scenario, boottime.MedianUnder(2*time.Second))
+//	}
+//
+// with the package imported as boottesting. It needs Go 1.24 or later, for testing.TB.Context.
+package testing
+
+import (
+	"context"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// Runs of the reduced benchmarks of Require, for benchmarks that set no runs.
+const (
+	DryRuns = 1
+	Runs    = 5
+)
+
+// TB is the part of testing.TB that Require uses.
+type TB interface {
+	Helper()
+	Context() context.Context
+	Logf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// Require runs a reduced benchmark of b inside a Go test, and fails the test when a run fails or
+// the measured runs do not meet the objectives. Benchmarks that set no runs perform DryRuns dry
+// runs and Runs measured runs, without pauses. The results are returned for further checks.
+func Require(t TB, b *boottime.Benchmark, slos ...boottime.SLO) *boottime.Results {
+	t.Helper()
+	reduced := *b
+	if reduced.Runs == 0 {
+		reduced.DryRuns, reduced.Runs, reduced.Pause = DryRuns, Runs, 0
+	}
+	results, err := reduced.Run(t.Context())
+	if err != nil {
+		t.Fatalf("boot time benchmark failed: %v", err)
+	}
+	runs := results.Measured()
+	t.Logf("boot time: median %s over %d runs", boottime.Median(boottime.Durations(runs)), len(runs))
+	for _, slo := range slos {
+		if err := slo(runs); err != nil {
+			t.Fatalf("boot time objective not met: %v", err)
+		}
+	}
+	return results
+}
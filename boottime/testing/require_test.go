@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package testing_test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+	boottesting "github.com/jponge/time-to-boot-server/boottime/testing"
+)
+
+// serverAddress is set in the environment of the test binary when it runs as the server under test.
+const serverAddress = "BOOTTIME_TEST_SERVER"
+
+func TestMain(m *testing.M) {
+	if address := os.Getenv(serverAddress); len(address) > 0 {
+		serve(address)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// serve listens on address after a short boot, until killed.
+func serve(address string) {
+	time.Sleep(20 * time.Millisecond)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			os.Exit(1)
+		}
+		conn.Close()
+	}
+}
+
+// scenario boots the test binary as a server listening on a free port.
+func scenario(t *testing.T) *boottime.Benchmark {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return &boottime.Benchmark{
+		Mode:       "tcp-connect",
+		Target:     address,
+		Command:    os.Args[0],
+		Env:        []string{serverAddress + "=" + address},
+		RunTimeout: 10 * time.Second,
+	}
+}
+
+func TestRequire(t *testing.T) {
+	results := boottesting.Require(t, scenario(t), boottime.MedianUnder(5*time.Second), boottime.MaxUnder(10*time.Second))
+	if runs := len(results.Measured()); runs != boottesting.Runs {
+		t.Errorf("expected %d measured runs, got %d", boottesting.Runs, runs)
+	}
+	for _, run := range results.Measured() {
+		if run.Duration < 20*time.Millisecond {
+			t.Errorf("run %d took %s, less than the boot of the server", run.Index, run.Duration)
+		}
+	}
+}
+
+// recorder records the failures of Require instead of failing the test.
+type recorder struct {
+	*testing.T
+	failures []string
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestRequireObjectiveNotMet(t *testing.T) {
+	b := scenario(t)
+	b.DryRuns, b.Runs = 0, 2
+	r := &recorder{T: t}
+	boottesting.Require(r, b, boottime.MedianUnder(time.Millisecond))
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], "median boot time") {
+		t.Errorf("expected the median objective to fail the test, got %q", r.failures)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Hot upgrade phases, relative to the moment the process was spawned.
+const (
+	UpgradeSignalledPhase = "upgrade-signalled" // the upgrade signal has been sent
+	UpgradedPhase         = "upgraded"          // a new generation of the server is ready
+)
+
+// parseSignal parses a signal name, as in USR2 or SIGUSR2, among the signals of the platform.
+func parseSignal(name string) (syscall.Signal, error) {
+	sig, found := signals[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !found {
+		return 0, fmt.Errorf("unknown signal: %s", name)
+	}
+	return sig, nil
+}
+
+// upgrade signals the ready server to upgrade in place, and waits for a new generation to serve.
+func (s *session) upgrade(ctx context.Context, spec runSpec, run *Run, launcher Launcher, start time.Time, interval time.Duration) error {
+	sig, err := parseSignal(s.UpgradeSignal)
+	if err != nil {
+		return err
+	}
+	previous := run.ReadyProbe.Generation
+	if len(previous) == 0 {
+		return errors.New("the probe reported no server generation to detect the upgrade, see the generation options of the mode")
+	}
+	signalled := time.Since(start)
+	run.mark(UpgradeSignalledPhase, signalled)
+	if err := launcher.Signal(sig); err != nil {
+		return categorize(CrashCategory, fmt.Errorf("unable to signal the upgrade: %v", err))
+	}
+	result, readyAt, err := s.await(ctx, spec, run, start, interval, func(result ProbeResult) bool {
+		return result.Generation != previous
+	})
+	if err != nil {
+		return err
+	}
+	upgraded := readyAt.Sub(start)
+	run.mark(UpgradedPhase, upgraded)
+	run.Duration = upgraded - signalled
+	run.ReadyProbe = result
+	s.Logger.Debug("server upgraded", "from", previous, "to", result.Generation, "duration", run.Duration)
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package boottime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// watchedLauncher waits for the server in the background from the moment it started, so that runs
+// notice when it exits before they complete. Wait returns what the background wait got.
+type watchedLauncher struct {
+	Launcher
+	exited      chan struct{}
+	termination Termination
+	err         error
+}
+
+// watch waits for the started server of launcher in the background, calling onExit when it exits
+// if onExit is not nil.
+func watch(launcher Launcher, onExit func(termination Termination)) *watchedLauncher {
+	l := &watchedLauncher{Launcher: launcher, exited: make(chan struct{})}
+	go func() {
+		l.termination, l.err = l.Launcher.Wait()
+		close(l.exited)
+		if onExit != nil {
+			onExit(l.termination)
+		}
+	}()
+	return l
+}
+
+func (l *watchedLauncher) Wait() (Termination, error) {
+	<-l.exited
+	return l.termination, l.err
+}
+
+// hasExited tells whether the server already exited.
+func (l *watchedLauncher) hasExited() bool {
+	select {
+	case <-l.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// watchServer replaces the started launcher with one watching its server, and returns a context
+// derived from ctx for probing the readiness of the server, canceled with an *exitError when the
+// server exits. The servers of remote launchers are not watched for early exits, as their local
+// process is only a command driving them.
+func (s *session) watchServer(ctx context.Context, launcher *Launcher) (context.Context, context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	var onExit func(Termination)
+	if !remoteLaunchers[s.Launcher] {
+		onExit = func(termination Termination) {
+			cancel(&exitError{exit: termination.Exit})
+		}
+	}
+	*launcher = watch(*launcher, onExit)
+	return ctx, cancel
+}
+
+// exitError reports a server that exited before the run completed.
+type exitError struct {
+	exit *ExitStatus // nil when unknown
+}
+
+func (e *exitError) Error() string {
+	switch {
+	case e.exit == nil:
+		return "the server exited before being ready"
+	case len(e.exit.Signal) > 0:
+		return fmt.Sprintf("the server was terminated by signal %s before being ready", e.exit.Signal)
+	}
+	return fmt.Sprintf("the server exited with code %d before being ready", e.exit.Code)
+}
+
+// logExit logs the last lines of the standard error of a server that exited early.
+func (s *session) logExit(err error, stderr []string) {
+	if len(stderr) == 0 {
+		s.Logger.Warn(err.Error())
+		return
+	}
+	s.Logger.Warn(err.Error(), "stderr", strings.Join(stderr, "\n"))
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+func defaultHostProfilePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "time-to-boot-server-host-profile.json"
+	}
+	return filepath.Join(home, ".time-to-boot-server", "host-profile.json")
+}
+
+// calibrateHost measures the profile of the host, prints it and stores it at path, where
+// benchmarks pick it up to embed it in their results.
+func calibrateHost(ctx context.Context, w io.Writer, path, dir, styleName string) error {
+	style, err := tableStyleFor(styleName)
+	if err != nil {
+		return err
+	}
+	profile, err := boottime.CalibrateHost(ctx, dir)
+	if err != nil {
+		return err
+	}
+	table := newTable("Host profile of "+profile.Host.Name, column{"Measure", alignLeft}, column{"Value", alignRight})
+	table.addRow("CPU score (thousand SHA-256 digests/s)", strconv.FormatFloat(profile.Host.CPUScore, 'f', 1, 64))
+	table.addRow("Process spawn latency (µs)", formatMicros(profile.SpawnLatency))
+	table.addRow("TCP loopback round trip (µs)", formatMicros(profile.LoopbackRTT))
+	disk := strconv.FormatFloat(profile.DiskRead/(1<<20), 'f', 1, 64)
+	if profile.DiskReadCached {
+		disk += " (page cache)"
+	}
+	table.addRow("Disk read throughput (MiB/s)", disk)
+	table.addRow("Timer resolution (µs)", formatMicros(profile.TimerResolution))
+	table.addRow("Sleep overshoot of 1ms (µs)", formatMicros(profile.SleepOvershoot))
+	table.render(w, style)
+	if err := boottime.SaveHostProfile(path, profile); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Saved to %s, and embedded in the results of the next benchmarks\n", path)
+	return nil
+}
+
+// formatMicros renders the short durations of host profiles as microseconds with 3 decimals.
+func formatMicros(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Microsecond), 'f', 3, 64)
+}
+
+// loadHostProfile returns the host profile at path to embed in results, or nil when there is
+// none or when it is stale.
+func loadHostProfile(path string) *boottime.HostProfile {
+	profile, err := boottime.LoadHostProfile(path)
+	if err != nil {
+		logger.Warn("ignoring the host profile", "path", path, "error", err)
+		return nil
+	}
+	return profile
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// candidateBenchmarks parses --candidate values given as 'name=command arguments...' into copies of
+// the benchmark b running those commands.
+func candidateBenchmarks(b *boottime.Benchmark, specs []string) ([]*boottime.Benchmark, error) {
+	if len(specs) < 2 {
+		return nil, fmt.Errorf("comparing candidates takes at least 2 of them, got %d", len(specs))
+	}
+	benchmarks := make([]*boottime.Benchmark, 0, len(specs))
+	seen := map[string]bool{}
+	for _, spec := range specs {
+		i := strings.IndexByte(spec, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid candidate: %q (expected name=command)", spec)
+		}
+		name, command := strings.TrimSpace(spec[:i]), strings.Fields(spec[i+1:])
+		if len(command) == 0 {
+			return nil, fmt.Errorf("candidate %s has no command", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate candidate: %s", name)
+		}
+		seen[name] = true
+		candidate := *b
+		candidate.Name, candidate.Command, candidate.Args = name, command[0], command[1:]
+		benchmarks = append(benchmarks, &candidate)
+	}
+	return benchmarks, nil
+}
+
+// runCandidates runs the benchmarks of candidates with their runs interleaved, see
+// boottime.RunInterleaved, then exports the results of each of them like runBenchmark and, when
+// w is not nil, prints them side by side.
+func runCandidates(ctx context.Context, benchmarks []*boottime.Benchmark, exportSpecs []string, recordPath string, journalPath string, out *resultsOutput, gate *regressionGate, w io.Writer, style tableStyle) error {
+	outputs := make([]*benchmarkOutputs, len(benchmarks))
+	names := make([]string, len(benchmarks))
+	for i, bench := range benchmarks {
+		var err error
+		if outputs[i], err = newBenchmarkOutputs(bench, exportSpecs, recordPath, out, gate); err != nil {
+			return err
+		}
+		name := bench.Name
+		if bench.OnRun != nil { // unless --summary-only
+			bench.OnRun = func(run boottime.Run) { printCandidateRun(name, run) }
+		}
+		names[i] = name
+	}
+	color.New(color.FgMagenta, color.Bold).Printf("Candidates %s, with interleaved runs\n", strings.Join(names, ", "))
+	results, errs := boottime.RunInterleaved(ctx, benchmarks)
+	var err error
+	for i, bench := range benchmarks {
+		if len(exportSpecs) > 0 || out == nil {
+			printScenario(bench.Name)
+		}
+		benchErr := outputs[i].complete(bench, results[i], errs[i], journalPath, out)
+		if expectedFailure(ctx, bench, benchErr) {
+			continue
+		}
+		if benchErr != nil {
+			benchErr = fmt.Errorf("candidate %s: %v", bench.Name, benchErr)
+			if err == nil {
+				err = benchErr
+			} else {
+				logger.Error("benchmark failed", "error", benchErr)
+			}
+		}
+	}
+	if w != nil {
+		renderCandidates(w, style, results)
+	}
+	return err
+}
+
+// printCandidateRun prints a run of a candidate as it completes.
+func printCandidateRun(name string, run boottime.Run) {
+	if run.Dry {
+		color.Cyan("  - %s%s (dry): %s", stamp(run.StartedAt), name, run.Duration)
+		return
+	}
+	color.Green("  - %s%s: %s", stamp(run.StartedAt), name, run.Duration)
+}
+
+// renderCandidates prints the statistics of candidates side by side, with their speedup relative
+// to the first candidate with measured runs and the significance of the difference, see
+// mannWhitney.
+func renderCandidates(w io.Writer, style tableStyle, results []*boottime.Results) {
+	var baseline []time.Duration
+	table := newTable("Candidates", column{"Candidate", alignLeft}, column{"Runs", alignRight},
+		column{"Min (ms)", alignRight}, column{"Median (ms)", alignRight}, column{"p90 (ms)", alignRight},
+		column{"p95 (ms)", alignRight}, column{"Max (ms)", alignRight}, column{"Speedup", alignRight},
+		column{"p-value", alignRight}, column{"Effect", alignLeft})
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		durations := boottime.Durations(r.Measured())
+		statistics := boottime.ComputeStatistics(durations)
+		if statistics == nil {
+			table.addRow(r.Scenario, "0", "", "", "", "", "", "", "", "")
+			continue
+		}
+		pValue, effect := "", ""
+		if baseline == nil {
+			baseline = durations
+		} else {
+			s := mannWhitney(baseline, durations)
+			pValue, effect = formatPValue(s.p), formatEffect(s)
+		}
+		table.addRow(r.Scenario, fmt.Sprint(statistics.Count),
+			formatMillis(statistics.Min), formatMillis(statistics.Median),
+			formatMillis(statistics.Percentiles["90"]), formatMillis(statistics.Percentiles["95"]),
+			formatMillis(statistics.Max), formatRatio(boottime.Median(baseline), statistics.Median), pValue, effect)
+	}
+	table.render(w, style)
+}
@@ -0,0 +1,291 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// verdictThresholds tell how much faster or slower than its baseline, in percents of the baseline
+// median, results must be to count as improved or regressed.
+type verdictThresholds struct {
+	improvement float64
+	regression  float64
+	alpha       float64 // significance level of the differences
+	significant bool    // whether differences must also be significant, see mannWhitney
+}
+
+// Verdicts of the compare command.
+const (
+	improvedVerdict  = "improved"
+	unchangedVerdict = "unchanged"
+	regressedVerdict = "regressed"
+)
+
+// verdict classifies a median against the median of its baseline, given the significance of the
+// difference between their durations.
+func (t verdictThresholds) verdict(median, baseline time.Duration, s significance) (string, float64) {
+	delta := 100 * float64(median-baseline) / float64(baseline)
+	switch {
+	case t.significant && s.p >= t.alpha:
+		return unchangedVerdict, delta
+	case delta <= -t.improvement:
+		return improvedVerdict, delta
+	case delta >= t.regression:
+		return regressedVerdict, delta
+	}
+	return unchangedVerdict, delta
+}
+
+// comparedResults are results read by the compare command.
+type comparedResults struct {
+	label     string
+	results   *boottime.Results
+	durations []time.Duration // sorted, and normalized to the first host with --normalize
+	factor    float64         // the normalization factor of the durations, 1 when not normalized
+	host      *boottime.Host  // with the CPU score of the normalization
+}
+
+// scoredHost returns the host of results with its CPU score, measured with --cpu-score or else by
+// the calibrate command, or nil when it has none.
+func scoredHost(results *boottime.Results) *boottime.Host {
+	if results.Host != nil && results.Host.CPUScore > 0 {
+		return results.Host
+	}
+	if results.HostProfile != nil && results.HostProfile.Host.CPUScore > 0 {
+		return &results.HostProfile.Host
+	}
+	return nil
+}
+
+// normalize scales the durations of results measured on different hosts by the ratio of the CPU
+// score of their host to the score of the host of the first results, as if they all ran on the
+// first host. Every results must have a CPU score, see scoredHost.
+func normalize(compared []comparedResults, paths []string) error {
+	var missing []string
+	for i := range compared {
+		if compared[i].host = scoredHost(compared[i].results); compared[i].host == nil {
+			missing = append(missing, paths[i])
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the results %s have no CPU score, measure it with --cpu-score or calibrate", strings.Join(missing, ", "))
+	}
+	reference := compared[0].host
+	for i := range compared {
+		host := compared[i].host
+		if host.CPUScoreWorkload != reference.CPUScoreWorkload {
+			return fmt.Errorf("the CPU scores of %s and %s were measured with different workloads", paths[0], paths[i])
+		}
+		// A host scoring higher is faster, so its durations would be longer on the first host.
+		factor := host.CPUScore / reference.CPUScore
+		scaled := make([]time.Duration, len(compared[i].durations))
+		for j, d := range compared[i].durations {
+			scaled[j] = time.Duration(float64(d) * factor)
+		}
+		compared[i].durations, compared[i].factor = scaled, factor
+	}
+	return nil
+}
+
+// compareResults prints results exported with the json exporter side by side, relative to the
+// first ones, then the verdict of each results against its baseline: the first results of the same
+// scenario, as when comparing before/*.json with after/*.json, or else the first results. Results
+// are also compared against a reference dataset when reference is not empty.
+//
+// With normalized, the results are compared as if they ran on the host of the first results, see
+// normalize, while the comparison against the reference dataset keeps the measured durations.
+func compareResults(w io.Writer, paths []string, reference string, normalized bool, thresholds verdictThresholds, styleName string) error {
+	style, err := tableStyleFor(styleName)
+	if err != nil {
+		return err
+	}
+	compared := make([]comparedResults, len(paths))
+	for i, path := range paths {
+		results, err := boottime.ReadResults(path)
+		if err != nil {
+			return err
+		}
+		durations := boottime.Durations(results.Measured())
+		if len(durations) == 0 {
+			return fmt.Errorf("the results %s have no measured run", path)
+		}
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		label := results.Scenario
+		if len(label) == 0 {
+			label = filepath.Base(path)
+		}
+		compared[i] = comparedResults{label: label, results: results, durations: durations, factor: 1}
+	}
+	// Results of the same scenario are told apart by their path.
+	scenarios := map[string]int{}
+	for _, c := range compared {
+		scenarios[c.label]++
+	}
+	for i := range compared {
+		if scenarios[compared[i].label] > 1 {
+			compared[i].label += " (" + paths[i] + ")"
+		}
+	}
+
+	if len(compared) > 1 {
+		measured := make([][]time.Duration, len(compared))
+		for i, c := range compared {
+			measured[i] = c.durations
+		}
+		if normalized {
+			if err := normalize(compared, paths); err != nil {
+				return err
+			}
+		}
+		baseline := boottime.Median(compared[0].durations)
+		title := "Comparison with " + compared[0].label
+		columns := []column{{"Results", alignLeft}, {"Runs", alignRight},
+			{"Min (ms)", alignRight}, {"Median (ms)", alignRight}, {"Max (ms)", alignRight},
+			{"Relative", alignRight}}
+		if normalized {
+			title += ", normalized to the host " + compared[0].host.Name
+			columns = append(columns, column{"Host", alignLeft}, column{"CPU score", alignRight}, column{"Factor", alignRight})
+		}
+		table := newTable(title, columns...)
+		for _, c := range compared {
+			cells := []string{c.label, strconv.Itoa(len(c.durations)),
+				formatMillis(c.durations[0]), formatMillis(boottime.Median(c.durations)), formatMillis(c.durations[len(c.durations)-1]),
+				formatRatio(boottime.Median(c.durations), baseline)}
+			if normalized {
+				cells = append(cells, c.host.Name, strconv.FormatFloat(c.host.CPUScore, 'f', 1, 64),
+					strconv.FormatFloat(c.factor, 'f', 3, 64)+"x")
+			}
+			table.addRow(cells...)
+		}
+		table.render(w, style)
+		renderVerdicts(w, compared, thresholds, style)
+		for i := range compared {
+			compared[i].durations = measured[i] // the reference dataset compares measured durations
+		}
+	}
+
+	if len(reference) == 0 {
+		return nil
+	}
+	dataset, err := boottime.LoadReferenceDataset(reference)
+	if err != nil {
+		return err
+	}
+	title := "Against " + dataset.Name
+	if len(dataset.Source) > 0 {
+		title += " (" + dataset.Source + ")"
+	}
+	for _, c := range compared {
+		type row struct {
+			name   string
+			median time.Duration
+		}
+		yours := boottime.Median(c.durations)
+		rows := []row{{c.label + " (yours)", yours}}
+		for _, entry := range dataset.Entries {
+			rows = append(rows, row{entry.Name, entry.MedianDuration()})
+		}
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].median < rows[j].median })
+		table := newTable(title, column{"Name", alignLeft}, column{"Median (ms)", alignRight}, column{"Relative to yours", alignRight})
+		for _, r := range rows {
+			table.addRow(r.name, formatMillis(r.median), formatRatio(r.median, yours))
+		}
+		table.render(w, style)
+
+		caveats := newTable("Caveats for "+c.label, column{"Caveat", alignLeft})
+		caveats.addRow("reference boot times were measured elsewhere, only orders of magnitude compare")
+		for _, mismatch := range dataset.Mismatches(c.results) {
+			caveats.addRow(mismatch)
+		}
+		caveats.render(w, style)
+	}
+	return nil
+}
+
+// renderVerdicts prints the verdict of each compared results against its baseline, see
+// compareResults.
+func renderVerdicts(w io.Writer, compared []comparedResults, thresholds verdictThresholds, style tableStyle) {
+	title := fmt.Sprintf("Verdicts (improved by %s%% or more, regressed by %s%% or more",
+		strconv.FormatFloat(thresholds.improvement, 'f', -1, 64), strconv.FormatFloat(thresholds.regression, 'f', -1, 64))
+	if thresholds.significant {
+		title += fmt.Sprintf(", with p < %s", strconv.FormatFloat(thresholds.alpha, 'f', -1, 64))
+	}
+	table := newTable(title+")",
+		column{"Results", alignLeft}, column{"Baseline", alignLeft},
+		column{"Median (ms)", alignRight}, column{"Delta (ms)", alignRight}, column{"Delta", alignRight},
+		column{"p-value", alignRight}, column{"Effect", alignLeft}, column{"Verdict", alignLeft})
+	paints := map[string]func(...interface{}) string{
+		improvedVerdict:  color.New(color.FgGreen, color.Bold).SprintFunc(),
+		unchangedVerdict: fmt.Sprint,
+		regressedVerdict: color.New(color.FgRed, color.Bold).SprintFunc(),
+	}
+	arrows := map[string]string{improvedVerdict: "▼", unchangedVerdict: "=", regressedVerdict: "▲"}
+	scenarios := map[string]int{}
+	for _, c := range compared {
+		scenarios[c.results.Scenario]++
+	}
+	counts := map[string]int{}
+	for i, c := range compared {
+		baseline, found := compared[0], false
+		for _, b := range compared[:i] {
+			if b.results.Scenario == c.results.Scenario {
+				baseline, found = b, true
+				break
+			}
+		}
+		if i == 0 || (!found && scenarios[c.results.Scenario] > 1) {
+			continue // a baseline
+		}
+		current, reference := boottime.Median(c.durations), boottime.Median(baseline.durations)
+		s := mannWhitney(baseline.durations, c.durations)
+		verdict, delta := thresholds.verdict(current, reference, s)
+		counts[verdict]++
+		sign := ""
+		if current > reference {
+			sign = "+"
+		}
+		table.addRow(c.label, baseline.label, formatMillis(current), sign+formatMillis(current-reference),
+			fmt.Sprintf("%s %+.1f%%", arrows[verdict], delta), formatPValue(s.p), formatEffect(s), verdict)
+		for column := 3; column <= 5; column++ {
+			table.paint(column, paints[verdict])
+		}
+	}
+	table.render(w, style)
+	fmt.Fprintf(w, "%d improved, %d unchanged, %d regressed\n",
+		counts[improvedVerdict], counts[unchangedVerdict], counts[regressedVerdict])
+}
+
+// formatRatio formats d relative to reference, as in 1.25x.
+func formatRatio(d, reference time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(reference), 'f', 2, 64) + "x"
+}
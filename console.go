@@ -0,0 +1,711 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jponge/time-to-boot-server/boottime"
+	"github.com/montanaflynn/stats"
+)
+
+func printScenario(name string) {
+	color.New(color.FgMagenta, color.Bold).Printf("%sScenario %s\n", stamp(time.Now()), name)
+}
+
+// isTerminal tells whether a file is a terminal.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// promptMisconfiguration explains a likely misconfiguration on the standard error, and asks
+// whether to carry on.
+func promptMisconfiguration(m boottime.Misconfiguration) bool {
+	warning := color.New(color.FgYellow, color.Bold)
+	warning.Fprintf(os.Stderr, "The first run looks wrong: %s.\n", m.Symptom)
+	fmt.Fprintln(os.Stderr, "Likely causes:")
+	for _, cause := range m.Causes {
+		fmt.Fprintf(os.Stderr, "  - %s\n", cause)
+	}
+	fmt.Fprintln(os.Stderr, "Suggestions:")
+	for _, suggestion := range m.Suggestions {
+		fmt.Fprintf(os.Stderr, "  - %s\n", suggestion)
+	}
+	warning.Fprint(os.Stderr, "Carry on with the benchmark? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func printRun(run boottime.Run) {
+	if run.Dry {
+		if run.Index == 0 {
+			color.Cyan("Dry runs")
+		}
+		color.Cyan("  - %s%s", stamp(run.StartedAt), run.Duration)
+		return
+	}
+	if run.Index == 0 {
+		color.Green("Runs")
+	}
+	color.Green("  - %s%s", stamp(run.StartedAt), run.Duration)
+}
+
+// Console progress settings, see --summary-only and --show-attempts, and timestamps, which start
+// progress lines with their time in non-interactive sessions.
+var (
+	summaryOnly  bool
+	showAttempts bool
+	timestamps   bool
+)
+
+// stamp returns the prefix of the progress line of an event that happened at t.
+func stamp(t time.Time) string {
+	if !timestamps {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04:05.000 ")
+}
+
+// interactiveSession tells whether the console is interactive, from the --interactive setting:
+// auto when empty, as when the standard output is a terminal, yes or no.
+func interactiveSession(setting string) (bool, error) {
+	switch setting {
+	case "", "auto":
+		return isTerminal(os.Stdout), nil
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid --interactive setting: %s (expected auto, yes or no)", setting)
+}
+
+// printProgress makes a benchmark print its runs as they complete, unless summaryOnly, and its
+// probe attempts when showAttempts.
+func printProgress(bench *boottime.Benchmark) {
+	if !summaryOnly {
+		bench.OnRun = printRun
+	}
+	if showAttempts {
+		bench.OnAttempt = printAttempt
+	}
+}
+
+// printAttempt prints a probe attempt, with its offset since the process was spawned and its
+// outcome, to debug the readiness detection.
+func printAttempt(attempt boottime.Attempt) {
+	run := fmt.Sprintf("run %d", attempt.Run+1)
+	if attempt.Dry {
+		run = "dry " + run
+	}
+	outcome := "ready"
+	if attempt.Err != nil {
+		outcome = boottime.Redact(attempt.Err.Error())
+	}
+	color.New(color.Faint).Printf("    %s%s, attempt %d at %s, took %s: %s\n", stamp(time.Now()), run, attempt.Number, attempt.Offset, attempt.Latency, outcome)
+}
+
+// consoleExporter renders statistics tables to the standard output, in the style chosen with --style.
+func consoleExporter(style *string) boottime.ExporterFactory {
+	return func(destination string) (boottime.Exporter, error) {
+		if len(destination) > 0 {
+			return nil, errors.New("the console exporter only writes to the standard output")
+		}
+		tableStyle, err := tableStyleFor(*style)
+		if err != nil {
+			return nil, err
+		}
+		var w io.Writer = os.Stdout
+		return boottime.ExporterFunc(func(results *boottime.Results) error {
+			if len(results.Measured()) > 0 {
+				report(w, tableStyle, results)
+				if tableStyle.terminal() {
+					fmt.Fprintln(w, sparkline(results.Runs))
+				}
+			}
+			return nil
+		}), nil
+	}
+}
+
+func durationsToFloat64(durations []time.Duration) []float64 {
+	data := make([]float64, len(durations))
+	for i := range durations {
+		data[i] = float64(durations[i].Nanoseconds())
+	}
+	return data
+}
+
+// summaryStatistics are the rows of the statistics table of the console report, see --stats.
+var summaryStatistics = []string{"min", "max", "median", "stddev"}
+
+// parseSummaryStatistics parses the comma-separated statistics of --stats.
+func parseSummaryStatistics(spec string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(spec, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "min", "max", "mean", "geomean", "median", "median-ci", "stddev":
+			names = append(names, name)
+		default:
+			return nil, fmt.Errorf("unknown statistic: %s (expected min, max, mean, geomean, median, median-ci or stddev)", name)
+		}
+	}
+	return names, nil
+}
+
+func report(w io.Writer, style tableStyle, results *boottime.Results) {
+	if len(results.Description) > 0 || len(results.Hypothesis) > 0 || results.ExpectedFail {
+		table := newTable("Scenario", column{"Field", alignLeft}, column{"Value", alignLeft})
+		if len(results.Description) > 0 {
+			table.addRow("Description", results.Description)
+		}
+		if len(results.Hypothesis) > 0 {
+			table.addRow("Hypothesis", results.Hypothesis)
+		}
+		if results.ExpectedFail {
+			table.addRow("Expected to fail", "yes, failures do not fail the session")
+		}
+		table.render(w, style)
+	}
+
+	statistics := boottime.ComputeStatistics(boottime.Durations(results.Measured()))
+	summary := newTable("Statistics", column{"Statistic", alignLeft}, column{"Time (ms)", alignRight})
+	for _, name := range summaryStatistics {
+		switch name {
+		case "min":
+			summary.addRow("Min", formatMillis(statistics.Min))
+		case "max":
+			summary.addRow("Max", formatMillis(statistics.Max))
+		case "mean":
+			summary.addRow("Mean", formatMillis(statistics.Mean))
+		case "geomean":
+			summary.addRow("Geometric mean", formatMillis(statistics.GeoMean))
+		case "median":
+			summary.addRow("Median", formatMillis(statistics.Median))
+		case "median-ci":
+			summary.addRow("Median 95% CI", formatMillis(statistics.MedianCI[0])+" - "+formatMillis(statistics.MedianCI[1]))
+		case "stddev":
+			summary.addRow("Std dev", formatMillis(statistics.StdDev))
+		}
+	}
+	var resolutions []time.Duration
+	for _, run := range results.Measured() {
+		if run.ProbeResolution > 0 {
+			resolutions = append(resolutions, run.ProbeResolution)
+		}
+	}
+	if len(resolutions) > 0 {
+		summary.addRow("Probe resolution (median)", formatMillis(boottime.Median(resolutions)))
+	}
+	for _, d := range statistics.Outliers.Mild {
+		summary.addRow("Outlier (mild)", formatMillis(d))
+	}
+	for _, d := range statistics.Outliers.Extreme {
+		summary.addRow("Outlier (extreme)", formatMillis(d))
+	}
+	summary.render(w, style)
+
+	table := newTable("Percentiles", column{"Percentile", alignRight}, column{"Time (ms)", alignRight})
+	for _, p := range boottime.ReportedPercentiles {
+		table.addRow(boottime.FormatPercentile(p)+"%", formatMillis(statistics.Percentiles[boottime.FormatPercentile(p)]))
+	}
+	table.render(w, style)
+
+	// HTML reports plot the histogram instead.
+	if histogram := histogramTable(boottime.Durations(results.Measured()), histogramBuckets, style); histogram != nil && style != htmlStyle {
+		histogram.render(w, style)
+	}
+
+	if recovery := boottime.ComputeStatistics(recoveryDurations(results.Measured())); recovery != nil {
+		table := newTable("Crash recovery", column{"Statistic", alignLeft}, column{"Clean start (ms)", alignRight}, column{"Recovery (ms)", alignRight})
+		table.addRow("Min", formatMillis(statistics.Min), formatMillis(recovery.Min))
+		table.addRow("Max", formatMillis(statistics.Max), formatMillis(recovery.Max))
+		table.addRow("Median", formatMillis(statistics.Median), formatMillis(recovery.Median))
+		table.addRow("Std dev", formatMillis(statistics.StdDev), formatMillis(recovery.StdDev))
+		table.render(w, style)
+	}
+
+	if phases := phaseMedians(results.Measured()); len(phases) > 0 {
+		table := newTable("Phases", column{"Phase", alignLeft}, column{"Median (ms)", alignRight})
+		for _, phase := range phases {
+			table.addRow(phase.Name, formatMillis(phase.Offset))
+		}
+		table.render(w, style)
+	}
+
+	if stages := stageDurations(results.Measured()); len(stages) > 0 {
+		table := newTable("Stages", column{"Stage", alignLeft}, column{"Min (ms)", alignRight}, column{"Median (ms)", alignRight}, column{"Max (ms)", alignRight}, column{"Std dev (ms)", alignRight})
+		for _, stage := range stages {
+			statistics := boottime.ComputeStatistics(stage.durations)
+			table.addRow(stage.name, formatMillis(statistics.Min), formatMillis(statistics.Median), formatMillis(statistics.Max), formatMillis(statistics.StdDev))
+		}
+		table.render(w, style)
+	}
+
+	if orders := portOrders(results.Measured()); len(orders) > 0 {
+		table := newTable("Port order", column{"Ports by opening order", alignLeft}, column{"Runs", alignRight})
+		for _, order := range orders {
+			table.addRow(order.ports, strconv.Itoa(order.runs))
+		}
+		table.render(w, style)
+	}
+
+	if lingering := lingeringRuns(results.Measured()); len(lingering) > 0 {
+		table := newTable("Runs started with lingering sockets", column{"Run", alignRight}, column{"Sockets", alignLeft})
+		for _, run := range lingering {
+			table.addRow(strconv.Itoa(run.Index+1), formatSocketStates(run.LingeringSockets))
+		}
+		table.render(w, style)
+	}
+
+	flapping := newTable("Runs that flapped before being stable", column{"Run", alignRight}, column{"Flaps", alignRight})
+	for _, run := range results.Measured() {
+		if run.Flaps > 0 {
+			flapping.addRow(strconv.Itoa(run.Index+1), strconv.Itoa(run.Flaps))
+		}
+	}
+	if len(flapping.rows) > 0 {
+		flapping.render(w, style)
+	}
+
+	if scheduling := schedulings(results.Measured()); len(scheduling) > 0 {
+		running, waiting, shares := make([]time.Duration, len(scheduling)), make([]time.Duration, len(scheduling)), make([]float64, len(scheduling))
+		for i, sched := range scheduling {
+			running[i], waiting[i] = sched.Running, sched.Waiting
+			if total := sched.Running + sched.Waiting; total > 0 {
+				shares[i] = 100 * float64(sched.Waiting) / float64(total)
+			}
+		}
+		medShare, _ := stats.Median(shares)
+		table := newTable("Scheduling at readiness", column{"Metric", alignLeft}, column{"Median", alignRight})
+		table.addRow("Running (ms)", formatMillis(boottime.Median(running)))
+		table.addRow("Waiting for a CPU (ms)", formatMillis(boottime.Median(waiting)))
+		table.addRow("Waiting share (%)", strconv.FormatFloat(medShare, 'f', 1, 64))
+		table.render(w, style)
+	}
+
+	if ready, peak := memoryStatistics(results.Measured()); ready != nil {
+		columns := []column{{"Statistic", alignLeft}, {"RSS (MiB)", alignRight}}
+		if peak != nil {
+			columns = append(columns, column{"Peak RSS (MiB)", alignRight})
+		}
+		table := newTable("Memory at readiness", columns...)
+		rows := []struct {
+			name  string
+			value func(s *byteStatistics) int64
+		}{
+			{"Min", func(s *byteStatistics) int64 { return s.Min }},
+			{"Max", func(s *byteStatistics) int64 { return s.Max }},
+			{"Median", func(s *byteStatistics) int64 { return s.Median }},
+			{"Std dev", func(s *byteStatistics) int64 { return s.StdDev }},
+		}
+		for _, row := range rows {
+			cells := []string{row.name, formatMiB(row.value(ready))}
+			if peak != nil {
+				cells = append(cells, formatMiB(row.value(peak)))
+			}
+			table.addRow(cells...)
+		}
+		table.render(w, style)
+	}
+
+	if user, system, total, cores := cpuTimes(results.Measured()); len(total) > 0 {
+		table := newTable("CPU until readiness", column{"Statistic", alignLeft}, column{"User (ms)", alignRight},
+			column{"System (ms)", alignRight}, column{"Total (ms)", alignRight}, column{"Cores", alignRight})
+		userStats, systemStats, totalStats := boottime.ComputeStatistics(user), boottime.ComputeStatistics(system), boottime.ComputeStatistics(total)
+		minCores, _ := stats.Min(cores)
+		maxCores, _ := stats.Max(cores)
+		medCores, _ := stats.Median(cores)
+		table.addRow("Min", formatMillis(userStats.Min), formatMillis(systemStats.Min), formatMillis(totalStats.Min), strconv.FormatFloat(minCores, 'f', 2, 64))
+		table.addRow("Max", formatMillis(userStats.Max), formatMillis(systemStats.Max), formatMillis(totalStats.Max), strconv.FormatFloat(maxCores, 'f', 2, 64))
+		table.addRow("Median", formatMillis(userStats.Median), formatMillis(systemStats.Median), formatMillis(totalStats.Median), strconv.FormatFloat(medCores, 'f', 2, 64))
+		table.render(w, style)
+	}
+
+	if jvm := jvmMetrics(results.Measured()); len(jvm) > 0 {
+		classes, jit, pauses, gc := make([]float64, len(jvm)), make([]time.Duration, len(jvm)), make([]float64, len(jvm)), make([]time.Duration, len(jvm))
+		for i, metrics := range jvm {
+			classes[i], jit[i], pauses[i], gc[i] = float64(metrics.LoadedClasses), metrics.JITTime, float64(metrics.GCPauses), metrics.GCTime
+		}
+		medClasses, _ := stats.Median(classes)
+		medPauses, _ := stats.Median(pauses)
+		table := newTable("JVM at readiness", column{"Metric", alignLeft}, column{"Median", alignRight})
+		table.addRow("Loaded classes", strconv.FormatFloat(medClasses, 'f', -1, 64))
+		table.addRow("JIT time (ms)", formatMillis(boottime.Median(jit)))
+		table.addRow("GC pauses", strconv.FormatFloat(medPauses, 'f', -1, 64))
+		table.addRow("GC time (ms)", formatMillis(boottime.Median(gc)))
+		table.render(w, style)
+	}
+
+	if table := environmentTable(results.Measured()); table != nil {
+		table.render(w, style)
+	}
+
+	targets := map[string]int{}
+	for _, run := range results.Measured() {
+		if len(run.Target) > 0 {
+			targets[run.Target]++
+		}
+	}
+	if len(targets) > 0 {
+		table := newTable("Discovered targets", column{"Target", alignLeft}, column{"Runs", alignRight})
+		names := make([]string, 0, len(targets))
+		for target := range targets {
+			names = append(names, target)
+		}
+		sort.Strings(names)
+		for _, target := range names {
+			table.addRow(target, strconv.Itoa(targets[target]))
+		}
+		table.render(w, style)
+	}
+
+	refused := newTable("Writes refused by the read-only root filesystem", column{"Run", alignLeft}, column{"Output", alignLeft})
+	for _, run := range results.Runs {
+		label := fmt.Sprintf("run %d", run.Index+1)
+		if run.Dry {
+			label = "dry " + label
+		}
+		for _, line := range run.RefusedWrites {
+			refused.addRow(label, line)
+		}
+	}
+	if len(refused.rows) > 0 {
+		refused.render(w, style)
+	}
+
+	var shutdowns []time.Duration
+	killed := 0
+	for _, run := range results.Measured() {
+		if run.Shutdown != nil {
+			shutdowns = append(shutdowns, run.Shutdown.Duration)
+			if run.Shutdown.Killed {
+				killed++
+			}
+		}
+	}
+	if len(shutdowns) > 0 {
+		table := newTable("Shutdown", column{"Metric", alignLeft}, column{"Value", alignRight})
+		table.addRow("Median (ms)", formatMillis(boottime.Median(shutdowns)))
+		table.addRow("Killed after the grace period", fmt.Sprintf("%d/%d", killed, len(shutdowns)))
+		table.render(w, style)
+	}
+
+	if calibration := results.ProbeCalibration; calibration != nil {
+		rate := "unlimited"
+		if calibration.ProbeRate > 0 {
+			rate = strconv.FormatFloat(calibration.ProbeRate, 'f', -1, 64) + "/s"
+		}
+		reference := strconv.FormatFloat(calibration.ReferenceProbeRate, 'f', -1, 64) + "/s"
+		table := newTable("Probe calibration", column{"Probe rate", alignLeft}, column{"Median (ms)", alignRight})
+		table.addRow(rate, formatMillis(boottime.Median(calibration.Durations)))
+		table.addRow(reference+" (reference)", formatMillis(boottime.Median(calibration.ReferenceDurations)))
+		table.addRow("Estimated probing delay", formatMillis(calibration.Delay))
+		table.render(w, style)
+	}
+}
+
+// phaseMedians returns the median offset of each phase reached by the runs, by increasing offset.
+// Nothing is returned when the runs only went through the spawned and ready phases.
+func phaseMedians(runs []boottime.Run) []boottime.Phase {
+	offsets := map[string][]time.Duration{}
+	for _, run := range runs {
+		for _, phase := range run.Phases {
+			offsets[phase.Name] = append(offsets[phase.Name], phase.Offset)
+		}
+	}
+	delete(offsets, boottime.SpawnedPhase)
+	if _, ready := offsets[boottime.ReadyPhase]; ready && len(offsets) == 1 {
+		return nil
+	}
+	var phases []boottime.Phase
+	for name, durations := range offsets {
+		phases = append(phases, boottime.Phase{Name: name, Offset: boottime.Median(durations)})
+	}
+	sort.SliceStable(phases, func(i, j int) bool {
+		return phases[i].Offset < phases[j].Offset || phases[i].Offset == phases[j].Offset && phases[i].Name < phases[j].Name
+	})
+	return phases
+}
+
+type portOrder struct {
+	ports string
+	runs  int
+}
+
+// portOrders counts the runs by order of opening of the watched ports, most frequent first.
+func portOrders(runs []boottime.Run) []portOrder {
+	counts := map[string]int{}
+	for _, run := range runs {
+		if order := run.PortOrder(); len(order) > 0 {
+			counts[strings.Join(order, " < ")]++
+		}
+	}
+	var orders []portOrder
+	for ports, count := range counts {
+		orders = append(orders, portOrder{ports, count})
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].runs > orders[j].runs || orders[i].runs == orders[j].runs && orders[i].ports < orders[j].ports
+	})
+	return orders
+}
+
+// lingeringRuns returns the runs that started with lingering sockets on the target port.
+func lingeringRuns(runs []boottime.Run) []boottime.Run {
+	var lingering []boottime.Run
+	for _, run := range runs {
+		if len(run.LingeringSockets) > 0 {
+			lingering = append(lingering, run)
+		}
+	}
+	return lingering
+}
+
+func formatSocketStates(sockets map[string]int) string {
+	var states []string
+	for state, count := range sockets {
+		states = append(states, strconv.Itoa(count)+" "+state)
+	}
+	sort.Strings(states)
+	return strings.Join(states, ", ")
+}
+
+// environmentTable compares the environment before the fastest, median and slowest runs, or returns
+// nil when no environment was observed.
+func environmentTable(runs []boottime.Run) *table {
+	var observed []boottime.Run
+	for _, run := range runs {
+		if run.Environment != nil {
+			observed = append(observed, run)
+		}
+	}
+	if len(observed) == 0 {
+		return nil
+	}
+	sort.SliceStable(observed, func(i, j int) bool { return observed[i].Duration < observed[j].Duration })
+	picked := []boottime.Run{observed[0], observed[len(observed)/2], observed[len(observed)-1]}
+	table := newTable("Environment before the runs",
+		column{"Indicator", alignLeft}, column{"Fastest run", alignRight}, column{"Median run", alignRight}, column{"Slowest run", alignRight})
+	indicators := []struct {
+		name   string
+		format func(e *boottime.EnvironmentSnapshot) string
+	}{
+		{"Duration (ms)", nil},
+		{"Load", func(e *boottime.EnvironmentSnapshot) string {
+			if e.Load == nil {
+				return ""
+			}
+			return strconv.FormatFloat(*e.Load, 'f', 2, 64)
+		}},
+		{"Available memory (MiB)", func(e *boottime.EnvironmentSnapshot) string {
+			if e.MemoryAvailable == nil {
+				return ""
+			}
+			return strconv.FormatUint(*e.MemoryAvailable/(1024*1024), 10)
+		}},
+		{"Running containers", func(e *boottime.EnvironmentSnapshot) string {
+			if e.Containers == nil {
+				return ""
+			}
+			return strconv.Itoa(*e.Containers)
+		}},
+	}
+	for _, indicator := range indicators {
+		cells := []string{indicator.name}
+		empty := true
+		for _, run := range picked {
+			cell := formatMillis(run.Duration)
+			if indicator.format != nil {
+				cell = indicator.format(run.Environment)
+			}
+			empty = empty && len(cell) == 0
+			cells = append(cells, cell)
+		}
+		if !empty {
+			table.addRow(cells...)
+		}
+	}
+	return table
+}
+
+// schedulings returns the scheduling statistics collected during the runs.
+func schedulings(runs []boottime.Run) []*boottime.Scheduling {
+	var scheduling []*boottime.Scheduling
+	for _, run := range runs {
+		if run.Scheduling != nil {
+			scheduling = append(scheduling, run.Scheduling)
+		}
+	}
+	return scheduling
+}
+
+// histogramBuckets is the number of buckets of the histogram of the console report, see
+// --histogram-buckets.
+var histogramBuckets = 10
+
+// histogramWidth is the width of the longest bar of histograms, in characters.
+const histogramWidth = 40
+
+// histogramBars are the eighths of a character of the bars of histograms in the fancy style.
+var histogramBars = []rune("▏▎▍▌▋▊▉█")
+
+// histogramTable returns a histogram of durations with buckets of equal width, nil when there are
+// no buckets or the durations are all the same.
+func histogramTable(durations []time.Duration, buckets int, style tableStyle) *table {
+	if buckets < 1 || len(durations) < 2 {
+		return nil
+	}
+	min, max := durations[0], durations[0]
+	for _, d := range durations {
+		if d < min {
+			min = d
+		}
+		if d > max {
+			max = d
+		}
+	}
+	if min == max {
+		return nil
+	}
+	counts := make([]int, buckets)
+	width := float64(max-min) / float64(buckets)
+	highest := 0
+	for _, d := range durations {
+		i := int(float64(d-min) / width)
+		if i >= buckets {
+			i = buckets - 1
+		}
+		counts[i]++
+		if counts[i] > highest {
+			highest = counts[i]
+		}
+	}
+	table := newTable("Histogram", column{"Range (ms)", alignLeft}, column{"Runs", alignRight}, column{"", alignLeft})
+	for i, count := range counts {
+		from, to := min+time.Duration(float64(i)*width), min+time.Duration(float64(i+1)*width)
+		eighths := count * histogramWidth * 8 / highest
+		var bar string
+		if style == fancyStyle {
+			bar = strings.Repeat(string(histogramBars[7]), eighths/8)
+			if eighths%8 > 0 {
+				bar += string(histogramBars[eighths%8-1])
+			}
+		} else {
+			bar = strings.Repeat("#", (eighths+4)/8)
+		}
+		table.addRow(formatMillis(from)+" - "+formatMillis(to), strconv.Itoa(count), bar)
+	}
+	return table
+}
+
+// sparkBlocks are the characters of sparklines, from the shortest runs to the longest.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws the durations of the measured runs in order, failed runs being crosses, which
+// reveals trends such as warming or drift, and outliers.
+func sparkline(runs []boottime.Run) string {
+	var min, max time.Duration
+	for _, run := range runs {
+		if !run.Dry && !run.Failed() {
+			if min == 0 || run.Duration < min {
+				min = run.Duration
+			}
+			if run.Duration > max {
+				max = run.Duration
+			}
+		}
+	}
+	var line strings.Builder
+	for _, run := range runs {
+		switch {
+		case run.Dry:
+			continue
+		case run.Failed():
+			line.WriteRune('×')
+		case max == min:
+			line.WriteRune(sparkBlocks[0])
+		default:
+			line.WriteRune(sparkBlocks[int(float64(run.Duration-min)/float64(max-min)*float64(len(sparkBlocks)-1)+0.5)])
+		}
+	}
+	return fmt.Sprintf("Runs in order: %s (%s to %s ms)", line.String(), formatMillis(min), formatMillis(max))
+}
+
+// cpuTimes returns the user, system and total CPU times of the runs until readiness, and the
+// number of cores they kept busy on average, total CPU time over boot time.
+func cpuTimes(runs []boottime.Run) (user, system, total []time.Duration, cores []float64) {
+	for _, run := range runs {
+		if run.CPU != nil && run.Duration > 0 {
+			user, system = append(user, run.CPU.User), append(system, run.CPU.System)
+			total = append(total, run.CPU.Total())
+			cores = append(cores, float64(run.CPU.Total())/float64(run.Duration))
+		}
+	}
+	return user, system, total, cores
+}
+
+// memoryStatistics summarizes the resident set sizes of the servers at readiness and their peaks,
+// nil when not collected.
+func memoryStatistics(runs []boottime.Run) (ready, peak *byteStatistics) {
+	var readySizes, peakSizes []int64
+	for _, run := range runs {
+		if run.Memory != nil {
+			readySizes = append(readySizes, run.Memory.ReadyRSS)
+			if run.Memory.PeakRSS > 0 {
+				peakSizes = append(peakSizes, run.Memory.PeakRSS)
+			}
+		}
+	}
+	return computeByteStatistics(readySizes), computeByteStatistics(peakSizes)
+}
+
+func formatMiB(bytes int64) string {
+	return strconv.FormatFloat(float64(bytes)/(1024*1024), 'f', 1, 64)
+}
+
+// jvmMetrics returns the JVM metrics collected during the runs.
+func jvmMetrics(runs []boottime.Run) []*boottime.JVMMetrics {
+	var metrics []*boottime.JVMMetrics
+	for _, run := range runs {
+		if run.JVM != nil {
+			metrics = append(metrics, run.JVM)
+		}
+	}
+	return metrics
+}
+
+func float64ToDuration(f float64) time.Duration {
+	return time.Duration(int64(f))
+}
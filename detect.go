@@ -0,0 +1,254 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// changeDetection are the settings of the change-point detection of journal detect.
+type changeDetection struct {
+	alpha      float64 // significance level of a change
+	minShift   float64 // minimum change of the median, in percent
+	minEntries int     // minimum number of entries on each side of a change
+}
+
+// changePoint is a journal entry from which the boot time of a series of entries shifted.
+type changePoint struct {
+	entry         boottime.JournalEntry
+	before, after time.Duration // medians of the runs of the segments around the change
+	significance  significance
+}
+
+// changePermutations is the number of random orders of the entries of a series with which
+// detectChanges assesses its best split.
+const changePermutations = 999
+
+// detectChanges finds the change points of a series of entries by binary segmentation: the series
+// is split where the runs on both sides differ the most according to a Mann-Whitney U test, if the
+// difference is significant and large enough, and both sides are searched again.
+//
+// As the best of many splits is bound to look significant, and the runs of an entry share the
+// conditions of its session, the p-value of the best split is that of a permutation test: the
+// proportion of random orders of the entries, each keeping its runs together, whose best split
+// differs at least as much.
+func (d changeDetection) detectChanges(entries []boottime.JournalEntry) []changePoint {
+	if len(entries) < 2*d.minEntries {
+		return nil
+	}
+	ranked := rankEntries(entries)
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	best, bestZ := ranked.bestSplit(order, d.minEntries)
+	if best < 0 {
+		return nil
+	}
+	random := rand.New(rand.NewSource(int64(len(entries))))
+	exceeded := 0
+	for i := 0; i < changePermutations; i++ {
+		random.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		if _, z := ranked.bestSplit(order, d.minEntries); z >= bestZ {
+			exceeded++
+		}
+	}
+	p := float64(exceeded+1) / float64(changePermutations+1)
+	if p >= d.alpha {
+		return nil
+	}
+	before, after := boottime.Median(entryRuns(entries[:best])), boottime.Median(entryRuns(entries[best:]))
+	if math.Abs(float64(after-before))/float64(before)*100 < d.minShift {
+		return nil
+	}
+	s := mannWhitney(entryRuns(entries[:best]), entryRuns(entries[best:]))
+	s.p = p
+	points := d.detectChanges(entries[:best])
+	points = append(points, changePoint{entries[best], before, after, s})
+	return append(points, d.detectChanges(entries[best:])...)
+}
+
+// rankedEntries are the ranks of the runs of a series of entries among all of them, from which the
+// Mann-Whitney U test of any split of the entries, in any order, follows from sums.
+type rankedEntries struct {
+	runs     []float64 // number of runs of each entry
+	rankSums []float64 // sum of the ranks of the runs of each entry
+	n        float64   // number of runs
+	ties     float64   // tie correction of the variance of U
+}
+
+func rankEntries(entries []boottime.JournalEntry) rankedEntries {
+	type sample struct {
+		d     time.Duration
+		entry int
+	}
+	var samples []sample
+	ranked := rankedEntries{runs: make([]float64, len(entries)), rankSums: make([]float64, len(entries))}
+	for i, entry := range entries {
+		for _, d := range boottime.Durations(entry.Results.Measured()) {
+			samples = append(samples, sample{d, i})
+		}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].d < samples[j].d })
+	for i := 0; i < len(samples); {
+		j := i
+		for j < len(samples) && samples[j].d == samples[i].d {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // average of the ranks i+1 to j
+		for k := i; k < j; k++ {
+			ranked.runs[samples[k].entry]++
+			ranked.rankSums[samples[k].entry] += rank
+		}
+		t := float64(j - i)
+		ranked.ties += t*t*t - t
+		i = j
+	}
+	ranked.n = float64(len(samples))
+	return ranked
+}
+
+// bestSplit returns where the entries, in order, split with the most significant Mann-Whitney U
+// test with at least minEntries entries on each side, and the z-score of that test, or -1 when no
+// split has both sides varying.
+func (r rankedEntries) bestSplit(order []int, minEntries int) (int, float64) {
+	best, bestZ := -1, 0.0
+	var n1, rankSum float64
+	for k := 1; k < len(order)-minEntries+1; k++ {
+		n1 += r.runs[order[k-1]]
+		rankSum += r.rankSums[order[k-1]]
+		if k < minEntries {
+			continue
+		}
+		n2 := r.n - n1
+		pairs := n1 * n2
+		variance := pairs / 12 * (r.n + 1 - r.ties/(r.n*(r.n-1)))
+		if variance <= 0 {
+			continue
+		}
+		u := rankSum - n1*(n1+1)/2
+		if z := math.Max(math.Abs(u-pairs/2)-0.5, 0) / math.Sqrt(variance); best < 0 || z > bestZ {
+			best, bestZ = k, z
+		}
+	}
+	return best, bestZ
+}
+
+// entryRuns returns the durations of the measured runs of entries.
+func entryRuns(entries []boottime.JournalEntry) []time.Duration {
+	var durations []time.Duration
+	for _, entry := range entries {
+		durations = append(durations, boottime.Durations(entry.Results.Measured())...)
+	}
+	return durations
+}
+
+// detectJournalChanges prints the change points of the boot time of the entries of a scenario, or
+// of every scenario when empty, for each setup in turn.
+func detectJournalChanges(w io.Writer, journalPath string, scenario string, d changeDetection, styleName string) error {
+	style, err := tableStyleFor(styleName)
+	if err != nil {
+		return err
+	}
+	type series struct {
+		scenario, fingerprint string
+		entries               []boottime.JournalEntry
+	}
+	var all []*series
+	byKey := map[string]*series{}
+	err = boottime.ReadJournal(journalPath, func(entry boottime.JournalEntry) error {
+		if entry.Results == nil || len(entry.Results.Measured()) == 0 {
+			return nil
+		}
+		if len(scenario) > 0 && entry.Results.Scenario != scenario {
+			return nil
+		}
+		key := entry.Results.Scenario + " " + entry.Fingerprint
+		s, found := byKey[key]
+		if !found {
+			s = &series{scenario: entry.Results.Scenario, fingerprint: entry.Fingerprint}
+			byKey[key] = s
+			all = append(all, s)
+		}
+		s.entries = append(s.entries, entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(all) == 0 {
+		return fmt.Errorf("no result of scenario %q in the journal %s", scenario, journalPath)
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].scenario < all[j].scenario })
+	changes := 0
+	for _, s := range all {
+		sort.SliceStable(s.entries, func(i, j int) bool { return s.entries[i].Time().Before(s.entries[j].Time()) })
+		points := d.detectChanges(s.entries)
+		if len(points) == 0 {
+			continue
+		}
+		changes += len(points)
+		title := fmt.Sprintf("Changes of setup %s", s.fingerprint[:12])
+		if len(s.scenario) > 0 {
+			title = fmt.Sprintf("Changes of scenario %s, setup %s", s.scenario, s.fingerprint[:12])
+		}
+		table := newTable(title,
+			column{"Since", alignLeft}, column{"Commit", alignLeft},
+			column{"Before (ms)", alignRight}, column{"After (ms)", alignRight}, column{"Ratio", alignRight},
+			column{"p-value", alignRight}, column{"Effect", alignLeft})
+		for _, point := range points {
+			commit := point.entry.Commit
+			if len(commit) == 0 {
+				commit = "-"
+			}
+			table.addRow(point.entry.RecordedAt.Local().Format("2006-01-02 15:04:05"), commit,
+				formatMillis(point.before), formatMillis(point.after), formatRatio(point.after, point.before),
+				formatPValue(point.significance.p), formatEffect(point.significance))
+		}
+		table.render(w, style)
+	}
+	fmt.Fprintf(w, "%d change points in %d series\n", changes, len(all))
+	return nil
+}
+
+// journalCommit is the commit recorded in the journal entries, see --commit.
+var journalCommit string
+
+// headCommit returns the abbreviated commit checked out in the working directory, or an empty
+// string outside of a git repository.
+func headCommit() string {
+	out, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
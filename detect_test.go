@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// journalSeries returns a series of entries of 10 runs each around the medians, with runs varying
+// within an entry and entries varying from one session to the next.
+func journalSeries(medians ...time.Duration) []boottime.JournalEntry {
+	random := rand.New(rand.NewSource(1))
+	var entries []boottime.JournalEntry
+	for _, median := range medians {
+		session := median + time.Duration(random.NormFloat64()*float64(4*time.Millisecond))
+		results := &boottime.Results{}
+		for i := 0; i < 10; i++ {
+			d := session + time.Duration(random.NormFloat64()*float64(2*time.Millisecond))
+			results.Runs = append(results.Runs, boottime.Run{Index: i, Duration: d})
+		}
+		entries = append(entries, boottime.JournalEntry{Results: results})
+	}
+	return entries
+}
+
+func repeat(d time.Duration, n int) []time.Duration {
+	durations := make([]time.Duration, n)
+	for i := range durations {
+		durations[i] = d
+	}
+	return durations
+}
+
+func TestDetectChanges(t *testing.T) {
+	// without a minimum shift, only the significance of the changes tells them from noise
+	d := changeDetection{alpha: 0.01, minEntries: 3}
+	for _, c := range []struct {
+		name     string
+		medians  []time.Duration
+		expected []int // indexes of the entries of the change points
+	}{
+		{"flat", repeat(100*time.Millisecond, 30), nil},
+		{"stepped", append(repeat(100*time.Millisecond, 15), repeat(130*time.Millisecond, 15)...), []int{15}},
+		{"two steps", append(append(repeat(100*time.Millisecond, 10), repeat(130*time.Millisecond, 10)...), repeat(110*time.Millisecond, 10)...), []int{10, 20}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			entries := journalSeries(c.medians...)
+			for i := range entries {
+				entries[i].Commit = string(rune('a' + i))
+			}
+			points := d.detectChanges(entries)
+			if len(points) != len(c.expected) {
+				t.Fatalf("expected %d change points, got %d", len(c.expected), len(points))
+			}
+			for i, point := range points {
+				if expected := entries[c.expected[i]].Commit; point.entry.Commit != expected {
+					t.Errorf("expected a change at entry %d, got one at %q", c.expected[i], point.entry.Commit)
+				}
+				if point.significance.p >= d.alpha {
+					t.Errorf("expected a p-value below %g, got %g", d.alpha, point.significance.p)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// smtpSettings are those of the smtp block of the configuration file, if any.
+var smtpSettings boottime.SMTPSettings
+
+// emailExporter emails the report in the markdown style to the comma-separated recipients given as
+// destination, with the SMTP settings of the configuration file completed by the environment. With
+// ?format=html after the recipients, the page of the html exporter is sent as an alternative.
+func emailExporter(destination string) (boottime.Exporter, error) {
+	format := "markdown"
+	if i := strings.IndexByte(destination, '?'); i >= 0 {
+		query, err := url.ParseQuery(destination[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid email options: %v", err)
+		}
+		if query.Get("format") != "" {
+			format = query.Get("format")
+		}
+		destination = destination[:i]
+	}
+	if format != "markdown" && format != "html" {
+		return nil, fmt.Errorf("unknown email format: %s (expected markdown or html)", format)
+	}
+	var to []string
+	for _, recipient := range strings.Split(destination, ",") {
+		if recipient = strings.TrimSpace(recipient); len(recipient) > 0 {
+			to = append(to, recipient)
+		}
+	}
+	if len(to) == 0 {
+		return nil, errors.New("the email exporter needs recipients, as in email=team@example.com")
+	}
+	settings, err := smtpSettings.WithEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	return boottime.ExporterFunc(func(results *boottime.Results) error {
+		subject := "Boot time"
+		if len(results.Scenario) > 0 {
+			subject += " of " + results.Scenario
+		}
+		var body bytes.Buffer
+		measured := results.Measured()
+		if len(measured) > 0 {
+			subject += fmt.Sprintf(": median %s ms", formatMillis(boottime.Median(boottime.Durations(measured))))
+			report(&body, markdownStyle, results)
+		} else {
+			subject += ": no successful run"
+		}
+		var failures []string
+		for _, run := range results.Runs {
+			if run.Failed() {
+				failures = append(failures, fmt.Sprintf("| %d | %t | %s | %s |", run.Index+1, run.Dry, run.ErrorCategory, strings.Replace(boottime.Redact(run.Error), "|", "\\|", -1)))
+			}
+		}
+		if len(failures) > 0 {
+			fmt.Fprintf(&body, "### Failed runs\n\n| Run | Dry | Category | Error |\n|---:|---|---|---|\n%s\n", strings.Join(failures, "\n"))
+		}
+		parts := []boottime.MailPart{{ContentType: "text/markdown", Body: body.Bytes()}}
+		if format == "html" {
+			page, err := htmlReport(results)
+			if err != nil {
+				return err
+			}
+			parts = append(parts, boottime.MailPart{ContentType: "text/html", Body: page})
+		}
+		return settings.SendMail(to, subject, parts...)
+	}), nil
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jponge/time-to-boot-server/boottime"
+	"github.com/montanaflynn/stats"
+)
+
+// regressionGate fails benchmarks whose boot time regressed against baseline results, for CI.
+type regressionGate struct {
+	path       string  // of baseline results exported with --export json, {scenario} being expanded
+	percentile float64 // compared, the median when 50
+	threshold  float64 // in percents of the baseline
+}
+
+// parseThreshold parses a percentage such as 5% or 5.
+func parseThreshold(s string) (float64, error) {
+	threshold, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || threshold < 0 {
+		return 0, fmt.Errorf("invalid threshold: %s (expected a percentage, as in 5%%)", s)
+	}
+	return threshold, nil
+}
+
+// statistic returns the compared percentile of durations.
+func (g *regressionGate) statistic(durations []time.Duration) time.Duration {
+	if g.percentile == 50 {
+		return boottime.Median(durations)
+	}
+	p, _ := stats.Percentile(durationsToFloat64(durations), g.percentile)
+	return time.Duration(p)
+}
+
+func (g *regressionGate) name() string {
+	if g.percentile == 50 {
+		return "median"
+	}
+	return "p" + strconv.FormatFloat(g.percentile, 'f', -1, 64)
+}
+
+// baseline reads the baseline results of a scenario.
+func (g *regressionGate) baseline(scenario string) (*boottime.Results, error) {
+	path := expandScenario(g.path, scenario)
+	baseline, err := boottime.ReadResults(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the baseline: %v", err)
+	}
+	if len(baseline.Measured()) == 0 {
+		return nil, fmt.Errorf("the baseline %s has no measured run", path)
+	}
+	return baseline, nil
+}
+
+// check returns an error when the boot time of results is slower than that of the baseline by more
+// than the threshold.
+func (g *regressionGate) check(results *boottime.Results) error {
+	baseline, err := g.baseline(results.Scenario)
+	if err != nil {
+		return err
+	}
+	durations := boottime.Durations(results.Measured())
+	if len(durations) == 0 {
+		return fmt.Errorf("no measured run to compare with the baseline")
+	}
+	current, reference := g.statistic(durations), g.statistic(boottime.Durations(baseline.Measured()))
+	delta := 100 * float64(current-reference) / float64(reference)
+	comparison := fmt.Sprintf("%s of %s ms, %+.1f%% against %s ms for the baseline", g.name(),
+		formatMillis(current), delta, formatMillis(reference))
+	if delta > g.threshold {
+		return fmt.Errorf("boot time regression: %s, over the %s%% threshold", comparison,
+			strconv.FormatFloat(g.threshold, 'f', -1, 64))
+	}
+	color.Green("No regression: %s", comparison)
+	return nil
+}
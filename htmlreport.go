@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// htmlPage is the template of HTML reports, with the title, the charts and the tables.
+const htmlPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%[1]s</title>
+<style>
+body { font-family: Verdana, "DejaVu Sans", sans-serif; font-size: 14px; color: #333; margin: 2em auto; max-width: 680px; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; }
+th { background: #f4f4f4; }
+.right { text-align: right; }
+.left { text-align: left; }
+svg { display: block; margin-bottom: 1em; }
+</style>
+</head>
+<body>
+<h2>%[1]s</h2>
+%[2]s
+</body>
+</html>
+`
+
+// htmlExporter writes a self-contained HTML page of results, with charts of the runs in order and of
+// their distribution, and the tables of the console report, as an artifact of CI builds.
+func htmlExporter(destination string) (boottime.Exporter, error) {
+	return boottime.ExporterFunc(func(results *boottime.Results) error {
+		page, err := htmlReport(results)
+		if err != nil {
+			return err
+		}
+		if len(destination) == 0 || destination == "-" {
+			_, err := os.Stdout.Write(page)
+			return err
+		}
+		return ioutil.WriteFile(destination, page, 0644)
+	}), nil
+}
+
+// htmlReport renders the page of the html exporter, with registered secrets redacted.
+func htmlReport(results *boottime.Results) ([]byte, error) {
+	title := "Boot time"
+	if len(results.Scenario) > 0 {
+		title += " of " + results.Scenario
+	}
+	var body bytes.Buffer
+	if len(results.Measured()) == 0 {
+		body.WriteString("<p>No successful run.</p>\n")
+	} else {
+		runs, err := boottime.RunsSVG(results)
+		if err != nil {
+			return nil, err
+		}
+		buckets := histogramBuckets
+		if buckets == 0 {
+			buckets = 10
+		}
+		histogram, err := boottime.HistogramSVG(results, buckets)
+		if err != nil {
+			return nil, err
+		}
+		body.WriteString(runs)
+		body.WriteString(histogram)
+		report(&body, htmlStyle, results)
+	}
+	return []byte(boottime.Redact(fmt.Sprintf(htmlPage, html.EscapeString(title), body.String()))), nil
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// importSamples exports the results of samples measured by other tools. The format is guessed
+// from the file when empty, and results without a scenario name are named after the file.
+func importSamples(path, format, unitName, scenario string, exportSpecs []string) error {
+	unit, err := time.ParseDuration("1" + unitName)
+	if err != nil {
+		return fmt.Errorf("invalid unit: %s (expected s, ms, us or ns)", unitName)
+	}
+	var data []byte
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	if len(format) == 0 {
+		format = samplesFormat(path, data)
+	}
+	if len(scenario) == 0 && path != "-" {
+		scenario = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	all, err := boottime.ImportSamples(bytes.NewReader(data), format, unit, scenario)
+	if err != nil {
+		return fmt.Errorf("unable to import %s: %v", path, err)
+	}
+	for _, results := range all {
+		exporters, err := newExporters(exportSpecs, results.Scenario)
+		if err != nil {
+			return err
+		}
+		if len(all) > 1 {
+			printScenario(results.Scenario)
+		}
+		if err := export(exporters, results); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// samplesFormat guesses the format of samples: CSV files have a .csv extension, and JSON
+// objects with a results field are hyperfine exports.
+func samplesFormat(path string, data []byte) string {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return boottime.CSVSamples
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err == nil {
+		if _, found := object["results"]; found {
+			return boottime.HyperfineSamples
+		}
+		return boottime.JSONSamples
+	}
+	var array []json.RawMessage
+	if err := json.Unmarshal(data, &array); err == nil {
+		return boottime.JSONSamples
+	}
+	return boottime.CSVSamples
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// defaultJournalPath is ~/.time-to-boot-server/journal.jsonl, or a file of the working directory
+// when there is no home directory.
+func defaultJournalPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "time-to-boot-server-journal.jsonl"
+	}
+	return filepath.Join(home, ".time-to-boot-server", "journal.jsonl")
+}
+
+// journalOrDefault returns the journal of --journal, or the default journal.
+func journalOrDefault(journalPath string) string {
+	if len(journalPath) > 0 {
+		return journalPath
+	}
+	return defaultJournalPath()
+}
+
+// sessionJournalPath returns the journal that the results of a session are appended to: that of
+// --journal, and otherwise the default journal for the sessions of a configuration file or a
+// profile only, so that one-off runs from the command line leave nothing in the home directory.
+func sessionJournalPath(journalPath, configPath, profileName string) string {
+	if len(journalPath) == 0 && (len(configPath) > 0 || len(profileName) > 0) {
+		return defaultJournalPath()
+	}
+	return journalPath
+}
+
+// journalGroup holds the journal entries of a setup.
+type journalGroup struct {
+	fingerprint string
+	entries     []boottime.JournalEntry
+}
+
+// showJournal prints the journal entries of a scenario, grouped by setup with the most recently
+// used first. With a configuration file, only the entries of the current setup of the scenario,
+// with the given profile if any, are printed.
+func showJournal(w io.Writer, journalPath string, configPath string, profile string, scenario string, styleName string) error {
+	style, err := tableStyleFor(styleName)
+	if err != nil {
+		return err
+	}
+	var fingerprint string
+	if len(configPath) > 0 {
+		benchmarks, err := benchmarksFromConfig(configPath, profile, []string{scenario}, nil)
+		if err != nil {
+			return err
+		}
+		fingerprint = benchmarks[0].Fingerprint()
+	}
+	var groups []*journalGroup
+	byFingerprint := map[string]*journalGroup{}
+	err = boottime.ReadJournal(journalPath, func(entry boottime.JournalEntry) error {
+		if entry.Results == nil || entry.Results.Scenario != scenario || len(entry.Results.Measured()) == 0 {
+			return nil
+		}
+		if len(fingerprint) > 0 && entry.Fingerprint != fingerprint {
+			return nil
+		}
+		group, found := byFingerprint[entry.Fingerprint]
+		if !found {
+			group = &journalGroup{fingerprint: entry.Fingerprint}
+			byFingerprint[entry.Fingerprint] = group
+			groups = append(groups, group)
+		}
+		group.entries = append(group.entries, entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return fmt.Errorf("no result of scenario %q in the journal %s", scenario, journalPath)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return lastRecorded(groups[i]).After(lastRecorded(groups[j]))
+	})
+	for _, group := range groups {
+		host := group.entries[0].Host
+		title := fmt.Sprintf("Setup %s on %s (%s/%s, %d CPUs)", group.fingerprint[:12], host.Name, host.OS, host.Arch, host.CPUs)
+		table := newTable(title,
+			column{"Recorded at", alignLeft}, column{"Runs", alignRight},
+			column{"Min (ms)", alignRight}, column{"Median (ms)", alignRight}, column{"Max (ms)", alignRight})
+		for _, entry := range group.entries {
+			durations := boottime.Durations(entry.Results.Measured())
+			sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+			table.addRow(entry.RecordedAt.Local().Format("2006-01-02 15:04:05"), strconv.Itoa(len(durations)),
+				formatMillis(durations[0]), formatMillis(boottime.Median(durations)), formatMillis(durations[len(durations)-1]))
+		}
+		table.render(w, style)
+	}
+	return nil
+}
+
+func lastRecorded(group *journalGroup) time.Time {
+	return group.entries[len(group.entries)-1].RecordedAt
+}
+
+// pruneJournal downsamples the entries of the journal recorded before the keepLast period to the
+// keepPerDay most recent ones of each day, scenario and setup, and prints what was kept.
+func pruneJournal(w io.Writer, journalPath string, keepLast string, keepPerDay int) error {
+	period, err := parseRetention(keepLast)
+	if err != nil {
+		return err
+	}
+	if keepPerDay < 0 {
+		return fmt.Errorf("invalid --keep-per-day, expected a positive number: %d", keepPerDay)
+	}
+	var kept, total int
+	err = boottime.RewriteJournal(journalPath, func(entries []boottime.JournalEntry) []boottime.JournalEntry {
+		retained := retainEntries(entries, time.Now().Add(-period), keepPerDay)
+		kept, total = len(retained), len(entries)
+		return retained
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Kept %d of the %d entries of the journal %s\n", kept, total, journalPath)
+	return nil
+}
+
+// retainEntries returns the entries recorded from the cutoff, and the keepPerDay most recent ones
+// of each day, scenario and setup before it.
+func retainEntries(entries []boottime.JournalEntry, cutoff time.Time, keepPerDay int) []boottime.JournalEntry {
+	// entries are visited from the most recent, by commit date for those backfilled by a sweep
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = len(entries) - 1 - i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return entries[order[i]].Time().After(entries[order[j]].Time()) })
+	perDay := map[string]int{}
+	keep := make([]bool, len(entries))
+	for _, i := range order {
+		entry := entries[i]
+		if !entry.Time().Before(cutoff) {
+			keep[i] = true
+			continue
+		}
+		var scenario string
+		if entry.Results != nil {
+			scenario = entry.Results.Scenario
+		}
+		day := strings.Join([]string{entry.Time().Local().Format("2006-01-02"), scenario, entry.Fingerprint}, " ")
+		perDay[day]++
+		keep[i] = perDay[day] <= keepPerDay
+	}
+	var kept []boottime.JournalEntry
+	for i, entry := range entries {
+		if keep[i] {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// parseRetention parses a period such as 90d, in days, or any Go duration such as 36h.
+func parseRetention(s string) (time.Duration, error) {
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if period, err := time.ParseDuration(s); err == nil && period >= 0 {
+		return period, nil
+	}
+	return 0, fmt.Errorf("invalid --keep-last, expected a number of days such as 90d or a duration: %s", s)
+}
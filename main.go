@@ -23,130 +23,430 @@
 package main
 
 import (
-	"io/ioutil"
-	"log"
-	"net"
-	"net/http"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/fatih/color"
-	"github.com/montanaflynn/stats"
+	"github.com/jponge/time-to-boot-server/boottime"
 	"github.com/urfave/cli"
 )
 
-func boot(command string, args ...string) (*exec.Cmd, error) {
-	cmd := exec.Command(command, args...)
-	if err := cmd.Start(); err != nil {
+var logger = boottime.DefaultLogger()
+
+// interruptibleContext returns a context that is cancelled on SIGINT or SIGTERM.
+func interruptibleContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			logger.Warn("interrupted", "signal", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// profileOutputs are the output defaults of the profiles, see boottime.Profiles.
+var profileOutputs = map[string]struct {
+	style     string
+	noJournal bool
+}{
+	"ci": {style: "plain", noJournal: true}, // runners are disposable, and logs are read without a terminal
+}
+
+// modeFlagPrefixes maps each mode to the prefix of its dedicated flags, as in --http.timeout.
+var modeFlagPrefixes = map[string]string{
+	"http-get":      "http.",
+	"tcp-connect":   "tcp.",
+	"prom-metric":   "prom.",
+	"health-groups": "health.",
+	"callback":      "callback.",
+	"file":          "file.",
+	"logfile":       "logfile.",
+	"log-match":     "log-match.",
+}
+
+// checkModeFlags rejects flags dedicated to a mode other than the selected one and those of the
+// milestones and events.
+func checkModeFlags(c *cli.Context, mode string, events []boottime.LifecycleEvent) error {
+	modes := map[string]bool{mode: true}
+	for _, event := range events {
+		if len(event.Mode) > 0 {
+			modes[event.Mode] = true
+		}
+	}
+	for _, flag := range c.App.Flags {
+		name := flag.GetName()
+		if !c.GlobalIsSet(name) {
+			continue
+		}
+		for flagMode, prefix := range modeFlagPrefixes {
+			if !modes[flagMode] && strings.HasPrefix(name, prefix) {
+				return fmt.Errorf("--%s only applies to the %s mode, not %s", name, flagMode, mode)
+			}
+		}
+	}
+	return nil
+}
+
+// parseEvents parses --event or --milestone values, as told by flag, given as 'name=mode:target',
+// where the mode and target default to those of the benchmark, and sets the matches given to the
+// .match flag as 'name=match'.
+func parseEvents(flag string, specs, matches []string) ([]boottime.LifecycleEvent, error) {
+	events := make([]boottime.LifecycleEvent, 0, len(specs))
+	for _, spec := range specs {
+		var event boottime.LifecycleEvent
+		probe := ""
+		if i := strings.IndexByte(spec, '='); i >= 0 {
+			spec, probe = spec[:i], spec[i+1:]
+		}
+		event.Name = strings.TrimSpace(spec)
+		if i := strings.IndexByte(probe, ':'); i >= 0 {
+			event.Mode, event.Target = probe[:i], probe[i+1:]
+		} else {
+			event.Mode = probe
+		}
+		events = append(events, event)
+	}
+	eventMatches, err := parseAssignments(matches, flag+" match")
+	if err != nil {
 		return nil, err
 	}
-	return cmd, nil
+	for name, match := range eventMatches {
+		found := false
+		for i := range events {
+			if events[i].Name == name {
+				events[i].Match, found = match, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("--%s.match of an unknown %s: %s", flag, flag, name)
+		}
+	}
+	return events, nil
+}
+
+// scenarioPlaceholder is replaced by the scenario name in export destinations and record paths.
+const scenarioPlaceholder = "{scenario}"
+
+func healthFrameworks() []string {
+	var names []string
+	for name := range boottime.HealthFrameworks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
-func tryConnectingWithTCP(target string) (bool, func()) {
-	conn, err := net.Dial("tcp", target)
-	if err == nil {
-		return true, func() {
-			conn.Close()
+func expandScenario(s string, scenario string) string {
+	return strings.Replace(s, scenarioPlaceholder, scenario, -1)
+}
+
+// parseHeaders parses headers given as 'Name: value'.
+func parseHeaders(specs []string) (map[string]string, error) {
+	headers := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		i := strings.IndexByte(spec, ':')
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid header, expected 'Name: value': %s", spec)
 		}
+		headers[strings.TrimSpace(spec[:i])] = strings.TrimSpace(spec[i+1:])
 	}
-	return false, nil
+	return headers, nil
 }
 
-func tryConnectingWithHTTPGet(target string) (bool, func()) {
-	resp, err := http.Get(target)
-	if err == nil && resp.StatusCode == 200 {
-		ioutil.ReadAll(resp.Body)
-		return true, func() {
-			resp.Body.Close()
+// parseAssignments parses values given as 'name=value', such as health groups, where what names
+// the kind of values in errors.
+func parseAssignments(specs []string, what string) (map[string]string, error) {
+	values := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		i := strings.IndexByte(spec, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid %s, expected 'name=value': %s", what, spec)
 		}
+		values[strings.TrimSpace(spec[:i])] = strings.TrimSpace(spec[i+1:])
 	}
-	return false, nil
+	return values, nil
 }
 
-func connectionFunctionFor(mode string) func(string) (bool, func()) {
-	if mode == "tcp-connect" {
-		return tryConnectingWithTCP
-	} else if mode == "http-get" {
-		return tryConnectingWithHTTPGet
+// parseLogMarkers parses --phase values given as 'name=regexp', sorted by phase name.
+func parseLogMarkers(specs []string) ([]boottime.LogMarker, error) {
+	patterns, err := parseAssignments(specs, "phase")
+	if err != nil {
+		return nil, err
 	}
-	log.Fatal("Unknow mode: ", mode)
-	return nil
+	markers := make([]boottime.LogMarker, 0, len(patterns))
+	for name, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of phase %s: %v", name, err)
+		}
+		markers = append(markers, boottime.LogMarker{Phase: name, Pattern: re})
+	}
+	sort.Slice(markers, func(i, j int) bool { return markers[i].Phase < markers[j].Phase })
+	return markers, nil
+}
+
+// newExporters creates the exporters from --export specifications, defaulting to the console.
+func newExporters(specs []string, scenario string) ([]boottime.Exporter, error) {
+	if len(specs) == 0 {
+		specs = []string{"console"}
+	}
+	exporters := make([]boottime.Exporter, len(specs))
+	for i, spec := range specs {
+		exporter, err := boottime.NewExporter(expandScenario(spec, scenario))
+		if err != nil {
+			return nil, err
+		}
+		exporters[i] = exporter
+	}
+	return exporters, nil
+}
+
+// export runs every exporter, reporting the first failure after trying them all.
+func export(exporters []boottime.Exporter, results *boottime.Results) error {
+	if reproducible {
+		results = results.Reproducible()
+	}
+	var err error
+	for _, exporter := range exporters {
+		if exportErr := exporter.Export(results); exportErr != nil {
+			logger.Error("export failed", "error", exportErr)
+			if err == nil {
+				err = exportErr
+			}
+		}
+	}
+	return err
 }
 
-func measure(mode string, target string, command string, args ...string) time.Duration {
-	connectionFunction := connectionFunctionFor(mode)
-	start := time.Now()
-	cmd, err := boot(command, args...)
+// benchmarksFromConfig returns the benchmarks of the scenarios of a configuration file,
+// restricted to the selected names and to those having one of the tags when there are some, with the
+// profile of the scenarios that do not set one.
+func benchmarksFromConfig(path string, profile string, selected []string, tags []string) ([]*boottime.Benchmark, error) {
+	config, err := boottime.LoadConfigWithProfile(path, profile)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	for {
-		if status, houseKeeper := connectionFunction(target); status == true {
-			duration := time.Since(start)
-			houseKeeper()
-			cmd.Process.Kill()
-			cmd.Process.Wait()
-			return duration
+	smtpSettings = config.SMTP
+	var benchmarks []*boottime.Benchmark
+	for _, scenario := range config.Scenarios {
+		if len(selected) > 0 && !contains(selected, scenario.Name) {
+			continue
+		}
+		if len(tags) > 0 && !hasAnyTag(scenario.Tags, tags) {
+			continue
 		}
+		bench, err := scenario.Benchmark()
+		if err != nil {
+			return nil, err
+		}
+		benchmarks = append(benchmarks, bench)
+	}
+	for _, name := range selected {
+		found := false
+		for _, bench := range benchmarks {
+			found = found || bench.Name == name
+		}
+		if !found {
+			return nil, fmt.Errorf("no such scenario in %s: %s", path, name)
+		}
+	}
+	if len(tags) > 0 && len(benchmarks) == 0 {
+		return nil, fmt.Errorf("no scenario in %s has any of the tags: %s", path, strings.Join(tags, ", "))
 	}
+	return benchmarks, nil
 }
 
-func benchmark(mode string, dryRuns int, runs int, pauseBetweenRuns time.Duration, target string, command string, args ...string) {
+// splitTags returns the tags of --tags, split at commas.
+func splitTags(values []string) []string {
+	var tags []string
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); len(tag) > 0 {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
 
-	color.Cyan("Dry runs")
-	for i := 0; i < dryRuns; i++ {
-		duration := measure(mode, target, command, args...)
-		color.Cyan("  - %s", duration)
-		time.Sleep(pauseBetweenRuns)
+// hasAnyTag tells whether some of the tags of a scenario are in wanted.
+func hasAnyTag(tags []string, wanted []string) bool {
+	for _, tag := range tags {
+		if contains(wanted, tag) {
+			return true
+		}
 	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// runBenchmark runs a benchmark and exports its results, recording the session when recordPath is
+// not empty and appending the results to the journal when journalPath is not empty. The results are
+// also added to out when not nil, in which case there is no default exporter, and checked against
+// a baseline when gate is not nil.
+func runBenchmark(ctx context.Context, bench *boottime.Benchmark, exportSpecs []string, recordPath string, journalPath string, out *resultsOutput, gate *regressionGate) error {
+	outputs, err := newBenchmarkOutputs(bench, exportSpecs, recordPath, out, gate)
+	if err != nil {
+		return err
+	}
+	if len(bench.Name) > 0 {
+		printScenario(bench.Name)
+	}
+	results, err := bench.Run(ctx)
+	return outputs.complete(bench, results, err, journalPath, out)
+}
 
-	durations := make([]float64, runs)
-	color.Green("Runs")
-	for i := 0; i < runs; i++ {
-		duration := measure(mode, target, command, args...)
-		durations[i] = float64(duration.Nanoseconds())
-		color.Green("  - %s", duration)
-		time.Sleep(pauseBetweenRuns)
+// expectedFailure tells whether bench is expected to fail, in which case its failure is reported
+// without failing the session and its success only warned about, as with xfail tests.
+func expectedFailure(ctx context.Context, bench *boottime.Benchmark, err error) bool {
+	if !bench.ExpectedFail || ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		logger.Warn("benchmark failed as expected", "scenario", bench.Name, "error", err)
+	} else {
+		logger.Warn("benchmark expected to fail succeeded", "scenario", bench.Name)
 	}
+	return true
+}
 
-	min, _ := stats.Min(durations)
-	color.Yellow("Min: %s", float64ToDuration(min))
+// reproducible makes reports leave out timestamps and host names, see boottime.Results.Reproducible.
+var reproducible bool
 
-	max, _ := stats.Max(durations)
-	color.Yellow("Max: %s", float64ToDuration(max))
+// logDir is the directory where the output of each run is written, in a subdirectory per named
+// benchmark, when not empty.
+var logDir string
 
-	med, _ := stats.Median(durations)
-	dev, _ := stats.StandardDeviation(durations)
-	color.Yellow("Median: %s (std dev %s)", float64ToDuration(med), float64ToDuration(dev))
+// benchmarkOutputs are where the results of a benchmark go, see runBenchmark.
+type benchmarkOutputs struct {
+	exporters []boottime.Exporter
+	recorder  *boottime.Recorder
+	runLogs   *boottime.RunLogs
+	gate      *regressionGate
+}
 
-	outliers, _ := stats.QuartileOutliers(durations)
-	color.Yellow("Ouliers:")
-	color.Yellow("  - mild: %s", float64DataToDurations(outliers.Mild))
-	color.Yellow("  - extreme: %s", float64DataToDurations(outliers.Extreme))
+// newBenchmarkOutputs creates the exporters of a benchmark and attaches its recorder and run logs.
+func newBenchmarkOutputs(bench *boottime.Benchmark, exportSpecs []string, recordPath string, out *resultsOutput, gate *regressionGate) (*benchmarkOutputs, error) {
+	outputs := &benchmarkOutputs{gate: gate}
+	var err error
+	if out == nil || len(exportSpecs) > 0 {
+		if outputs.exporters, err = newExporters(exportSpecs, bench.Name); err != nil {
+			return nil, err
+		}
+	}
+	if len(recordPath) > 0 {
+		if outputs.recorder, err = boottime.NewRecorder(expandScenario(recordPath, bench.Name)); err != nil {
+			return nil, err
+		}
+		outputs.recorder.Attach(bench)
+	}
+	if len(logDir) > 0 {
+		if outputs.runLogs, err = boottime.NewRunLogs(filepath.Join(logDir, bench.Name)); err != nil {
+			return nil, err
+		}
+		outputs.runLogs.Attach(bench)
+	}
+	return outputs, nil
+}
 
-	percentiles := []float64{75.0, 80.0, 85.0, 90.0, 95.0, 97.5, 98.0, 99.0, 99.9, 100.0}
-	color.Yellow("Percentiles:")
-	for i := range percentiles {
-		r, _ := stats.Percentile(durations, percentiles[i])
-		color.Yellow("  - %f%%: %s", percentiles[i], float64ToDuration(r))
+// complete closes the recording of a benchmark, appends its results to the journal and exports
+// them, returning the error of the benchmark, or else the first export error, or else the
+// regression against the baseline.
+func (o *benchmarkOutputs) complete(bench *boottime.Benchmark, results *boottime.Results, err error, journalPath string, out *resultsOutput) error {
+	recorder, exporters := o.recorder, o.exporters
+	if recorder != nil {
+		if recordErr := recorder.Close(results); recordErr != nil {
+			logger.Error("recording failed", "error", recordErr)
+		}
+	}
+	if o.runLogs != nil {
+		if logsErr := o.runLogs.Close(); logsErr != nil {
+			logger.Error("unable to write the output of the runs", "error", logsErr)
+		}
+	}
+	if results == nil {
+		return err
+	}
+	if len(journalPath) > 0 && len(results.Measured()) > 0 {
+		entry := boottime.NewJournalEntry(bench, results)
+		entry.Commit = journalCommit
+		if journalErr := boottime.AppendJournal(journalPath, entry); journalErr != nil {
+			logger.Error("unable to append to the journal", "error", journalErr)
+		}
+	}
+	if out != nil {
+		out.add(results)
 	}
+	if exportErr := export(exporters, results); err == nil {
+		err = exportErr
+	}
+	if err == nil && o.gate != nil {
+		err = o.gate.check(results)
+	}
+	return err
 }
 
-func float64ToDuration(f float64) time.Duration {
-	return time.Duration(int64(f))
+// splitServerArgs separates the arguments of the server, which follow "--", from those of the
+// program, so that server arguments named like a command, as in "-- selftest serve", are not
+// taken for that command.
+func splitServerArgs(args []string) (program []string, server []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i:i], args[i+1:] // capped, so that appending never overwrites the server arguments
+		}
+	}
+	return args, nil
 }
 
-func float64DataToDurations(data stats.Float64Data) []time.Duration {
-	durations := make([]time.Duration, len(data))
-	for i := range data {
-		durations[i] = float64ToDuration(data[i])
+// secondsOrDuration is a duration flag that also accepts a number of seconds, as --pause used to.
+type secondsOrDuration time.Duration
+
+func (d *secondsOrDuration) Set(value string) error {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		*d = secondsOrDuration(seconds * float64(time.Second))
+		return nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration: %s (expected a duration such as 1m30s, or a number of seconds)", value)
 	}
-	return durations
+	*d = secondsOrDuration(duration)
+	return nil
+}
+
+func (d *secondsOrDuration) String() string {
+	return time.Duration(*d).String()
 }
 
 func main() {
+	args, serverArgs := splitServerArgs(os.Args)
 	app := cli.NewApp()
 
 	app.Name = "time-to-boot-server"
@@ -155,19 +455,274 @@ func main() {
 	app.ArgsUsage = "executable application arguments\n   (tip: use -- to pass flags to the executable, as in --executable python -- -m SimpleHTTPServer 8080)"
 
 	var mode string
+	var style string
+	var logLevelName string
+	var logFormat string
 	var dryRuns int
 	var runs int
-	var pauseDuration int
+	pause := secondsOrDuration(10 * time.Second)
+	var runTimeout time.Duration
+	var readyTimeout time.Duration
+	var onFailure string
+	var settle bool
+	var reserveCPUs int
+	var cpuScore bool
+	var readOnlyRootfs bool
+	var capabilities string
+	var seccomp string
 	var target string
+	var autoTarget bool
 	var executable string
+	var workDir string
+	var stdinFile string
+	var closeStdin bool
+	var launcher string
+	var checkpoint string
+	var deployOptions boottime.DeployOptions
+	var systemdOptions boottime.SystemdOptions
+	var systemdProperties cli.StringSlice
+	var dockerImage string
+	var dockerOptions boottime.DockerOptions
+	var dockerPublish, dockerRunOptions cli.StringSlice
+	var kubernetesOptions boottime.KubernetesOptions
+	var kubernetesPortForward cli.StringSlice
+	var maxProbeRate float64
+	var pollInterval, pollMaxInterval time.Duration
+	var pollBackoff float64
+	var readyAfterRequests int
+	var stableFor time.Duration
+	var calibrationRuns int
+	var calibrationProbeRate float64
+	var jvmMetrics bool
+	var upgradeSignal string
+	var crashRecovery bool
+	var shutdownSignal string
+	var shutdownGrace time.Duration
+	var lingeringSockets string
+	var watchPorts cli.StringSlice
+	var milestones, milestoneMatches, events, eventMatches cli.StringSlice
+	var candidates cli.StringSlice
+	var plots cli.StringSlice
+	var httpOptions boottime.HTTPOptions
+	var httpHeaders cli.StringSlice
+	var tcpOptions boottime.TCPOptions
+	var promOptions boottime.PromOptions
+	var healthOptions boottime.HealthOptions
+	var healthGroups cli.StringSlice
+	var callbackOptions boottime.CallbackOptions
+	var fileOptions boottime.FileOptions
+	var logFileOptions boottime.LogFileOptions
+	var logMatchOptions boottime.LogMatchOptions
+	var exportSpecs cli.StringSlice
+	var recordPath string
+	var notifySpecs cli.StringSlice
+	var configPath string
+	var referencePath string
+	var reportPath string
+	var thresholds verdictThresholds
+	var normalized bool
+	var importFormat, importUnit, importScenario string
+	var selftestDelay time.Duration
+	var selftestRuns int
+	var bisect bisection
+	var sweeping sweep
+	var selftestServe selftestServer
+	var scenarios cli.StringSlice
+	var tags cli.StringSlice
+	var journalPath string
+	var keepLast string
+	var keepPerDay int
+	var detection changeDetection
+	var hostProfilePath string
+	var calibrationDir string
+	var noJournal bool
+	var profileName string
+
+	styleFlag := cli.StringFlag{
+		Name:        "style",
+		Usage:       "console report style: plain, table, fancy, markdown",
+		Value:       "table",
+		Destination: &style,
+	}
+	var replayPhases cli.StringSlice
+	var outputFormat string
+	var outputPath string
+	var statsSpec string
+	var hdrPath string
+	var interactive string
+	var htmlReportPath string
+	var csvPath string
+	var emailRecipients string
+	var baselinePath, regressionThreshold string
+	var baselinePercentile float64
+	exportFlag := cli.StringSliceFlag{
+		Name:  "export",
+		Usage: "exporter of the results as name or name=destination, can be repeated (default: console)",
+		Value: &exportSpecs,
+	}
+	profileFlag := cli.StringFlag{
+		Name:        "profile",
+		Usage:       "preset of runs, pauses, checks and outputs for the machine: " + strings.Join(boottime.ProfileNames(), ", "),
+		Destination: &profileName,
+	}
+	configFlag := cli.StringFlag{
+		Name:        "config",
+		Usage:       "configuration file defining the scenarios to benchmark, instead of the other benchmark flags",
+		Destination: &configPath,
+	}
 
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
 			Name:        "mode",
-			Usage:       "mode for connecting in: http-get, tcp-connect",
+			Usage:       "mode for connecting in: " + strings.Join(boottime.Modes(), ", "),
 			Value:       "http-get",
 			Destination: &mode,
 		},
+		styleFlag,
+		cli.StringFlag{
+			Name:        "log-level",
+			Usage:       "log level: debug, info, warn, error",
+			Value:       "info",
+			Destination: &logLevelName,
+		},
+		cli.StringFlag{
+			Name:        "log-format",
+			Usage:       "log format: text, json",
+			Value:       "text",
+			Destination: &logFormat,
+		},
+		exportFlag,
+		configFlag,
+		cli.StringSliceFlag{
+			Name:  "scenario",
+			Usage: "name of a scenario of the configuration file to run, can be repeated (default: all)",
+			Value: &scenarios,
+		},
+		cli.StringSliceFlag{
+			Name:  "tags",
+			Usage: "tags of the scenarios of the configuration file to run, comma-separated or repeated, a scenario runs when it has any of them",
+			Value: &tags,
+		},
+		cli.StringFlag{
+			Name:        "record",
+			Usage:       "file where to record every event of the session, for later use with the replay command\n\t({scenario} in export destinations and record paths is replaced by the scenario name)",
+			Destination: &recordPath,
+		},
+		cli.StringSliceFlag{
+			Name:  "notify",
+			Usage: "notify the end of the session: desktop for a desktop notification, or cmd:command for a shell command line getting BOOT_NOTIFY_STATUS and BOOT_NOTIFY_MESSAGE, can be repeated",
+			Value: &notifySpecs,
+		},
+		cli.BoolFlag{
+			Name:        "reproducible",
+			Usage:       "leave timestamps and host names out of the reports and output formats, so that reports of the same runs are byte-identical on any machine",
+			Destination: &reproducible,
+		},
+		cli.StringFlag{
+			Name:        "log-dir",
+			Usage:       "directory where the output of each run is written, as run-<n>.out and run-<n>.err, in a subdirectory per scenario",
+			Destination: &logDir,
+		},
+		cli.StringFlag{
+			Name:        "output-format",
+			Usage:       "console, json for a document with every run and the statistics, or markdown for tables of the runs and statistics, progress going to the standard error",
+			Value:       consoleOutput,
+			Destination: &outputFormat,
+		},
+		cli.StringFlag{
+			Name:        "output",
+			Usage:       "file where the json and markdown output formats write (default: standard output)",
+			Destination: &outputPath,
+		},
+		cli.StringFlag{
+			Name:        "interactive",
+			Usage:       "auto, yes or no: non-interactive sessions, as when the standard output is not a terminal with auto, default to the plain style, without colors and with timestamped progress lines (default: auto)",
+			Destination: &interactive,
+		},
+		cli.BoolFlag{
+			Name:        "summary-only",
+			Usage:       "do not print the runs as they complete, only the report",
+			Destination: &summaryOnly,
+		},
+		cli.BoolFlag{
+			Name:        "show-attempts",
+			Usage:       "print every probe attempt with its time and outcome, to debug the readiness detection",
+			Destination: &showAttempts,
+		},
+		cli.StringFlag{
+			Name:        "stats",
+			Usage:       "statistics of the console report, comma-separated among min, max, mean, geomean, median, median-ci (95% confidence interval) and stddev (default: min,max,median,stddev)",
+			Destination: &statsSpec,
+		},
+		cli.IntFlag{
+			Name:        "histogram-buckets",
+			Usage:       "number of buckets of the histogram of the console report, 0 for none",
+			Value:       histogramBuckets,
+			Destination: &histogramBuckets,
+		},
+		cli.StringFlag{
+			Name:        "hdr-file",
+			Usage:       "file where to write the percentile distribution of the runs in the HdrHistogram format, like --export hdr=file",
+			Destination: &hdrPath,
+		},
+		cli.StringFlag{
+			Name:        "html-report",
+			Usage:       "file where to write a self-contained HTML page with charts and the statistics, like --export html=file",
+			Destination: &htmlReportPath,
+		},
+		cli.StringFlag{
+			Name:        "csv",
+			Usage:       "file where to write the index, start time and duration of every run as CSV, like --export csv=file",
+			Destination: &csvPath,
+		},
+		cli.StringSliceFlag{
+			Name:  "plot",
+			Usage: "plot of the distribution of the boot times, as boxplot=file or violin=file, in SVG or in PNG when the file ends with .png, like --export, can be repeated",
+			Value: &plots,
+		},
+		cli.StringFlag{
+			Name:        "baseline",
+			Usage:       "results exported with --export json to compare with, failing the benchmark when slower than --fail-if-slower-than ({scenario} is replaced by the scenario name)",
+			Destination: &baselinePath,
+		},
+		cli.StringFlag{
+			Name:        "fail-if-slower-than",
+			Usage:       "percentage of the baseline by which the boot time may be slower before failing, as in 5% (default: 5%)",
+			Destination: &regressionThreshold,
+		},
+		cli.Float64Flag{
+			Name:        "baseline-percentile",
+			Usage:       "percentile of the boot times compared with the baseline",
+			Value:       50,
+			Destination: &baselinePercentile,
+		},
+		cli.StringFlag{
+			Name:        "email-report",
+			Usage:       "comma-separated recipients to whom the report is emailed after completion, like --export email=recipients, see the smtp block of configuration files",
+			Destination: &emailRecipients,
+		},
+		cli.StringFlag{
+			Name:        "journal",
+			Usage:       "journal where the results of every benchmark are appended, see the journal command (default: ~/.time-to-boot-server/journal.jsonl with --config or --profile)",
+			Destination: &journalPath,
+		},
+		cli.StringFlag{
+			Name:        "commit",
+			Usage:       "commit of the benchmarked server recorded in the journal (default: the git HEAD of the working directory, if any)",
+			Destination: &journalCommit,
+		},
+		cli.BoolFlag{
+			Name:        "no-journal",
+			Usage:       "do not append the results to the journal",
+			Destination: &noJournal,
+		},
+		cli.StringFlag{
+			Name:        "host-profile",
+			Usage:       "host profile embedded in the results when it describes the current host, see the calibrate command",
+			Value:       defaultHostProfilePath(),
+			Destination: &hostProfilePath,
+		},
+		profileFlag,
 		cli.IntFlag{
 			Name:        "dry-runs",
 			Usage:       "number of dry runs",
@@ -180,11 +735,56 @@ func main() {
 			Value:       20,
 			Destination: &runs,
 		},
+		cli.GenericFlag{
+			Name:  "pause",
+			Usage: "pause between runs, as in 10s or 1m30s, or a number of seconds",
+			Value: &pause,
+		},
+		cli.DurationFlag{
+			Name:        "run-timeout",
+			Usage:       "time after which a run that did not complete is failed and its server killed (e.g. 2m), none when 0",
+			Destination: &runTimeout,
+		},
+		cli.DurationFlag{
+			Name:        "ready-timeout",
+			Usage:       "time after which a run whose server is not ready yet is failed and its server killed (e.g. 30s), only --run-timeout applying when 0",
+			Destination: &readyTimeout,
+		},
+		cli.StringFlag{
+			Name:        "on-failure",
+			Usage:       "what to do when a run fails: abort, skip to the next run, or retry it up to 3 times",
+			Value:       boottime.AbortOnFailure,
+			Destination: &onFailure,
+		},
+		cli.BoolFlag{
+			Name:        "settle",
+			Usage:       "after each pause, wait for the load, CPU usage and disk I/O of the system to return to their level before the first run (Linux only)",
+			Destination: &settle,
+		},
+		cli.BoolFlag{
+			Name:        "cpu-score",
+			Usage:       "measure a CPU score of the host before the runs, for compare --normalize to compare hosts",
+			Destination: &cpuScore,
+		},
 		cli.IntFlag{
-			Name:        "pause",
-			Usage:       "pause duration (in seconds) between runs",
-			Value:       10,
-			Destination: &pauseDuration,
+			Name:        "reserve-cpus",
+			Usage:       "number of CPUs reserved to probing, the server running on the other CPUs (Linux only, needs taskset)",
+			Destination: &reserveCPUs,
+		},
+		cli.BoolFlag{
+			Name:        "read-only-rootfs",
+			Usage:       "run the server with its root filesystem mounted read-only (Linux only, exec and shell launchers, needs unshare)",
+			Destination: &readOnlyRootfs,
+		},
+		cli.StringFlag{
+			Name:        "capabilities",
+			Usage:       "capabilities of the server: none, default (those of container runtimes) or a comma-separated list to keep (Linux only, exec and shell launchers)",
+			Destination: &capabilities,
+		},
+		cli.StringFlag{
+			Name:        "seccomp",
+			Usage:       "seccomp profile of the server: runtime-default, close to that of container runtimes (Linux only, exec and shell launchers)",
+			Destination: &seccomp,
 		},
 		cli.StringFlag{
 			Name:        "target",
@@ -192,21 +792,1087 @@ func main() {
 			Value:       "http://localhost:8080/",
 			Destination: &target,
 		},
+		cli.BoolFlag{
+			Name:        "auto-target",
+			Usage:       "probe the first address the server listens on, keeping the scheme and path of URL targets (Linux only)",
+			Destination: &autoTarget,
+		},
 		cli.StringFlag{
 			Name:        "executable",
 			Usage:       "executable to run",
 			Value:       "",
 			Destination: &executable,
 		},
+		cli.StringFlag{
+			Name:        "workdir",
+			Usage:       "directory to run the executable from, relative executable paths being resolved from it (exec, shell and deploy launchers)",
+			Destination: &workDir,
+		},
+		cli.StringFlag{
+			Name:        "stdin-file",
+			Usage:       "file fed to the standard input of the executable, instead of /dev/null (exec, shell and deploy launchers)",
+			Destination: &stdinFile,
+		},
+		cli.BoolFlag{
+			Name:        "close-stdin",
+			Usage:       "run the executable with its standard input closed, instead of reading /dev/null (exec, shell and deploy launchers)",
+			Destination: &closeStdin,
+		},
+		cli.StringFlag{
+			Name:        "launcher",
+			Usage:       "launcher running the executable: " + strings.Join(boottime.LauncherNames(), ", "),
+			Value:       boottime.DefaultLauncher,
+			Destination: &launcher,
+		},
+		cli.StringFlag{
+			Name:        "checkpoint",
+			Usage:       "checkpoint directory restored by the crac and criu launchers",
+			Destination: &checkpoint,
+		},
+		cli.StringFlag{
+			Name:        "deploy.teardown",
+			Usage:       "shell command run after each run of the deploy launcher, removing what the deploy created",
+			Destination: &deployOptions.Teardown,
+		},
+		cli.StringSliceFlag{
+			Name:  "systemd.property",
+			Usage: "resource control property of the scope of the systemd-scope launcher, as in 'CPUQuota=50%', may be repeated",
+			Value: &systemdProperties,
+		},
+		cli.BoolFlag{
+			Name:        "systemd.user",
+			Usage:       "run the scope of the systemd-scope launcher in the service manager of the user",
+			Destination: &systemdOptions.User,
+		},
+		cli.StringFlag{
+			Name:        "docker",
+			Usage:       "image to boot in a container with the docker launcher, as in nginx:1.27, instead of --executable, the arguments being the command of the container",
+			Destination: &dockerImage,
+		},
+		cli.StringSliceFlag{
+			Name:  "docker.publish",
+			Usage: "port of the container published on the host by the docker launcher, as in 8080:8080, may be repeated",
+			Value: &dockerPublish,
+		},
+		cli.StringFlag{
+			Name:        "docker.cpus",
+			Usage:       "number of CPUs of the container of the docker launcher, as in 1.5",
+			Destination: &dockerOptions.CPUs,
+		},
+		cli.StringFlag{
+			Name:        "docker.memory",
+			Usage:       "memory limit of the container of the docker launcher, as in 512m",
+			Destination: &dockerOptions.Memory,
+		},
+		cli.StringSliceFlag{
+			Name:  "docker.option",
+			Usage: "other docker run option of the docker launcher, as in --network=host, may be repeated",
+			Value: &dockerRunOptions,
+		},
+		cli.StringFlag{
+			Name:        "kubernetes.context",
+			Usage:       "kubectl context of the kubernetes launcher and kubernetes-ready mode, the current one when empty",
+			Destination: &kubernetesOptions.Context,
+		},
+		cli.StringFlag{
+			Name:        "kubernetes.namespace",
+			Usage:       "namespace of the kubernetes launcher and kubernetes-ready mode, that of the context when empty",
+			Destination: &kubernetesOptions.Namespace,
+		},
+		cli.StringFlag{
+			Name:        "kubernetes.resource",
+			Usage:       "workload whose readiness the kubernetes-ready mode waits for, and that ports are forwarded to, as in deployment/app",
+			Destination: &kubernetesOptions.Resource,
+		},
+		cli.StringSliceFlag{
+			Name:  "kubernetes.port-forward",
+			Usage: "local port forwarded to the resource by the kubernetes launcher during the runs, as in 8080:8080, may be repeated",
+			Value: &kubernetesPortForward,
+		},
+		cli.Float64Flag{
+			Name:        "max-probe-rate",
+			Usage:       "maximum number of probe attempts per second, unlimited when 0",
+			Destination: &maxProbeRate,
+		},
+		cli.DurationFlag{
+			Name:        "poll-interval",
+			Usage:       "minimum time between the starts of consecutive probe attempts (e.g. 10ms), none when 0",
+			Destination: &pollInterval,
+		},
+		cli.Float64Flag{
+			Name:        "poll-backoff",
+			Usage:       "factor by which the polling interval grows after each failed probe attempt (e.g. 1.5), constant when 0 or 1",
+			Destination: &pollBackoff,
+		},
+		cli.DurationFlag{
+			Name:        "poll-max-interval",
+			Usage:       "bound of the polling interval grown by --poll-backoff, 1s when 0",
+			Destination: &pollMaxInterval,
+		},
+		cli.IntFlag{
+			Name:        "ready-after-requests",
+			Usage:       "number of consecutive successful probe attempts after which the server is ready",
+			Value:       1,
+			Destination: &readyAfterRequests,
+		},
+		cli.DurationFlag{
+			Name:        "stable-for",
+			Usage:       "window during which probe attempts must keep succeeding once the server is ready (e.g. 2s), readiness remaining the start of the window",
+			Destination: &stableFor,
+		},
+		cli.IntFlag{
+			Name:        "calibration-runs",
+			Usage:       "number of pairs of calibration runs estimating how much probing delays readiness",
+			Destination: &calibrationRuns,
+		},
+		cli.Float64Flag{
+			Name:        "calibration-probe-rate",
+			Usage:       "probe rate of the reference calibration runs",
+			Value:       boottime.DefaultCalibrationProbeRate,
+			Destination: &calibrationProbeRate,
+		},
+		cli.BoolFlag{
+			Name:        "jvm-metrics",
+			Usage:       "capture classes loaded, JIT time and GC pauses at readiness with jcmd, when the executable is a JVM",
+			Destination: &jvmMetrics,
+		},
+		cli.StringFlag{
+			Name:        "upgrade-signal",
+			Usage:       "signal sent to the ready server to upgrade in place (e.g. USR2), measuring until a new generation serves",
+			Destination: &upgradeSignal,
+		},
+		cli.BoolFlag{
+			Name:        "crash-recovery",
+			Usage:       "kill the ready server and start it again, measuring its recovery boot as a separate distribution",
+			Destination: &crashRecovery,
+		},
+		cli.StringFlag{
+			Name:        "shutdown-signal",
+			Usage:       "signal stopping the server at the end of each run (e.g. INT), KILL to kill it at once",
+			Value:       boottime.DefaultShutdownSignal,
+			Destination: &shutdownSignal,
+		},
+		cli.DurationFlag{
+			Name:        "shutdown-grace",
+			Usage:       "how long the server may take to stop after the shutdown signal before it is killed",
+			Value:       boottime.DefaultShutdownGrace,
+			Destination: &shutdownGrace,
+		},
+		cli.StringFlag{
+			Name:        "lingering-sockets",
+			Usage:       "what to do with sockets lingering on the target port before a run: flag, wait, ignore",
+			Value:       boottime.FlagLingeringSockets,
+			Destination: &lingeringSockets,
+		},
+		cli.StringSliceFlag{
+			Name:  "watch-port",
+			Usage: "port opened by the server whose opening time is recorded, as name=host:port, can be repeated",
+			Value: &watchPorts,
+		},
+		cli.StringSliceFlag{
+			Name:  "candidate",
+			Usage: "executable compared with the other candidates, as name=command arguments..., instead of --executable, their runs being interleaved, can be repeated",
+			Value: &candidates,
+		},
+		cli.StringSliceFlag{
+			Name:  "milestone",
+			Usage: "milestone of the boot waited for before the probe of the benchmark, in order, as name=mode:target (e.g. port=tcp-connect:localhost:8080), the mode and target of the benchmark when omitted, can be repeated",
+			Value: &milestones,
+		},
+		cli.StringSliceFlag{
+			Name:  "milestone.match",
+			Usage: "condition, pattern or regular expression of the mode of a milestone, as name=match, can be repeated",
+			Value: &milestoneMatches,
+		},
+		cli.StringSliceFlag{
+			Name:  "event",
+			Usage: "lifecycle event waited for once the server is ready, in order, as name=mode:target (e.g. jobs=logfile:/var/log/app.log), the mode and target of the benchmark when omitted, can be repeated",
+			Value: &events,
+		},
+		cli.StringSliceFlag{
+			Name:  "event.match",
+			Usage: "condition, pattern or regular expression of the mode of an event, as name=match, can be repeated",
+			Value: &eventMatches,
+		},
+		cli.DurationFlag{
+			Name:        "http.timeout",
+			Usage:       "timeout of each HTTP request in the http-get mode (e.g. 500ms), none when 0",
+			Destination: (*time.Duration)(&httpOptions.Timeout),
+		},
+		cli.StringSliceFlag{
+			Name:  "http.header",
+			Usage: "header added to each HTTP request in the http-get mode, as 'Name: value', can be repeated",
+			Value: &httpHeaders,
+		},
+		cli.StringFlag{
+			Name:        "http.bearer-token",
+			Usage:       "bearer token sent in HTTP requests in the http-get mode, such as ${env:TOKEN} or file:/run/secrets/token",
+			Destination: &httpOptions.BearerToken,
+		},
+		cli.StringFlag{
+			Name:        "http.connection",
+			Usage:       "in the http-get mode, whether attempts reuse connections or open new ones: reuse, new",
+			Value:       boottime.ReuseConnections,
+			Destination: &httpOptions.Connection,
+		},
+		cli.StringFlag{
+			Name:        "http.expect-status",
+			Usage:       "in the http-get mode, response statuses telling that the server is ready, as codes and ranges (e.g. 200-299,302), 200 by default",
+			Destination: &httpOptions.ExpectStatus,
+		},
+		cli.StringFlag{
+			Name:        "http.redirects",
+			Usage:       "in the http-get mode, whether redirects are followed or their own status is checked: follow (default), keep",
+			Destination: &httpOptions.Redirects,
+		},
+		cli.StringFlag{
+			Name:        "http.generation-header",
+			Usage:       "in the http-get mode, response header identifying the generation of the server, to detect upgrades",
+			Destination: &httpOptions.GenerationHeader,
+		},
+		cli.BoolFlag{
+			Name:        "http.insecure",
+			Usage:       "in the http-get mode, do not verify the certificate of https targets",
+			Destination: &httpOptions.Insecure,
+		},
+		cli.StringFlag{
+			Name:        "http.ca-cert",
+			Usage:       "in the http-get mode, PEM bundle of the certificate authorities trusted for https targets instead of those of the system",
+			Destination: &httpOptions.CACert,
+		},
+		cli.StringFlag{
+			Name:        "http.client-cert",
+			Usage:       "in the http-get mode, PEM file of the client certificate presented to https targets, with --http.client-key",
+			Destination: &httpOptions.ClientCert,
+		},
+		cli.StringFlag{
+			Name:        "http.client-key",
+			Usage:       "in the http-get mode, PEM file of the key of the client certificate",
+			Destination: &httpOptions.ClientKey,
+		},
+		cli.StringFlag{
+			Name:        "http.server-name",
+			Usage:       "in the http-get mode, server name sent with SNI and verified in the certificate of https targets, the host of the target by default",
+			Destination: &httpOptions.ServerName,
+		},
+		cli.DurationFlag{
+			Name:        "tcp.timeout",
+			Usage:       "timeout of each connection attempt in the tcp-connect mode (e.g. 500ms), none when 0",
+			Destination: (*time.Duration)(&tcpOptions.Timeout),
+		},
+		cli.StringFlag{
+			Name:        "prom.condition",
+			Usage:       "in the prom-metric mode, condition on a metric of the target making the server ready, as in 'app_ready == 1'",
+			Destination: &promOptions.Condition,
+		},
+		cli.DurationFlag{
+			Name:        "prom.timeout",
+			Usage:       "timeout of each scrape in the prom-metric mode (e.g. 500ms), none when 0",
+			Destination: (*time.Duration)(&promOptions.Timeout),
+		},
+		cli.StringFlag{
+			Name:        "prom.generation-metric",
+			Usage:       "in the prom-metric mode, metric whose value identifies the generation of the server, to detect upgrades",
+			Destination: &promOptions.GenerationMetric,
+		},
+		cli.StringFlag{
+			Name:        "health.framework",
+			Usage:       "in the health-groups mode, framework whose health groups are probed: " + strings.Join(healthFrameworks(), ", "),
+			Destination: &healthOptions.Framework,
+		},
+		cli.StringSliceFlag{
+			Name:  "health.group",
+			Usage: "health group probed in the health-groups mode, as 'name=path' relative to the target, can be repeated",
+			Value: &healthGroups,
+		},
+		cli.DurationFlag{
+			Name:        "health.timeout",
+			Usage:       "timeout of each health request in the health-groups mode (e.g. 500ms), none when 0",
+			Destination: (*time.Duration)(&healthOptions.Timeout),
+		},
+		cli.IntFlag{
+			Name:        "callback.port",
+			Usage:       "local port of the callback listener in the callback mode, a free port when 0",
+			Destination: &callbackOptions.Port,
+		},
+		cli.StringFlag{
+			Name:        "file.match",
+			Usage:       "in the file mode, regular expression that the content of the target file must match, existence only when empty",
+			Destination: &fileOptions.Match,
+		},
+		cli.StringFlag{
+			Name:        "logfile.pattern",
+			Usage:       "in the logfile mode, regular expression matched by the line telling that the server is ready",
+			Destination: &logFileOptions.Pattern,
+		},
+		cli.StringFlag{
+			Name:        "logfile.unit",
+			Usage:       "in the logfile mode, systemd unit whose journal is followed instead of the target log file",
+			Destination: &logFileOptions.Unit,
+		},
+		cli.StringFlag{
+			Name:        "log-match.stream",
+			Usage:       "in the log-match mode, the only output stream watched for the readiness line: stdout, stderr",
+			Destination: &logMatchOptions.Stream,
+		},
 	}
 
-	app.Action = func(c *cli.Context) error {
-		if len(executable) == 0 {
-			log.Fatal("An executable must be specified")
+	app.Before = func(c *cli.Context) error {
+		level, err := boottime.ParseLogLevel(logLevelName)
+		if err != nil {
+			return err
+		}
+		configured, err := boottime.NewLogger(os.Stderr, level, logFormat)
+		if err != nil {
+			return err
+		}
+		logger = configured
+		interactiveConsole, err := interactiveSession(interactive)
+		if err != nil {
+			return err
 		}
-		benchmark(mode, dryRuns, runs, time.Duration(pauseDuration)*time.Second, target, executable, c.Args()...)
+		if interactive == "yes" {
+			color.NoColor = false
+		} else if !interactiveConsole {
+			color.NoColor = true
+			timestamps = true
+			if !c.IsSet("style") {
+				style = "plain"
+			}
+		}
+		if notifiers, err = parseNotifiers(notifySpecs); err != nil {
+			return err
+		}
+		boottime.RegisterExporter("console", consoleExporter(&style))
+		boottime.RegisterExporter("email", emailExporter)
+		boottime.RegisterExporter("html", htmlExporter)
 		return nil
 	}
 
-	app.Run(os.Args)
+	// setupBenchmarks returns the benchmarks defined by the configuration file or by the flags, with
+	// the profile applied and the arguments of c as server arguments.
+	setupBenchmarks := func(c *cli.Context) ([]*boottime.Benchmark, error) {
+		if len(profileName) > 0 {
+			profile, err := boottime.LookupProfile(profileName)
+			if err != nil {
+				return nil, err
+			}
+			outputs := profileOutputs[profileName]
+			if !c.GlobalIsSet("style") && len(outputs.style) > 0 {
+				style = outputs.style
+			}
+			if !c.GlobalIsSet("no-journal") && outputs.noJournal {
+				noJournal = true
+			}
+			if !c.GlobalIsSet("dry-runs") {
+				dryRuns = profile.DryRuns
+			}
+			if !c.GlobalIsSet("runs") {
+				runs = profile.Runs
+			}
+			if !c.GlobalIsSet("pause") {
+				pause = secondsOrDuration(profile.Pause)
+			}
+			if !c.GlobalIsSet("settle") {
+				settle = profile.Settle
+			}
+		}
+		var benchmarks []*boottime.Benchmark
+		if len(configPath) > 0 {
+			if len(candidates) > 0 {
+				return nil, errors.New("--candidate cannot be used with --config")
+			}
+			var err error
+			if benchmarks, err = benchmarksFromConfig(configPath, profileName, scenarios, splitTags(tags)); err != nil {
+				return nil, err
+			}
+		} else {
+			if len(scenarios) > 0 {
+				return nil, errors.New("--scenario requires --config")
+			}
+			if len(tags) > 0 {
+				return nil, errors.New("--tags requires --config")
+			}
+			if len(dockerImage) > 0 {
+				if len(executable) > 0 {
+					return nil, errors.New("--docker replaces --executable")
+				}
+				if c.GlobalIsSet("launcher") && launcher != "docker" {
+					return nil, fmt.Errorf("--docker runs the docker launcher, not %s", launcher)
+				}
+				executable, launcher = dockerImage, "docker"
+			}
+			if len(executable) == 0 && len(candidates) == 0 {
+				return nil, errors.New("an executable must be specified")
+			}
+			parsedMilestones, err := parseEvents("milestone", milestones, milestoneMatches)
+			if err != nil {
+				return nil, err
+			}
+			parsedEvents, err := parseEvents("event", events, eventMatches)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkModeFlags(c, mode, append(append([]boottime.LifecycleEvent{}, parsedMilestones...), parsedEvents...)); err != nil {
+				return nil, err
+			}
+			headers, err := parseHeaders(httpHeaders)
+			if err != nil {
+				return nil, err
+			}
+			httpOptions.Headers = headers
+			if healthOptions.Groups, err = parseAssignments(healthGroups, "health group"); err != nil {
+				return nil, err
+			}
+			ports, err := parseAssignments(watchPorts, "watched port")
+			if err != nil {
+				return nil, err
+			}
+			if systemdOptions.Properties, err = parseAssignments(systemdProperties, "systemd property"); err != nil {
+				return nil, err
+			}
+			dockerOptions.Publish, dockerOptions.Options = dockerPublish, dockerRunOptions
+			kubernetesOptions.PortForward = kubernetesPortForward
+			benchmarks = append(benchmarks, &boottime.Benchmark{
+				Profile:    profileName,
+				Mode:       mode,
+				Target:     target,
+				AutoTarget: autoTarget,
+				Command:    executable,
+				Args:       append(c.Args(), serverArgs...),
+				WorkDir:    workDir,
+				StdinFile:  stdinFile,
+				CloseStdin: closeStdin,
+				Launcher:   launcher,
+				Checkpoint: checkpoint,
+				Deploy:     deployOptions,
+				Systemd:    systemdOptions,
+				Docker:     dockerOptions,
+				Kubernetes: kubernetesOptions,
+				DryRuns:    dryRuns,
+				Runs:       runs,
+				Pause:      time.Duration(pause),
+				RunTimeout: runTimeout,
+				OnFailure:  onFailure,
+				Settle:     settle,
+				HTTP:       httpOptions,
+				TCP:        tcpOptions,
+				Prom:       promOptions,
+				Health:     healthOptions,
+				Callback:   callbackOptions,
+				File:       fileOptions,
+				LogFile:    logFileOptions,
+				LogMatch:   logMatchOptions,
+
+				ReadyTimeout:         readyTimeout,
+				MaxProbeRate:         maxProbeRate,
+				PollInterval:         pollInterval,
+				PollBackoff:          pollBackoff,
+				PollMaxInterval:      pollMaxInterval,
+				ReadyAfterRequests:   readyAfterRequests,
+				StableFor:            stableFor,
+				CalibrationRuns:      calibrationRuns,
+				CalibrationProbeRate: calibrationProbeRate,
+				CollectJVMMetrics:    jvmMetrics,
+				ReservedCPUs:         reserveCPUs,
+				MeasureCPUScore:      cpuScore,
+				ReadOnlyRootfs:       readOnlyRootfs,
+				Capabilities:         capabilities,
+				Seccomp:              seccomp,
+				UpgradeSignal:        upgradeSignal,
+				CrashRecovery:        crashRecovery,
+				ShutdownSignal:       shutdownSignal,
+				ShutdownGrace:        shutdownGrace,
+				LingeringSockets:     lingeringSockets,
+				WatchPorts:           ports,
+				Milestones:           parsedMilestones,
+				Events:               parsedEvents,
+			})
+			if len(candidates) > 0 {
+				if len(executable) > 0 {
+					return nil, errors.New("--candidate replaces --executable")
+				}
+				if benchmarks, err = candidateBenchmarks(benchmarks[0], candidates); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, bench := range benchmarks {
+			if err := bench.Validate(); err != nil {
+				if len(bench.Name) > 0 {
+					err = fmt.Errorf("scenario %s: %v", bench.Name, err)
+				}
+				return nil, err
+			}
+		}
+		return benchmarks, nil
+	}
+
+	app.Commands = []cli.Command{
+		{
+			Name:      "replay",
+			Usage:     "Regenerate reports from a session recorded with --record",
+			ArgsUsage: "archive",
+			Flags: []cli.Flag{styleFlag, exportFlag,
+				cli.StringSliceFlag{
+					Name:  "phase",
+					Usage: "phase marked by the first line of the server output of each run matching a regular expression, as name=regexp, can be repeated",
+					Value: &replayPhases,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 1 {
+					return errors.New("replay expects the path of a session archive")
+				}
+				markers, err := parseLogMarkers(replayPhases)
+				if err != nil {
+					return err
+				}
+				exporters, err := newExporters(exportSpecs, "")
+				if err != nil {
+					return err
+				}
+				results, err := boottime.Replay(c.Args().First(), markers...)
+				if results == nil {
+					return err
+				}
+				if exportErr := export(exporters, results); err == nil {
+					err = exportErr
+				}
+				return err
+			},
+		},
+		{
+			Name:  "journal",
+			Usage: "Browse the journal of past results",
+			Subcommands: []cli.Command{
+				{
+					Name:      "show",
+					Usage:     "Show the past results of a scenario, grouped by setup, or only those of its exact current setup with --config",
+					ArgsUsage: "[scenario]",
+					Flags:     []cli.Flag{styleFlag, configFlag, profileFlag},
+					Action: func(c *cli.Context) error {
+						if c.NArg() > 1 {
+							return errors.New("journal show expects at most a scenario name")
+						}
+						return showJournal(os.Stdout, journalOrDefault(journalPath), configPath, profileName, c.Args().First(), style)
+					},
+				},
+				{
+					Name:  "prune",
+					Usage: "Downsample the entries older than --keep-last to --keep-per-day entries per day, scenario and setup",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:        "keep-last",
+							Usage:       "period during which all the entries are kept, in days such as 90d or as a duration",
+							Value:       "90d",
+							Destination: &keepLast,
+						},
+						cli.IntFlag{
+							Name:        "keep-per-day",
+							Usage:       "number of the most recent entries kept per day, scenario and setup beyond --keep-last, 0 to drop them",
+							Value:       1,
+							Destination: &keepPerDay,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						if c.NArg() > 0 {
+							return errors.New("journal prune expects no argument")
+						}
+						return pruneJournal(os.Stdout, journalOrDefault(journalPath), keepLast, keepPerDay)
+					},
+				},
+				{
+					Name:      "detect",
+					Usage:     "Find when the boot time of a scenario, or of every scenario, shifted, for each setup",
+					ArgsUsage: "[scenario]",
+					Flags: []cli.Flag{
+						styleFlag,
+						cli.Float64Flag{
+							Name:        "alpha",
+							Usage:       "significance level of a change",
+							Value:       0.01,
+							Destination: &detection.alpha,
+						},
+						cli.Float64Flag{
+							Name:        "min-shift",
+							Usage:       "minimum change of the median boot time, in percent",
+							Value:       5,
+							Destination: &detection.minShift,
+						},
+						cli.IntFlag{
+							Name:        "min-entries",
+							Usage:       "minimum number of journal entries before and after a change",
+							Value:       3,
+							Destination: &detection.minEntries,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						if c.NArg() > 1 {
+							return errors.New("journal detect expects at most a scenario name")
+						}
+						if detection.minEntries < 1 {
+							return errors.New("--min-entries must be at least 1")
+						}
+						return detectJournalChanges(os.Stdout, journalOrDefault(journalPath), c.Args().First(), detection, style)
+					},
+				},
+			},
+		},
+		{
+			Name:  "calibrate",
+			Usage: "Measure the process spawn latency, TCP loopback round trip, disk read throughput and timer resolution of the host, and store them at --host-profile",
+			Flags: []cli.Flag{
+				styleFlag,
+				cli.StringFlag{
+					Name:        "dir",
+					Usage:       "directory where the disk read throughput is measured, the default directory for temporary files when empty",
+					Destination: &calibrationDir,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				ctx, stop := interruptibleContext()
+				defer stop()
+				return calibrateHost(ctx, os.Stdout, hostProfilePath, calibrationDir, style)
+			},
+		},
+		{
+			Name:      "import",
+			Usage:     "Report samples measured by other tools, such as hyperfine exports, CSV files or JSON arrays of durations",
+			ArgsUsage: "file",
+			Flags: []cli.Flag{
+				styleFlag,
+				exportFlag,
+				cli.StringFlag{
+					Name:        "format",
+					Usage:       "format of the samples: csv, json or hyperfine, guessed from the file when empty",
+					Destination: &importFormat,
+				},
+				cli.StringFlag{
+					Name:        "unit",
+					Usage:       "unit of durations given as plain numbers: s, ms, us or ns",
+					Value:       "ms",
+					Destination: &importUnit,
+				},
+				cli.StringFlag{
+					Name:        "scenario",
+					Usage:       "scenario name of the samples that are not named, the file name by default",
+					Destination: &importScenario,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 1 {
+					return errors.New("import expects the path of a samples file, or - for the standard input")
+				}
+				return importSamples(c.Args().First(), importFormat, importUnit, importScenario, exportSpecs)
+			},
+		},
+		{
+			Name:      "bisect",
+			Usage:     "Find the first commit whose boot time is over a threshold with git bisect, building and benchmarking each commit to test",
+			ArgsUsage: "[-- server arguments]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "good",
+					Usage:       "commit known to boot fast enough",
+					Destination: &bisect.good,
+				},
+				cli.StringFlag{
+					Name:        "bad",
+					Usage:       "commit known to boot too slowly",
+					Value:       "HEAD",
+					Destination: &bisect.bad,
+				},
+				cli.StringFlag{
+					Name:        "build",
+					Usage:       "shell command building the server at each commit, as in 'make build'",
+					Destination: &bisect.build,
+				},
+				cli.StringFlag{
+					Name:        "threshold",
+					Usage:       "median boot time over which a commit is bad, as in 250ms, or percentage over the median of the good commit, as in 10%",
+					Destination: &bisect.threshold,
+				},
+				cli.IntFlag{
+					Name:        "runs-per-commit",
+					Usage:       "number of measured runs per commit",
+					Value:       5,
+					Destination: &bisect.runs,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if len(bisect.good) == 0 || len(bisect.threshold) == 0 {
+					return errors.New("bisect requires --good and --threshold")
+				}
+				if bisect.runs < 1 {
+					return errors.New("--runs-per-commit must be at least 1")
+				}
+				if len(candidates) > 0 {
+					return errors.New("--candidate cannot be used with bisect")
+				}
+				benchmarks, err := setupBenchmarks(c)
+				if err != nil {
+					return err
+				}
+				if len(benchmarks) != 1 {
+					return errors.New("bisect benchmarks a single scenario, select it with --scenario")
+				}
+				bench := benchmarks[0]
+				printProgress(bench)
+				bench.Logger = logger
+				ctx, stop := interruptibleContext()
+				defer stop()
+				started := time.Now()
+				err = bisect.run(ctx, bench)
+				notifyCompletion(ctx, "bisect", started, err)
+				return err
+			},
+		},
+		{
+			Name:      "sweep",
+			Usage:     "Build and benchmark each commit of a range, appending the results to the journal with their commit, as to backfill the history",
+			ArgsUsage: "[-- server arguments]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "commits",
+					Usage:       "range of commits to benchmark, as in HEAD~20..HEAD",
+					Destination: &sweeping.commits,
+				},
+				cli.StringFlag{
+					Name:        "build",
+					Usage:       "shell command building the server at each commit, as in 'make build'",
+					Destination: &sweeping.build,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if len(sweeping.commits) == 0 {
+					return errors.New("sweep requires --commits")
+				}
+				if len(candidates) > 0 {
+					return errors.New("--candidate cannot be used with sweep")
+				}
+				benchmarks, err := setupBenchmarks(c)
+				if err != nil {
+					return err
+				}
+				tableStyle, err := tableStyleFor(style)
+				if err != nil {
+					return err
+				}
+				journal := journalOrDefault(journalPath)
+				if noJournal {
+					journal = ""
+				}
+				for _, bench := range benchmarks {
+					printProgress(bench)
+					bench.Logger = logger
+				}
+				ctx, stop := interruptibleContext()
+				defer stop()
+				started := time.Now()
+				err = sweeping.run(ctx, benchmarks, journal, os.Stdout, tableStyle)
+				notifyCompletion(ctx, "sweep", started, err)
+				return err
+			},
+		},
+		{
+			Name:  "selftest",
+			Usage: "Benchmark a built-in test server with a known startup delay, to check the whole measurement pipeline on this machine",
+			Flags: []cli.Flag{
+				styleFlag,
+				cli.DurationFlag{
+					Name:        "delay",
+					Usage:       "startup delay of the test server",
+					Value:       250 * time.Millisecond,
+					Destination: &selftestDelay,
+				},
+				cli.IntFlag{
+					Name:        "runs",
+					Usage:       "number of measured runs per mode",
+					Value:       5,
+					Destination: &selftestRuns,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				ctx, stop := interruptibleContext()
+				defer stop()
+				return selftest(ctx, os.Stdout, selftestDelay, selftestRuns, style)
+			},
+			Subcommands: []cli.Command{
+				{
+					Name:  "serve",
+					Usage: "Run the test server, which listens after a startup delay",
+					Flags: []cli.Flag{
+						cli.DurationFlag{
+							Name:        "delay",
+							Usage:       "startup delay before listening",
+							Destination: &selftestServe.Delay,
+						},
+						cli.StringFlag{
+							Name:        "listen",
+							Usage:       "address to listen to",
+							Value:       "localhost:8080",
+							Destination: &selftestServe.Listen,
+						},
+						cli.StringFlag{
+							Name:        "protocol",
+							Usage:       "http to answer requests with 200 OK, tcp to accept connections, smtp to greet them with a banner",
+							Value:       "http",
+							Destination: &selftestServe.Protocol,
+						},
+						cli.DurationFlag{
+							Name:        "accept-delay",
+							Usage:       "delay between listening and accepting connections",
+							Destination: &selftestServe.AcceptDelay,
+						},
+						cli.DurationFlag{
+							Name:        "unavailable",
+							Usage:       "how long http requests are answered with 503 once connections are accepted",
+							Destination: &selftestServe.Unavailable,
+						},
+						cli.DurationFlag{
+							Name:        "banner-delay",
+							Usage:       "delay before greeting smtp connections",
+							Destination: &selftestServe.BannerDelay,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						ctx, stop := interruptibleContext()
+						defer stop()
+						return selftestServe.serve(ctx)
+					},
+				},
+			},
+		},
+		{
+			Name:  "report",
+			Usage: "Render results exported with --export json as documents",
+			Subcommands: []cli.Command{
+				{
+					Name:      "pdf",
+					Usage:     "Write the reports of results to a self-contained PDF file",
+					ArgsUsage: "results.json...",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:        "output, o",
+							Usage:       "PDF file to write",
+							Value:       "boot-time-report.pdf",
+							Destination: &reportPath,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						return writePDFReport(reportPath, c.Args())
+					},
+				},
+			},
+		},
+		{
+			Name:      "compare",
+			Usage:     "Compare results exported with --export json, relative to the first ones, and against a reference dataset with --reference",
+			ArgsUsage: "results.json...",
+			Flags: []cli.Flag{
+				styleFlag,
+				cli.StringFlag{
+					Name:        "reference",
+					Usage:       "file or http(s) URL of a dataset of reference boot times, such as published framework benchmarks",
+					Destination: &referencePath,
+				},
+				cli.Float64Flag{
+					Name:        "improvement-threshold",
+					Usage:       "percentage of the baseline median by which results must be faster to be improved",
+					Value:       5,
+					Destination: &thresholds.improvement,
+				},
+				cli.Float64Flag{
+					Name:        "regression-threshold",
+					Usage:       "percentage of the baseline median by which results must be slower to be regressed",
+					Value:       5,
+					Destination: &thresholds.regression,
+				},
+				cli.Float64Flag{
+					Name:        "alpha",
+					Usage:       "significance level below which the p-value of a Mann-Whitney U test makes a difference significant",
+					Value:       defaultAlpha,
+					Destination: &thresholds.alpha,
+				},
+				cli.BoolFlag{
+					Name:        "require-significance",
+					Usage:       "only give improved and regressed verdicts to significant differences",
+					Destination: &thresholds.significant,
+				},
+				cli.BoolFlag{
+					Name:        "normalize",
+					Usage:       "normalize the durations by the CPU scores of the hosts, measured with --cpu-score, to compare hosts",
+					Destination: &normalized,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.NArg() == 0 {
+					return errors.New("compare expects the paths of results exported with --export json")
+				}
+				if c.NArg() == 1 && len(referencePath) == 0 {
+					return errors.New("compare expects several results, or a reference dataset with --reference")
+				}
+				if thresholds.improvement < 0 || thresholds.regression < 0 {
+					return errors.New("the improvement and regression thresholds must not be negative")
+				}
+				if thresholds.alpha <= 0 || thresholds.alpha >= 1 {
+					return errors.New("the significance level must be between 0 and 1")
+				}
+				return compareResults(os.Stdout, c.Args(), referencePath, normalized, thresholds, style)
+			},
+		},
+	}
+
+	app.Action = func(c *cli.Context) error {
+		benchmarks, err := setupBenchmarks(c)
+		if err != nil {
+			return err
+		}
+		if len(csvPath) > 0 {
+			if len(exportSpecs) == 0 && outputFormat == consoleOutput {
+				exportSpecs = append(exportSpecs, "console")
+			}
+			exportSpecs = append(exportSpecs, "csv="+csvPath)
+		}
+		if len(htmlReportPath) > 0 {
+			if len(exportSpecs) == 0 && outputFormat == consoleOutput {
+				exportSpecs = append(exportSpecs, "console")
+			}
+			exportSpecs = append(exportSpecs, "html="+htmlReportPath)
+		}
+		if len(hdrPath) > 0 {
+			if len(exportSpecs) == 0 && outputFormat == consoleOutput {
+				exportSpecs = append(exportSpecs, "console")
+			}
+			exportSpecs = append(exportSpecs, "hdr="+hdrPath)
+		}
+		for _, plot := range plots {
+			if !strings.HasPrefix(plot, "boxplot=") && !strings.HasPrefix(plot, "violin=") {
+				return fmt.Errorf("invalid plot: %s (expected boxplot=file or violin=file)", plot)
+			}
+			if len(exportSpecs) == 0 && outputFormat == consoleOutput {
+				exportSpecs = append(exportSpecs, "console")
+			}
+			exportSpecs = append(exportSpecs, plot)
+		}
+		if len(emailRecipients) > 0 {
+			if len(exportSpecs) == 0 && outputFormat == consoleOutput {
+				exportSpecs = append(exportSpecs, "console")
+			}
+			exportSpecs = append(exportSpecs, "email="+emailRecipients)
+		}
+		var gate *regressionGate
+		if len(baselinePath) > 0 {
+			gate = &regressionGate{path: baselinePath, percentile: baselinePercentile, threshold: 5}
+			if len(regressionThreshold) > 0 {
+				var err error
+				if gate.threshold, err = parseThreshold(regressionThreshold); err != nil {
+					return err
+				}
+			}
+			if baselinePercentile <= 0 || baselinePercentile > 100 {
+				return fmt.Errorf("invalid baseline percentile: %v", baselinePercentile)
+			}
+		} else if len(regressionThreshold) > 0 || c.IsSet("baseline-percentile") {
+			return errors.New("--fail-if-slower-than and --baseline-percentile require --baseline")
+		}
+		for _, bench := range benchmarks {
+			if len(bench.Command) == 0 {
+				return fmt.Errorf("scenario %s has no executable", bench.Name)
+			}
+			if gate != nil {
+				if _, err := gate.baseline(bench.Name); err != nil {
+					return err
+				}
+			}
+			if _, err := newExporters(exportSpecs, bench.Name); err != nil {
+				return err
+			}
+		}
+
+		if histogramBuckets < 0 {
+			return fmt.Errorf("invalid number of histogram buckets: %d", histogramBuckets)
+		}
+		if len(statsSpec) > 0 {
+			var err error
+			if summaryStatistics, err = parseSummaryStatistics(statsSpec); err != nil {
+				return err
+			}
+		}
+
+		var out *resultsOutput
+		switch outputFormat {
+		case consoleOutput:
+		case jsonOutput, markdownOutput:
+			out = &resultsOutput{format: outputFormat, path: outputPath, args: os.Args}
+			if len(outputPath) == 0 || outputPath == "-" {
+				// Progress goes to the standard error, the standard output is only the document.
+				color.Output = color.Error
+			}
+		default:
+			return fmt.Errorf("unknown output format: %s (expected %s, %s or %s)", outputFormat, consoleOutput, jsonOutput, markdownOutput)
+		}
+
+		hostProfile := loadHostProfile(hostProfilePath)
+		ctx, stop := interruptibleContext()
+		defer stop()
+		journal := sessionJournalPath(journalPath, configPath, profileName)
+		if noJournal {
+			journal = ""
+		} else if len(journal) > 0 && len(journalCommit) == 0 {
+			journalCommit = headCommit()
+		}
+		for _, bench := range benchmarks {
+			bench.HostProfile = hostProfile
+			printProgress(bench)
+			if isTerminal(os.Stdin) && isTerminal(os.Stderr) && !boottime.Profiles[bench.Profile].Strict {
+				bench.OnMisconfiguration = promptMisconfiguration
+			}
+			bench.Logger = logger
+		}
+		started := time.Now()
+		if len(candidates) > 0 {
+			tableStyle, styleErr := tableStyleFor(style)
+			if styleErr != nil {
+				return styleErr
+			}
+			var w io.Writer
+			if out == nil {
+				w = os.Stdout
+			}
+			err = runCandidates(ctx, benchmarks, exportSpecs, recordPath, journal, out, gate, w, tableStyle)
+		} else {
+			for _, bench := range benchmarks {
+				benchErr := runBenchmark(ctx, bench, exportSpecs, recordPath, journal, out, gate)
+				if expectedFailure(ctx, bench, benchErr) {
+					continue
+				}
+				if benchErr != nil {
+					if len(bench.Name) > 0 {
+						benchErr = fmt.Errorf("scenario %s: %v", bench.Name, benchErr)
+					}
+					if err == nil {
+						err = benchErr
+					}
+					if ctx.Err() != nil || len(benchmarks) == 1 {
+						break
+					}
+					logger.Error("benchmark failed", "error", benchErr)
+				}
+			}
+		}
+		if out != nil && len(out.results) > 0 {
+			if outErr := out.write(); err == nil {
+				err = outErr
+			}
+		}
+		notifyCompletion(ctx, sessionName(benchmarks), started, err)
+		return err
+	}
+
+	if err := app.Run(args); err != nil {
+		logger.Error("aborting", "error", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// writeMarkdown writes a Markdown document of results for --output-format markdown, to paste in
+// pull requests and wiki pages: the command line and the host, then the runs and the statistics of
+// each results.
+func writeMarkdown(w io.Writer, results []*boottime.Results, args []string) {
+	program := append([]string{filepath.Base(args[0])}, args[1:]...)
+	fmt.Fprintf(w, "## Boot time\n\n```\n%s\n```\n\n", boottime.Redact(commandLine(program)))
+	host := boottime.CurrentHost()
+	if results[0].Host != nil {
+		host = *results[0].Host
+	} else if reproducible {
+		host.Name = ""
+	}
+	environment := newTable("Environment", column{"Field", alignLeft}, column{"Value", alignLeft})
+	if len(host.Name) > 0 {
+		environment.addRow("Host", host.Name)
+	}
+	environment.addRow("OS", host.OS+"/"+host.Arch)
+	if len(host.Kernel) > 0 {
+		environment.addRow("Kernel", host.Kernel)
+	}
+	environment.addRow("CPUs", strconv.Itoa(host.CPUs))
+	if !results[0].StartedAt.IsZero() {
+		environment.addRow("Started at", results[0].StartedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	environment.render(w, markdownStyle)
+
+	for _, r := range results {
+		if len(r.Scenario) > 0 {
+			fmt.Fprintf(w, "## Scenario %s\n\n", r.Scenario)
+		}
+		// Runs have no start time in reproducible results.
+		timed := len(r.Runs) > 0 && !r.Runs[0].StartedAt.IsZero()
+		columns := []column{{"Run", alignRight}, {"Kind", alignLeft}, {"Started at", alignLeft}, {"Time (ms)", alignRight}, {"Outcome", alignLeft}}
+		if !timed {
+			columns = append(columns[:2], columns[3:]...)
+		}
+		runs := newTable("Runs", columns...)
+		for _, run := range r.Runs {
+			kind, duration, outcome := "measured", formatMillis(run.Duration), "ready"
+			if run.Dry {
+				kind = "dry"
+			}
+			if run.Failed() {
+				duration, outcome = "", "failed: "+run.ErrorCategory
+			}
+			if timed {
+				runs.addRow(strconv.Itoa(run.Index+1), kind, run.StartedAt.Local().Format("15:04:05.000"), duration, outcome)
+			} else {
+				runs.addRow(strconv.Itoa(run.Index+1), kind, duration, outcome)
+			}
+		}
+		runs.render(w, markdownStyle)
+		if len(r.Measured()) > 0 {
+			report(w, markdownStyle, r)
+		}
+	}
+}
+
+// unquotedArg matches the arguments that need no quoting in a shell.
+var unquotedArg = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// commandLine returns args as a shell command line.
+func commandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if unquotedArg.MatchString(arg) {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// notifiers are told when a session completes, see parseNotifiers.
+var notifiers []notifier
+
+// notification tells how a session completed.
+type notification struct {
+	title   string
+	message string
+	failed  bool
+}
+
+type notifier func(ctx context.Context, n notification) error
+
+// notifyTimeout bounds how long a notifier may delay the end of the program.
+const notifyTimeout = 30 * time.Second
+
+// Environment variables of the commands of cmd notifiers.
+const (
+	notifyStatusVariable  = "BOOT_NOTIFY_STATUS" // success or failure
+	notifyMessageVariable = "BOOT_NOTIFY_MESSAGE"
+)
+
+// parseNotifiers parses notifiers given as desktop, for a desktop notification, or cmd:command, for
+// a shell command line.
+func parseNotifiers(specs []string) ([]notifier, error) {
+	parsed := make([]notifier, 0, len(specs))
+	for _, spec := range specs {
+		switch {
+		case spec == "desktop":
+			notify, err := desktopNotifier()
+			if err != nil {
+				return nil, err
+			}
+			parsed = append(parsed, notify)
+		case strings.HasPrefix(spec, "cmd:"):
+			command := strings.TrimPrefix(spec, "cmd:")
+			if len(command) == 0 {
+				return nil, errors.New("the cmd notifier needs a command, as in cmd:'make notify'")
+			}
+			parsed = append(parsed, commandNotifier(command))
+		default:
+			return nil, fmt.Errorf("unknown notifier: %s (expected desktop or cmd:command)", spec)
+		}
+	}
+	return parsed, nil
+}
+
+// desktopNotifier shows notifications with notify-send, or osascript on macOS.
+func desktopNotifier() (notifier, error) {
+	if runtime.GOOS == "darwin" {
+		return func(ctx context.Context, n notification) error {
+			script := fmt.Sprintf("display notification %s with title %s", appleScriptString(n.message), appleScriptString(n.title))
+			return runNotifier(exec.CommandContext(ctx, "osascript", "-e", script))
+		}, nil
+	}
+	if _, err := exec.LookPath("notify-send"); err != nil {
+		return nil, errors.New("desktop notifications need notify-send, as provided by libnotify")
+	}
+	return func(ctx context.Context, n notification) error {
+		urgency := "normal"
+		if n.failed {
+			urgency = "critical"
+		}
+		return runNotifier(exec.CommandContext(ctx, "notify-send", "--urgency", urgency, n.title, n.message))
+	}, nil
+}
+
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// commandNotifier runs a shell command line, given the outcome in its environment.
+func commandNotifier(command string) notifier {
+	return func(ctx context.Context, n notification) error {
+		status := "success"
+		if n.failed {
+			status = "failure"
+		}
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+		cmd.Env = append(os.Environ(), notifyStatusVariable+"="+status, notifyMessageVariable+"="+n.message)
+		return runNotifier(cmd)
+	}
+}
+
+func runNotifier(cmd *exec.Cmd) error {
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// sessionName names a session running benchmarks in notifications.
+func sessionName(benchmarks []*boottime.Benchmark) string {
+	if len(benchmarks) == 1 && len(benchmarks[0].Name) > 0 {
+		return "scenario " + benchmarks[0].Name
+	}
+	if len(benchmarks) > 1 {
+		return fmt.Sprintf("%d benchmarks", len(benchmarks))
+	}
+	return "benchmark"
+}
+
+// notifyCompletion tells the notifiers that what completed, or failed with err, since started.
+// Nothing is notified when the session was interrupted, since the user is around.
+func notifyCompletion(ctx context.Context, what string, started time.Time, err error) {
+	if len(notifiers) == 0 || ctx.Err() != nil {
+		return
+	}
+	elapsed := time.Since(started).Round(time.Second)
+	n := notification{title: "time-to-boot-server", message: fmt.Sprintf("%s completed in %s", what, elapsed)}
+	if err != nil {
+		n.message, n.failed = fmt.Sprintf("%s failed after %s: %s", what, elapsed, boottime.Redact(err.Error())), true
+	}
+	notifyCtx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	for _, notify := range notifiers {
+		if notifyErr := notify(notifyCtx, n); notifyErr != nil {
+			logger.Warn("notification failed", "error", notifyErr)
+		}
+	}
+}
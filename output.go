@@ -0,0 +1,201 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+	"github.com/montanaflynn/stats"
+)
+
+// Output formats of the results of benchmarks.
+const (
+	consoleOutput  = "console"  // progress and the exporters, the console one by default
+	jsonOutput     = "json"     // a JSON document with the statistics, progress going to the standard error
+	markdownOutput = "markdown" // a Markdown document with the runs and the statistics, progress going to the standard error
+)
+
+// byteStatistics summarize sizes in bytes.
+type byteStatistics struct {
+	Count  int   `json:"count"`
+	Min    int64 `json:"min_bytes"`
+	Max    int64 `json:"max_bytes"`
+	Mean   int64 `json:"mean_bytes"`
+	Median int64 `json:"median_bytes"`
+	StdDev int64 `json:"std_dev_bytes"`
+}
+
+// computeByteStatistics summarizes sizes, nil when there is none.
+func computeByteStatistics(sizes []int64) *byteStatistics {
+	if len(sizes) == 0 {
+		return nil
+	}
+	data := make([]float64, len(sizes))
+	for i, size := range sizes {
+		data[i] = float64(size)
+	}
+	min, _ := stats.Min(data)
+	max, _ := stats.Max(data)
+	mean, _ := stats.Mean(data)
+	med, _ := stats.Median(data)
+	dev, _ := stats.StandardDeviation(data)
+	return &byteStatistics{Count: len(sizes), Min: int64(min), Max: int64(max), Mean: int64(mean), Median: int64(med), StdDev: int64(dev)}
+}
+
+// resultsDocument is the JSON document of results: all the fields of the results, with the
+// statistics of their successful runs.
+type resultsDocument struct {
+	*boottime.Results
+	Statistics struct {
+		Runs       *boottime.Statistics `json:"runs,omitempty"`
+		DryRuns    *boottime.Statistics `json:"dry_runs,omitempty"`
+		Recoveries *boottime.Statistics `json:"recoveries,omitempty"` // of the measured runs, with --crash-recovery
+		Stages     []stageStatistics    `json:"stages,omitempty"`     // of the measured runs, with --milestone
+		ReadyRSS   *byteStatistics      `json:"ready_rss,omitempty"`  // of the measured runs
+		CPU        *boottime.Statistics `json:"cpu,omitempty"`        // total CPU time until readiness of the measured runs
+		PeakRSS    *byteStatistics      `json:"peak_rss,omitempty"`
+	} `json:"statistics"`
+}
+
+// stageStatistics summarize the durations of a stage of the boot.
+type stageStatistics struct {
+	Name string `json:"name"`
+	*boottime.Statistics
+}
+
+func newResultsDocument(results *boottime.Results) resultsDocument {
+	document := resultsDocument{Results: results}
+	var dry []boottime.Run
+	for _, run := range results.Runs {
+		if run.Dry && !run.Failed() {
+			dry = append(dry, run)
+		}
+	}
+	document.Statistics.Runs = boottime.ComputeStatistics(boottime.Durations(results.Measured()))
+	document.Statistics.DryRuns = boottime.ComputeStatistics(boottime.Durations(dry))
+	document.Statistics.Recoveries = boottime.ComputeStatistics(recoveryDurations(results.Measured()))
+	document.Statistics.ReadyRSS, document.Statistics.PeakRSS = memoryStatistics(results.Measured())
+	_, _, cpu, _ := cpuTimes(results.Measured())
+	document.Statistics.CPU = boottime.ComputeStatistics(cpu)
+	for _, stage := range stageDurations(results.Measured()) {
+		document.Statistics.Stages = append(document.Statistics.Stages, stageStatistics{stage.name, boottime.ComputeStatistics(stage.durations)})
+	}
+	return document
+}
+
+// recoveryDurations returns the durations of the crash recoveries of runs.
+func recoveryDurations(runs []boottime.Run) []time.Duration {
+	var durations []time.Duration
+	for _, run := range runs {
+		if run.Recovery != nil {
+			durations = append(durations, run.Recovery.Duration)
+		}
+	}
+	return durations
+}
+
+// stage is a stage of the boot, ending with a milestone or with readiness.
+type stage struct {
+	name      string // that of the milestone, or ready
+	durations []time.Duration
+}
+
+// stageDurations breaks the boot times of runs down into the durations between the spawn of the
+// process, their milestones and readiness, in the order of the milestones.
+func stageDurations(runs []boottime.Run) []stage {
+	var stages []stage
+	index := map[string]int{}
+	for _, run := range runs {
+		var previous time.Duration
+		for _, phase := range run.Phases {
+			name := strings.TrimPrefix(phase.Name, boottime.MilestonePhasePrefix)
+			if name == phase.Name && (phase.Name != boottime.ReadyPhase || previous == 0) {
+				continue
+			}
+			i, ok := index[name]
+			if !ok {
+				i, index[name] = len(stages), len(stages)
+				stages = append(stages, stage{name: name})
+			}
+			stages[i].durations = append(stages[i].durations, phase.Offset-previous)
+			previous = phase.Offset
+		}
+	}
+	return stages
+}
+
+// resultsOutput collects the results of benchmarks for --output-format json or markdown.
+type resultsOutput struct {
+	format  string
+	path    string   // the standard output when empty or "-"
+	args    []string // the command line, in the markdown format
+	results []*boottime.Results
+}
+
+func (o *resultsOutput) add(results *boottime.Results) {
+	if reproducible {
+		results = results.Reproducible()
+	}
+	o.results = append(o.results, results)
+}
+
+// write writes the document of the results, or an array of documents when there are several,
+// as when running several scenarios of a configuration file.
+func (o *resultsOutput) write() error {
+	if len(o.results) == 0 {
+		return errors.New("no results to output")
+	}
+	if o.format == markdownOutput {
+		var buffer bytes.Buffer
+		writeMarkdown(&buffer, o.results, o.args)
+		return o.writeData([]byte(boottime.Redact(buffer.String())))
+	}
+	documents := make([]resultsDocument, len(o.results))
+	for i, results := range o.results {
+		documents[i] = newResultsDocument(results)
+	}
+	var value interface{} = documents
+	if len(documents) == 1 {
+		value = documents[0]
+	}
+	data, err := json.MarshalIndent(boottime.RedactValue(value), "", "  ")
+	if err != nil {
+		return err
+	}
+	return o.writeData(append(data, '\n'))
+}
+
+func (o *resultsOutput) writeData(data []byte) error {
+	if len(o.path) == 0 || o.path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(o.path, data, 0644)
+}
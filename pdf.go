@@ -0,0 +1,280 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// Layout of PDF reports, in points on A4 pages.
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 50.0
+	pdfFontSize   = 8.0 // of report lines, shrunk to fit the widest line
+)
+
+// Fonts of PDF reports, among the standard ones that need no embedding.
+var pdfFonts = []string{"Courier", "Courier-Bold", "Helvetica-Bold", "Helvetica"}
+
+const (
+	pdfMono = iota
+	pdfMonoBold
+	pdfHeading
+	pdfText
+)
+
+// pdfDocument lays out text and bars on pages, then writes them as a self-contained PDF file.
+type pdfDocument struct {
+	pages []*bytes.Buffer // content streams
+	y     float64         // of the next line on the last page, from the bottom
+}
+
+func (d *pdfDocument) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// reserve starts a new page unless height fits above the bottom margin of the last one.
+func (d *pdfDocument) reserve(height float64) {
+	if len(d.pages) == 0 || d.y-height < pdfMargin {
+		d.pages = append(d.pages, &bytes.Buffer{})
+		d.y = pdfPageHeight - pdfMargin
+	}
+}
+
+// line writes a line of text in a font, at an indentation from the left margin.
+func (d *pdfDocument) line(font int, size float64, indent float64, text string) {
+	d.reserve(size * 1.25)
+	d.y -= size * 1.25
+	fmt.Fprintf(d.page(), "BT /F%d %s Tf %s %s Td (%s) Tj ET\n", font+1, pdfNumber(size),
+		pdfNumber(pdfMargin+indent), pdfNumber(d.y), pdfEscape(text))
+}
+
+// space leaves vertical space, unless at the top of a page.
+func (d *pdfDocument) space(height float64) {
+	if len(d.pages) > 0 && d.y-height >= pdfMargin {
+		d.y -= height
+	}
+}
+
+// bars draws a chart of durations as bars, in order, with the maximum as scale.
+func (d *pdfDocument) bars(durations []time.Duration) {
+	const height = 120.0
+	var max time.Duration
+	for _, duration := range durations {
+		if duration > max {
+			max = duration
+		}
+	}
+	if max == 0 {
+		return
+	}
+	d.reserve(height + 2*pdfFontSize)
+	d.line(pdfMono, pdfFontSize, 0, fmt.Sprintf("Runs in order, up to %s ms", formatMillis(max)))
+	bottom := d.y - height
+	width := pdfPageWidth - 2*pdfMargin
+	step := width / float64(len(durations))
+	w := d.page()
+	fmt.Fprintf(w, "0.6 g\n")
+	for i, duration := range durations {
+		barHeight := height * float64(duration) / float64(max)
+		fmt.Fprintf(w, "%s %s %s %s re f\n", pdfNumber(pdfMargin+float64(i)*step+step*0.1), pdfNumber(bottom),
+			pdfNumber(step*0.8), pdfNumber(barHeight))
+	}
+	fmt.Fprintf(w, "0 g 0.5 w %s %s m %s %s l S\n", pdfNumber(pdfMargin), pdfNumber(bottom), pdfNumber(pdfMargin+width), pdfNumber(bottom))
+	d.y = bottom - pdfFontSize
+}
+
+// write writes the document with its objects: the catalog, the page tree, the fonts, then the page
+// and content stream of every page.
+func (d *pdfDocument) write(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	var offsets []int
+	written := 0
+	object := func(format string, args ...interface{}) {
+		offsets = append(offsets, written)
+		n, _ := fmt.Fprintf(out, "%d 0 obj\n", len(offsets))
+		written += n
+		n, _ = fmt.Fprintf(out, format, args...)
+		written += n
+		n, _ = fmt.Fprint(out, "\nendobj\n")
+		written += n
+	}
+	n, _ := fmt.Fprint(out, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	written += n
+	firstPage := 3 + len(pdfFonts)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	fonts := make([]string, len(pdfFonts))
+	for i := range pdfFonts {
+		fonts[i] = fmt.Sprintf("/F%d %d 0 R", i+1, 3+i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages))
+	for _, font := range pdfFonts {
+		object("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", font)
+	}
+	for i, page := range d.pages {
+		object("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			pdfNumber(pdfPageWidth), pdfNumber(pdfPageHeight), strings.Join(fonts, " "), firstPage+2*i+1)
+		var compressed bytes.Buffer
+		z := zlib.NewWriter(&compressed)
+		z.Write(page.Bytes())
+		z.Close()
+		object("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes())
+	}
+	xref := written
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Flush()
+}
+
+func pdfNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+// pdfEscape escapes text for a PDF string in the WinAnsi encoding, which matches Latin-1 for the
+// characters it has, replacing the others with question marks.
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// writePDFReport writes the console reports of results exported with the json exporter to a
+// self-contained PDF file, with the plain style set in a monospaced font and a chart of the runs.
+func writePDFReport(path string, paths []string) error {
+	if len(paths) == 0 {
+		return errors.New("report pdf expects the paths of results exported with --export json")
+	}
+	doc := &pdfDocument{}
+	doc.reserve(0)
+	doc.line(pdfHeading, 18, 0, "Boot time report")
+	if !reproducible {
+		doc.line(pdfText, 9, 0, "Generated on "+time.Now().Format("2006-01-02 15:04 MST"))
+	}
+	for _, resultsPath := range paths {
+		results, err := boottime.ReadResults(resultsPath)
+		if err != nil {
+			return err
+		}
+		if reproducible {
+			results = results.Reproducible()
+		}
+		title := results.Scenario
+		if len(title) == 0 {
+			title = resultsPath
+		}
+		doc.space(12)
+		doc.reserve(120) // the heading and details, with the first table
+		doc.line(pdfHeading, 14, 0, title)
+		details := []string{"Command: " + strings.TrimSpace(results.Command+" "+strings.Join(results.Args, " ")),
+			fmt.Sprintf("Mode %s, target %s", results.Mode, results.Target)}
+		if !results.StartedAt.IsZero() {
+			details = append(details, "Started on "+results.StartedAt.Format("2006-01-02 15:04 MST"))
+		}
+		if results.Host != nil {
+			host := fmt.Sprintf("%s/%s, %d CPUs", results.Host.OS, results.Host.Arch, results.Host.CPUs)
+			if len(results.Host.Name) > 0 {
+				host = results.Host.Name + ", " + host
+			}
+			details = append(details, "Host "+host)
+		}
+		for _, detail := range details {
+			doc.line(pdfText, 9, 0, detail)
+		}
+		doc.space(6)
+		measured := results.Measured()
+		if len(measured) == 0 {
+			doc.line(pdfMono, pdfFontSize, 0, "No run succeeded.")
+			continue
+		}
+		var text bytes.Buffer
+		report(&text, plainStyle, results)
+		lines := strings.Split(strings.TrimRight(text.String(), "\n"), "\n")
+		size := pdfFontSize
+		for _, line := range lines {
+			// Courier characters are 0.6 em wide.
+			if width := 0.6 * size * float64(utf8.RuneCountInString(line)); width > pdfPageWidth-2*pdfMargin {
+				size = (pdfPageWidth - 2*pdfMargin) / (0.6 * float64(utf8.RuneCountInString(line)))
+			}
+		}
+		for i, line := range lines {
+			if strings.HasPrefix(line, " ") {
+				doc.line(pdfMono, size, 0, line)
+				continue
+			}
+			// A table title, kept on the page of its rows.
+			rows := 0
+			for _, next := range lines[i+1:] {
+				if !strings.HasPrefix(next, " ") {
+					break
+				}
+				rows++
+			}
+			doc.space(size / 2)
+			doc.reserve(float64(rows+1) * size * 1.25)
+			doc.line(pdfMonoBold, size, 0, line)
+		}
+		doc.space(12)
+		doc.bars(boottime.Durations(measured))
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := doc.write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
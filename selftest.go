@@ -0,0 +1,263 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// selftestTolerance is how much later than expected the test server may be detected ready,
+// covering the time to spawn a process and to listen.
+const selftestTolerance = 250 * time.Millisecond
+
+// selftestServer describes how the test server behaves.
+type selftestServer struct {
+	Protocol    string        // http, tcp or smtp
+	Listen      string        // address to listen to
+	Delay       time.Duration // before listening
+	AcceptDelay time.Duration // between listening and accepting connections
+	Unavailable time.Duration // how long http requests are answered with 503 once accepting connections
+	BannerDelay time.Duration // before smtp connections get the greeting banner
+}
+
+// args returns the arguments of the selftest serve command running the server.
+func (s selftestServer) args() []string {
+	return []string{"selftest", "serve", "--protocol", s.Protocol, "--listen", s.Listen,
+		"--delay", s.Delay.String(), "--accept-delay", s.AcceptDelay.String(),
+		"--unavailable", s.Unavailable.String(), "--banner-delay", s.BannerDelay.String()}
+}
+
+// serve runs the test server until ctx is cancelled.
+func (s selftestServer) serve(ctx context.Context) error {
+	if s.Protocol != "http" && s.Protocol != "tcp" && s.Protocol != "smtp" {
+		return fmt.Errorf("unknown protocol: %s (expected http, tcp or smtp)", s.Protocol)
+	}
+	if err := pauseFor(ctx, s.Delay); err != nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", s.Listen)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	// Connections complete in the backlog of the listener meanwhile.
+	if err := pauseFor(ctx, s.AcceptDelay); err != nil {
+		return nil
+	}
+	accepting := time.Now()
+	if s.Protocol == "http" {
+		err = http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if time.Since(accepting) < s.Unavailable {
+				http.Error(w, "starting", http.StatusServiceUnavailable)
+				return
+			}
+			io.WriteString(w, "ready\n")
+		}))
+	} else {
+		for {
+			var conn net.Conn
+			if conn, err = listener.Accept(); err != nil {
+				break
+			}
+			go func() {
+				defer conn.Close()
+				if s.Protocol == "smtp" {
+					if pauseFor(ctx, s.BannerDelay) == nil {
+						io.WriteString(conn, "220 selftest ESMTP ready\r\n")
+					}
+					return
+				}
+				io.WriteString(conn, "ready\n")
+			}()
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func pauseFor(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// selftestCase is a case of the conformance suite of the modes: the mode must detect the test
+// server ready at the expected time, a multiple of the delay of the self-test.
+type selftestCase struct {
+	mode        string
+	description string
+	server      func(d time.Duration) selftestServer
+	expected    func(d time.Duration) time.Duration
+}
+
+// selftestCases is the conformance suite of the modes, new modes that can probe the test server
+// should add their cases.
+var selftestCases = []selftestCase{
+	{
+		mode:        "http-get",
+		description: "http, listening after the delay",
+		server:      func(d time.Duration) selftestServer { return selftestServer{Protocol: "http", Delay: d} },
+		expected:    func(d time.Duration) time.Duration { return d },
+	},
+	{
+		mode:        "http-get",
+		description: "http, listening after the delay, then answering 503 during the delay",
+		server: func(d time.Duration) selftestServer {
+			return selftestServer{Protocol: "http", Delay: d, Unavailable: d}
+		},
+		expected: func(d time.Duration) time.Duration { return 2 * d },
+	},
+	{
+		mode:        "http-get",
+		description: "http, accepting connections after the delay",
+		server:      func(d time.Duration) selftestServer { return selftestServer{Protocol: "http", AcceptDelay: d} },
+		expected:    func(d time.Duration) time.Duration { return d },
+	},
+	{
+		mode:        "tcp-connect",
+		description: "tcp, listening after the delay",
+		server:      func(d time.Duration) selftestServer { return selftestServer{Protocol: "tcp", Delay: d} },
+		expected:    func(d time.Duration) time.Duration { return d },
+	},
+	{
+		// The kernel completes connections in the backlog of a listening socket.
+		mode:        "tcp-connect",
+		description: "tcp, listening at once and accepting connections after the delay",
+		server:      func(d time.Duration) selftestServer { return selftestServer{Protocol: "tcp", AcceptDelay: d} },
+		expected:    func(d time.Duration) time.Duration { return 0 },
+	},
+	{
+		// The tcp-connect mode does not wait for banners.
+		mode:        "tcp-connect",
+		description: "smtp, listening after the delay and greeting after the delay",
+		server: func(d time.Duration) selftestServer {
+			return selftestServer{Protocol: "smtp", Delay: d, BannerDelay: d}
+		},
+		expected: func(d time.Duration) time.Duration { return d },
+	},
+}
+
+// run runs the case against a test server spawned from executable, the selftest serve command of
+// which runs the server, and returns the median of the measured runs.
+func (c selftestCase) run(ctx context.Context, executable, name string, delay time.Duration, runs int) (time.Duration, error) {
+	server := c.server(delay)
+	var err error
+	if server.Listen, err = freeAddress(); err != nil {
+		return 0, err
+	}
+	target := server.Listen
+	if c.mode == "http-get" {
+		target = "http://" + server.Listen + "/"
+	}
+	bench := &boottime.Benchmark{
+		Name:    name,
+		Mode:    c.mode,
+		Target:  target,
+		Command: executable,
+		Args:    server.args(),
+		DryRuns: 1,
+		Runs:    runs,
+		Logger:  logger,
+		// Go listeners use SO_REUSEADDR, sockets left by previous runs do not matter.
+		LingeringSockets: boottime.IgnoreLingeringSockets,
+	}
+	results, err := bench.Run(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return boottime.Median(boottime.Durations(results.Measured())), nil
+}
+
+// passes tells whether the test server was detected ready at the expected time.
+func (c selftestCase) passes(median, delay time.Duration) bool {
+	expected := c.expected(delay)
+	return median >= expected && median <= expected+selftestTolerance
+}
+
+// selftest runs the conformance suite of the modes against the test server, checking that each
+// mode detects readiness at the expected time.
+func selftest(ctx context.Context, w io.Writer, delay time.Duration, runs int, styleName string) error {
+	style, err := tableStyleFor(styleName)
+	if err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	table := newTable("Self-test with a delay of "+delay.String(),
+		column{"Mode", alignLeft}, column{"Test server", alignLeft},
+		column{"Median (ms)", alignRight}, column{"Expected (ms)", alignRight}, column{"Result", alignLeft})
+	failed := false
+	for i, c := range selftestCases {
+		expected := c.expected(delay)
+		expectedRange := fmt.Sprintf("%s - %s", formatMillis(expected), formatMillis(expected+selftestTolerance))
+		median, err := c.run(ctx, executable, "selftest-"+strconv.Itoa(i+1), delay, runs)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			table.addRow(c.mode, c.description, "", expectedRange, "failed: "+err.Error())
+			failed = true
+			continue
+		}
+		result := "pass"
+		if !c.passes(median, delay) {
+			result = "fail"
+			failed = true
+		}
+		table.addRow(c.mode, c.description, formatMillis(median), expectedRange, result)
+	}
+	table.render(w, style)
+	if failed {
+		return fmt.Errorf("the self-test failed")
+	}
+	return nil
+}
+
+// freeAddress returns a local address with a port that is free at the moment.
+func freeAddress() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	return "127.0.0.1:" + strconv.Itoa(listener.Addr().(*net.TCPAddr).Port), nil
+}
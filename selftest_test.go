@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// The test binary runs the test servers, as the executable does for the selftest command.
+	if len(os.Args) > 2 && os.Args[1] == "selftest" && os.Args[2] == "serve" {
+		main()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func TestSelftestCases(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	delay := 200 * time.Millisecond
+	for i, c := range selftestCases {
+		t.Run(c.mode+"/"+c.description, func(t *testing.T) {
+			median, err := c.run(t.Context(), executable, "selftest-"+strconv.Itoa(i+1), delay, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !c.passes(median, delay) {
+				expected := c.expected(delay)
+				t.Errorf("detected ready after %s, expected between %s and %s", median, expected, expected+selftestTolerance)
+			}
+		})
+	}
+}
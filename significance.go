@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// defaultAlpha is the significance level below which the p-value of a difference makes it
+// statistically significant.
+const defaultAlpha = 0.05
+
+// significance is the outcome of a Mann-Whitney U test between the durations of a baseline and of
+// other results.
+type significance struct {
+	p float64 // two-sided p-value of the difference
+	// delta is the Cliff's delta effect size, the probability that a duration of the other results
+	// is longer than one of the baseline minus the probability that it is shorter, from -1 to 1.
+	delta float64
+}
+
+// mannWhitney tests whether durations differ from those of baseline with a Mann-Whitney U test,
+// which assumes nothing about how boot times are distributed. The p-value comes from the normal
+// approximation with ties and continuity corrections, good enough from about 8 runs each.
+func mannWhitney(baseline, durations []time.Duration) significance {
+	n1, n2 := len(baseline), len(durations)
+	if n1 == 0 || n2 == 0 {
+		return significance{p: 1}
+	}
+	type sample struct {
+		d     time.Duration
+		first bool
+	}
+	samples := make([]sample, 0, n1+n2)
+	for _, d := range baseline {
+		samples = append(samples, sample{d, true})
+	}
+	for _, d := range durations {
+		samples = append(samples, sample{d, false})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].d < samples[j].d })
+	var rankSum, ties float64 // of the baseline, and the tie correction
+	for i := 0; i < len(samples); {
+		j := i
+		for j < len(samples) && samples[j].d == samples[i].d {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // average of the ranks i+1 to j
+		for k := i; k < j; k++ {
+			if samples[k].first {
+				rankSum += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+	// u counts the pairs where the baseline is longer, ties counting for half.
+	u := rankSum - float64(n1*(n1+1))/2
+	pairs := float64(n1 * n2)
+	n := float64(n1 + n2)
+	result := significance{p: 1, delta: 1 - 2*u/pairs}
+	variance := pairs / 12 * (n + 1 - ties/(n*(n-1)))
+	if variance <= 0 {
+		return result
+	}
+	z := math.Max(math.Abs(u-pairs/2)-0.5, 0) / math.Sqrt(variance)
+	result.p = math.Erfc(z / math.Sqrt2)
+	return result
+}
+
+// magnitude names the size of the effect, with the thresholds of Romano et al. for Cliff's delta.
+func (s significance) magnitude() string {
+	switch d := math.Abs(s.delta); {
+	case d < 0.147:
+		return "negligible"
+	case d < 0.33:
+		return "small"
+	case d < 0.474:
+		return "medium"
+	}
+	return "large"
+}
+
+func formatPValue(p float64) string {
+	if p < 0.001 {
+		return "<0.001"
+	}
+	return strconv.FormatFloat(p, 'f', 3, 64)
+}
+
+func formatEffect(s significance) string {
+	return strconv.FormatFloat(s.delta, 'f', 2, 64) + " " + s.magnitude()
+}
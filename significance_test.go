@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMannWhitney(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		durations := make([]time.Duration, len(values))
+		for i, v := range values {
+			durations[i] = time.Duration(v) * time.Millisecond
+		}
+		return durations
+	}
+	for _, c := range []struct {
+		name                string
+		baseline, durations []time.Duration
+		p, delta            float64
+	}{
+		// The example of the documentation of SciPy's mannwhitneyu, where U is 17 for the males.
+		{"textbook", ms(19, 22, 16, 29, 24), ms(20, 11, 17, 12), 0.11134688653314041, -0.7},
+		{"separated", ms(1, 2, 3, 4, 5, 6, 7, 8), ms(9, 10, 11, 12, 13, 14, 15, 16), 0.000939106, 1},
+		{"identical", ms(5, 5, 5), ms(5, 5, 5), 1, 0},
+		{"empty", nil, ms(5), 1, 0},
+	} {
+		s := mannWhitney(c.baseline, c.durations)
+		if math.Abs(s.p-c.p) > 1e-7 || math.Abs(s.delta-c.delta) > 1e-9 {
+			t.Errorf("%s: expected a p-value of %g and a delta of %g, got %g and %g", c.name, c.p, c.delta, s.p, s.delta)
+		}
+	}
+}
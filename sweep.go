@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jponge/time-to-boot-server/boottime"
+)
+
+// sweep are the settings of the sweep command.
+type sweep struct {
+	commits string // revision range, as in HEAD~20..HEAD
+	build   string // shell command building the server, if any
+}
+
+// run checks out, builds and benchmarks each commit of the range in turn, from the oldest, and
+// appends the results to the journal with their commit when journalPath is not empty, before
+// printing a summary. The commit checked out at first is restored at the end.
+func (s *sweep) run(ctx context.Context, benchmarks []*boottime.Benchmark, journalPath string, w io.Writer, style tableStyle) error {
+	if !strings.Contains(s.commits, "..") {
+		return fmt.Errorf("invalid commit range: %s (expected a range, as in HEAD~20..HEAD)", s.commits)
+	}
+	if changes, err := git(ctx, "status", "--porcelain", "--untracked-files=no"); err != nil {
+		return err
+	} else if len(changes) > 0 {
+		return errors.New("the working directory has uncommitted changes")
+	}
+	list, err := git(ctx, "rev-list", "--reverse", s.commits)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return fmt.Errorf("no commit in %s", s.commits)
+	}
+	original, err := git(ctx, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		if original, err = git(ctx, "rev-parse", "HEAD"); err != nil {
+			return err
+		}
+	}
+	defer git(context.Background(), "checkout", "--quiet", original)
+
+	named := len(benchmarks[0].Name) > 0
+	columns := []column{{"Commit", alignLeft}, {"Subject", alignLeft}, {"Scenario", alignLeft}, {"Runs", alignRight}, {"Median (ms)", alignRight}}
+	if !named {
+		columns = append(columns[:2], columns[3:]...)
+	}
+	summary := newTable("Sweep", columns...)
+	addRow := func(cells ...string) {
+		if !named {
+			cells = append(cells[:2], cells[3:]...)
+		}
+		summary.addRow(cells...)
+	}
+	for _, commit := range strings.Fields(list) {
+		if _, err := git(ctx, "checkout", "--quiet", "--detach", commit); err != nil {
+			return err
+		}
+		short, subject := commit[:7], ""
+		if line, err := git(ctx, "show", "--no-patch", "--format=%h %s", commit); err == nil {
+			short, subject = line, ""
+			if i := strings.IndexByte(line, ' '); i >= 0 {
+				short, subject = line[:i], line[i+1:]
+			}
+		}
+		var committedAt *time.Time
+		if date, err := git(ctx, "show", "--no-patch", "--format=%cI", commit); err == nil {
+			if t, err := time.Parse(time.RFC3339, date); err == nil {
+				committedAt = &t
+			}
+		}
+		color.New(color.FgMagenta, color.Bold).Printf("Commit %s %s\n", short, subject)
+		if err := buildServer(ctx, s.build); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			color.Yellow("Skipping %s: %v", short, err)
+			addRow(short, subject, "", "", "build failed")
+			continue
+		}
+		for _, bench := range benchmarks {
+			if len(bench.Name) > 0 {
+				printScenario(bench.Name)
+			}
+			results, err := bench.Run(ctx)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				logger.Error("benchmark failed", "commit", short, "error", err)
+			}
+			if results == nil || len(results.Measured()) == 0 {
+				addRow(short, subject, bench.Name, "0", "failed")
+				continue
+			}
+			durations := boottime.Durations(results.Measured())
+			addRow(short, subject, bench.Name, strconv.Itoa(len(durations)), formatMillis(boottime.Median(durations)))
+			if len(journalPath) > 0 {
+				entry := boottime.NewJournalEntry(bench, results)
+				entry.Commit = short
+				entry.CommittedAt = committedAt
+				if err := boottime.AppendJournal(journalPath, entry); err != nil {
+					logger.Error("unable to append to the journal", "error", err)
+				}
+			}
+		}
+	}
+	summary.render(w, style)
+	return nil
+}
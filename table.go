@@ -0,0 +1,304 @@
+/*
+ * Copyright (c) 2017 Julien Ponge
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+type tableStyle int
+
+const (
+	plainStyle tableStyle = iota
+	asciiStyle
+	fancyStyle
+	markdownStyle
+	htmlStyle // for HTML reports only
+)
+
+func tableStyleFor(name string) (tableStyle, error) {
+	switch name {
+	case "plain":
+		return plainStyle, nil
+	case "table":
+		return asciiStyle, nil
+	case "fancy":
+		return fancyStyle, nil
+	case "markdown":
+		return markdownStyle, nil
+	}
+	return plainStyle, fmt.Errorf("unknown style: %s", name)
+}
+
+// terminal tells whether the style is meant for terminals, rather than for documents.
+func (s tableStyle) terminal() bool {
+	return s != markdownStyle && s != htmlStyle
+}
+
+type alignment int
+
+const (
+	alignLeft alignment = iota
+	alignRight
+)
+
+type column struct {
+	header string
+	align  alignment
+}
+
+type table struct {
+	title   string
+	columns []column
+	rows    [][]string
+	paints  []map[int]func(...interface{}) string // by row then column, in the fancy style
+}
+
+func newTable(title string, columns ...column) *table {
+	return &table{title: title, columns: columns}
+}
+
+func (t *table) addRow(cells ...string) {
+	t.rows = append(t.rows, cells)
+	t.paints = append(t.paints, nil)
+}
+
+// paint colors a cell of the last row in the fancy style.
+func (t *table) paint(column int, paint func(...interface{}) string) {
+	last := len(t.rows) - 1
+	if t.paints[last] == nil {
+		t.paints[last] = map[int]func(...interface{}) string{}
+	}
+	t.paints[last][column] = paint
+}
+
+func (t *table) widths() []int {
+	widths := make([]int, len(t.columns))
+	for i, col := range t.columns {
+		widths[i] = utf8.RuneCountInString(col.header)
+	}
+	for _, row := range t.rows {
+		for i := range t.columns {
+			if i < len(row) {
+				if n := utf8.RuneCountInString(row[i]); n > widths[i] {
+					widths[i] = n
+				}
+			}
+		}
+	}
+	return widths
+}
+
+func pad(s string, width int, align alignment) string {
+	n := width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return s
+	}
+	if align == alignRight {
+		return strings.Repeat(" ", n) + s
+	}
+	return s + strings.Repeat(" ", n)
+}
+
+type frame struct {
+	top, middle, bottom [3]string // left, junction, right
+	horizontal          string
+	vertical            string
+}
+
+var asciiFrame = frame{
+	top:        [3]string{"+", "+", "+"},
+	middle:     [3]string{"+", "+", "+"},
+	bottom:     [3]string{"+", "+", "+"},
+	horizontal: "-",
+	vertical:   "|",
+}
+
+var fancyFrame = frame{
+	top:        [3]string{"┌", "┬", "┐"},
+	middle:     [3]string{"├", "┼", "┤"},
+	bottom:     [3]string{"└", "┴", "┘"},
+	horizontal: "─",
+	vertical:   "│",
+}
+
+func (f frame) rule(parts [3]string, widths []int) string {
+	segments := make([]string, len(widths))
+	for i, w := range widths {
+		segments[i] = strings.Repeat(f.horizontal, w+2)
+	}
+	return parts[0] + strings.Join(segments, parts[1]) + parts[2]
+}
+
+func (t *table) line(f frame, widths []int, cells []string, paint func(...interface{}) string, cellPaints map[int]func(...interface{}) string) string {
+	parts := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		cell := ""
+		if i < len(cells) {
+			cell = cells[i]
+		}
+		cellPaint := paint
+		if p, found := cellPaints[i]; found {
+			cellPaint = p
+		}
+		parts[i] = " " + cellPaint(pad(cell, widths[i], col.align)) + " "
+	}
+	return f.vertical + strings.Join(parts, f.vertical) + f.vertical
+}
+
+func (t *table) render(w io.Writer, style tableStyle) {
+	widths := t.widths()
+	headers := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		headers[i] = col.header
+	}
+	noPaint := fmt.Sprint
+
+	if style == plainStyle {
+		fmt.Fprintln(w, t.title)
+		plainLine := func(cells []string) string {
+			parts := make([]string, len(t.columns))
+			for i, col := range t.columns {
+				parts[i] = pad(cells[i], widths[i], col.align)
+			}
+			return "  " + strings.TrimRight(strings.Join(parts, "  "), " ")
+		}
+		fmt.Fprintln(w, plainLine(headers))
+		for _, row := range t.rows {
+			fmt.Fprintln(w, plainLine(row))
+		}
+		return
+	}
+
+	if style == htmlStyle {
+		fmt.Fprintf(w, "<h3>%s</h3>\n<table>\n<tr>", html.EscapeString(t.title))
+		for i, col := range t.columns {
+			fmt.Fprintf(w, "<th class=\"%s\">%s</th>", htmlAlignment(col.align), html.EscapeString(headers[i]))
+		}
+		fmt.Fprintln(w, "</tr>")
+		for _, row := range t.rows {
+			fmt.Fprint(w, "<tr>")
+			for i, col := range t.columns {
+				cell := ""
+				if i < len(row) {
+					cell = row[i]
+				}
+				fmt.Fprintf(w, "<td class=\"%s\">%s</td>", htmlAlignment(col.align), html.EscapeString(cell))
+			}
+			fmt.Fprintln(w, "</tr>")
+		}
+		fmt.Fprintln(w, "</table>")
+		return
+	}
+
+	if style == markdownStyle {
+		fmt.Fprintf(w, "### %s\n\n", t.title)
+		markdownLine := func(cells []string) string {
+			parts := make([]string, len(t.columns))
+			for i := range t.columns {
+				if i < len(cells) {
+					parts[i] = strings.Replace(cells[i], "|", "\\|", -1)
+				}
+			}
+			return "| " + strings.Join(parts, " | ") + " |"
+		}
+		fmt.Fprintln(w, markdownLine(headers))
+		separators := make([]string, len(t.columns))
+		for i, col := range t.columns {
+			separators[i] = "---"
+			if col.align == alignRight {
+				separators[i] = "---:"
+			}
+		}
+		fmt.Fprintln(w, "|"+strings.Join(separators, "|")+"|")
+		for _, row := range t.rows {
+			fmt.Fprintln(w, markdownLine(row))
+		}
+		fmt.Fprintln(w)
+		return
+	}
+
+	f, titlePaint, headerPaint, cellPaints := asciiFrame, noPaint, noPaint, make([]map[int]func(...interface{}) string, len(t.rows))
+	if style == fancyStyle {
+		cellPaints = t.paints
+		f = fancyFrame
+		titlePaint = color.New(color.FgYellow, color.Bold).SprintFunc()
+		headerPaint = color.New(color.FgCyan).SprintFunc()
+	}
+	fmt.Fprintln(w, titlePaint(t.title))
+	fmt.Fprintln(w, f.rule(f.top, widths))
+	fmt.Fprintln(w, t.line(f, widths, headers, headerPaint, nil))
+	fmt.Fprintln(w, f.rule(f.middle, widths))
+	for i, row := range t.rows {
+		fmt.Fprintln(w, t.line(f, widths, row, noPaint, cellPaints[i]))
+	}
+	fmt.Fprintln(w, f.rule(f.bottom, widths))
+}
+
+func htmlAlignment(align alignment) string {
+	if align == alignRight {
+		return "right"
+	}
+	return "left"
+}
+
+// formatMillis renders a duration as milliseconds with 3 decimals and comma thousands
+// separators, independently of the user locale (e.g. 12,345.678).
+func formatMillis(d time.Duration) string {
+	s := strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	integer, decimals := s, ""
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		integer, decimals = s[:dot], s[dot:]
+	}
+	return sign + groupThousands(integer) + decimals
+}
+
+func groupThousands(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}